@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"foover/internal/export"
+	"foover/internal/service"
+	"foover/internal/store/mongo"
+)
+
+// runExport streams votes or aggregated scores to a file or stdout
+func runExport(ctx context.Context, store mongo.Store, args []string) (err error) {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", export.FormatCSV, "export format: csv, ndjson or parquet")
+	output := flags.String("output", "", "file to write to, stdout if empty")
+	if len(args) == 0 {
+		return errors.New("missing export target, votes or scores")
+	}
+	target := args[0]
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	exportService := service.NewExportService(store)
+	var exportFn func(ctx context.Context, format string, w io.Writer) (int64, error)
+	switch target {
+	case "votes":
+		exportFn = exportService.ExportVotes
+	case "scores":
+		exportFn = exportService.ExportScores
+	default:
+		return fmt.Errorf("unknown export target %q, expected votes or scores", target)
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer func() { err = errors.Join(err, file.Close()) }()
+		out = file
+	}
+
+	buffered := bufio.NewWriter(out)
+	count, err := exportFn(ctx, *format, buffered)
+	if err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d %s as %s\n", count, target, *format)
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"foover/internal/config"
+	"foover/internal/fraud"
+	"foover/internal/metrics"
+	"foover/internal/service"
+	"foover/internal/store/mongo"
+)
+
+// runImport imports votes from a CSV or NDJSON file and prints the import report
+func runImport(ctx context.Context, store mongo.Store, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := flags.String("file", "", "CSV or NDJSON file to import")
+	format := flags.String("format", "", "import format: csv or ndjson, derived from the file extension if empty")
+	dryRun := flags.Bool("dry-run", false, "only validate the rows without writing them")
+	if len(args) == 0 || args[0] != "votes" {
+		return errors.New("missing import target, votes")
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("missing -file")
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*file)), ".")
+	}
+
+	in, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// Imports skip the fraud detection and the outbox, so the vote service gets a disabled detector
+	voteService := service.NewVoteService(store, fraud.NewDetector(store, config.Fraud{}), nil, config.Outbox{}, metrics.New())
+	response, err := voteService.ImportVotes(ctx, *format, bufio.NewReader(in), *dryRun)
+	if err != nil {
+		return fmt.Errorf("%w, %d votes were imported before the error", err, response.Imported)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(response)
+}
@@ -0,0 +1,62 @@
+// Command foover-cli runs offline data tasks against the foover MongoDB store,
+// it reads the same environment variables as the service.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"foover/internal/config"
+	"foover/internal/metrics"
+	"foover/internal/store/mongo"
+)
+
+const usage = `Usage:
+  foover-cli export votes|scores [-format csv|ndjson|parquet] [-output file]
+  foover-cli import votes -file file [-format csv|ndjson] [-dry-run]
+`
+
+// command runs a subcommand with its arguments against the store
+type command func(ctx context.Context, store mongo.Store, args []string) error
+
+var commands = map[string]command{
+	"export": runExport,
+	"import": runImport,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadEnvVars()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	store, err := mongo.NewStore(cfg.Mongo, metrics.New())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize MongoDB store: %v\n", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	if err := cmd(ctx, store, os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", os.Args[1], err)
+		store.Close()
+		os.Exit(1)
+	}
+}
@@ -2,22 +2,51 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	golog "log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	_ "foover/docs"
+	"foover/internal/cache"
 	"foover/internal/config"
+	"foover/internal/events"
+	"foover/internal/fraud"
+	"foover/internal/health"
 	"foover/internal/log"
+	"foover/internal/metrics"
+	"foover/internal/ratelimit"
+	"foover/internal/recommend"
 	"foover/internal/service"
 	"foover/internal/store/mongo"
+	"foover/internal/tracing"
 	httpTransport "foover/internal/transport/http"
+	"foover/internal/votebuffer"
+	"foover/internal/webhook"
+	"foover/internal/worker"
+	"github.com/google/uuid"
 )
 
 // @title Foover API
 // @version 1.0
 // @description This is the API documentation for Foover.
+// @termsOfService http://example.com/terms/
+
+// @contact.name API Support
+// @contact.url http://www.example.com/support
+// @contact.email support@example.com
+
+// @license.name MIT License
+// @license.url https://opensource.org/licenses/MIT
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 
 // @host localhost:8080
 // @BasePath /
@@ -32,41 +61,305 @@ func main() {
 	// initialize logger
 	logger := log.InitializeLogger(cfg.Service.LogLevel)
 
+	// Cancel the root context on SIGINT/SIGTERM to start the graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(ctx, cfg.Tracing, cfg.Service)
+	if err != nil {
+		logger.Error("Failed to initialize tracing", "error", err)
+		os.Exit(1)
+	}
+
+	// Initialize metrics
+	m := metrics.New()
+
 	// Initialize MongoDB store
-	store, err := mongo.NewStore(cfg.Mongo)
+	store, err := mongo.NewStore(cfg.Mongo, m)
 	if err != nil {
-		logger.Error("Failed to initialize MongoDB store: %v", err)
+		logger.Error("Failed to initialize MongoDB store", "error", err)
+		os.Exit(1)
+	}
+	// Fail the vote path fast while MongoDB is unreachable, so votes are buffered within the write timeout
+	store = mongo.NewBreakerStore(store, cfg.Mongo.BreakerCooldown)
+
+	// Open the local buffer votes are written to while MongoDB is unavailable
+	var voteBuffer votebuffer.Buffer
+	if cfg.VoteBuffer.Enabled {
+		voteBuffer, err = votebuffer.NewFileBuffer(cfg.VoteBuffer)
+		if err != nil {
+			logger.Error("Failed to open vote buffer", "error", err)
+			os.Exit(1)
+		}
+		m.SetVoteBufferDepth(voteBuffer.Depth())
+	}
+
+	// Connect the publisher outbox events are relayed to
+	var eventPublisher events.EventPublisher
+	if cfg.Outbox.Enabled {
+		eventPublisher, err = events.NewEventPublisher(cfg.Outbox)
+		if err != nil {
+			logger.Error("Failed to initialize event publisher", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Fail fast on a similarity method the index can't compute
+	if !recommend.IsSimilarityMethod(cfg.Similarity.Method) {
+		logger.Error("Unknown similarity method", "method", cfg.Similarity.Method)
+		os.Exit(1)
+	}
+
+	// Fail fast on a significance level no test can be run at
+	if cfg.Experiment.SignificanceLevel <= 0 || cfg.Experiment.SignificanceLevel >= 1 {
+		logger.Error("Experiment significance level must be between 0 and 1", "level", cfg.Experiment.SignificanceLevel)
+		os.Exit(1)
 	}
-	defer store.Close()
 
 	// Initialize services
-	sessionService := service.NewSessionService(store)
-	voteService := service.NewVoteService(store)
+	owner := replicaID()
+	sessionService := service.NewSessionService(store, cfg.Experiment, m)
+	voteService := service.NewVoteService(store, fraud.NewDetector(store, cfg.Fraud), voteBuffer, cfg.Outbox, m)
 	aggregationService := service.NewAggregationService(store)
-	productService := service.NewProductService(store)
+	productService := service.NewProductService(store, cfg.ProductCache, m)
+	authService := service.NewAuthService(store, cfg.Auth.BootstrapAdminKey)
+	retentionService := service.NewRetentionService(store, cfg.Retention, m)
+	exportService := service.NewExportService(store)
+	idempotencyService := service.NewIdempotencyService(store, cfg.Idempotency, m)
+	webhookService := service.NewWebhookService(store, webhook.NewClient(cfg.Webhook.Timeout), cfg.Webhook, m)
+	recommendationService := service.NewRecommendationService(store, cfg.Recommendation, m)
+	similarityService := service.NewSimilarityService(store, cfg.Similarity, owner, m)
+	trendingService := service.NewTrendingService(store, cache.NewMemoryBackend(), cfg.Trending, m)
+
+	// Forward score anomalies to the webhooks subscribed to them
+	var anomalyWebhooks service.WebhookService
+	if cfg.Webhook.Enabled && cfg.Anomaly.Notify {
+		anomalyWebhooks = webhookService
+	}
+	anomalyService := service.NewAnomalyService(store, anomalyWebhooks, cfg.Anomaly, owner, m)
+	experimentService := service.NewExperimentService(store, cfg.Experiment)
+
+	// Queue vote.saved webhooks from the outbox, so they are sent once the vote is committed
+	if cfg.Webhook.Enabled {
+		if cfg.Outbox.Enabled {
+			eventPublisher = events.NewFanOutPublisher(eventPublisher, service.NewWebhookEventPublisher(webhookService))
+		} else {
+			logger.Warn("vote.saved webhooks are relayed from the outbox and won't be sent with the outbox disabled")
+		}
+	}
+
+	// Cache aggregated scores, votes changed through the vote, session and retention services invalidate them
+	if cfg.ScoreCache.Enabled {
+		scoreCache := cache.NewMemoryBackend()
+		aggregationService = service.NewCachedAggregationService(aggregationService, scoreCache, cfg.ScoreCache.TTL, m)
+		voteService = service.NewScoreInvalidatingVoteService(voteService, scoreCache)
+		sessionService = service.NewScoreInvalidatingSessionService(sessionService, scoreCache)
+		retentionService = service.NewScoreInvalidatingRetentionService(retentionService, scoreCache)
+	}
+
+	// Register health checks
+	healthRegistry := health.NewRegistry(cfg.Health.CheckTimeout)
+	healthRegistry.Register("product_catalog", health.CheckerFunc(productService.CheckCatalog))
+	if voteBuffer != nil {
+		// Votes are buffered while MongoDB is down, so the service stays ready, degraded, until the buffer is full
+		healthRegistry.Register("mongo", health.WithFallback(health.CheckerFunc(store.Ping), voteBuffer))
+		healthRegistry.Register("vote_buffer", voteBuffer)
+	} else {
+		healthRegistry.Register("mongo", health.CheckerFunc(store.Ping))
+	}
 
 	// Initialize HTTP server
-	router := httpTransport.NewRouter(sessionService, voteService, aggregationService, productService, logger)
+	router := httpTransport.NewRouter(httpTransport.Dependencies{
+		SessionService:        sessionService,
+		VoteService:           voteService,
+		AggregationService:    aggregationService,
+		ProductService:        productService,
+		AuthService:           authService,
+		RetentionService:      retentionService,
+		ExportService:         exportService,
+		IdempotencyService:    idempotencyService,
+		WebhookService:        webhookService,
+		RecommendationService: recommendationService,
+		SimilarityService:     similarityService,
+		TrendingService:       trendingService,
+		AnomalyService:        anomalyService,
+		ExperimentService:     experimentService,
+		HealthRegistry:        healthRegistry,
+		Metrics:               m,
+		RateLimiter:           ratelimit.NewMemoryBackend(),
+		HTTPServer:            cfg.HTTPServer,
+		RateLimit:             cfg.RateLimit,
+		Idempotency:           cfg.Idempotency,
+		Logger:                logger,
+	})
 
 	// Fetch and store products
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-	err = productService.FetchAndStoreProducts(ctx, cfg.ExternalAPI.ProductAPIURL)
+	fetchCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	err = productService.FetchAndStoreProducts(fetchCtx, cfg.ExternalAPI.ProductAPIURL)
+	cancel()
 	if err != nil {
-		logger.Error("Failed to fetch and store products: %v", err)
+		logger.Error("Failed to fetch and store products", "error", err)
+	} else {
+		logger.Info("Products fetched and stored successfully")
+	}
+
+	// Start background workers
+	workers := worker.NewGroup(logger)
+	workers.Every("product_sync", cfg.ExternalAPI.ProductSyncInterval, func(ctx context.Context) error {
+		return productService.FetchAndStoreProducts(ctx, cfg.ExternalAPI.ProductAPIURL)
+	})
+	if cfg.ProductCache.Enabled {
+		workers.Every("product_cache_refresh", cfg.ProductCache.RefreshInterval, productService.RefreshProducts)
+	}
+	if voteBuffer != nil {
+		workers.Every("vote_buffer_replay", cfg.VoteBuffer.ReplayInterval, func(ctx context.Context) error {
+			replayed, err := voteService.ReplayBufferedVotes(ctx)
+			if replayed > 0 {
+				logger.Info("Buffered votes replayed", "votes", replayed, "remaining", voteBuffer.Depth())
+			}
+			return err
+		})
+	}
+	if eventPublisher != nil {
+		outboxService := service.NewOutboxService(store, eventPublisher, cfg.Outbox, owner, m)
+		workers.Every("outbox_relay", cfg.Outbox.RelayInterval, func(ctx context.Context) error {
+			_, err := outboxService.Relay(ctx)
+			return err
+		})
+	}
+	if cfg.Webhook.Enabled {
+		workers.Every("webhook_delivery", cfg.Webhook.DeliveryInterval, func(ctx context.Context) error {
+			_, err := webhookService.Deliver(ctx)
+			return err
+		})
+		workers.Every("webhook_score_check", cfg.Webhook.CheckInterval, webhookService.CheckScores)
+	}
+	if cfg.Recommendation.Enabled {
+		// The first model is built in the background, recommendations are unavailable until it is done
+		workers.Go("recommendation_build", func(ctx context.Context) {
+			if err := recommendationService.Refresh(ctx); err != nil {
+				logger.Error("Failed to build recommendation model", "error", err)
+			}
+		})
+		workers.Every("recommendation_refresh", cfg.Recommendation.RefreshInterval, recommendationService.Refresh)
+	}
+	if cfg.Similarity.Enabled {
+		workers.Go("similarity_build", func(ctx context.Context) {
+			if err := similarityService.Refresh(ctx); err != nil {
+				logger.Error("Failed to refresh similarity index", "error", err)
+			}
+		})
+		workers.Every("similarity_refresh", cfg.Similarity.RefreshInterval, similarityService.Refresh)
+	}
+	// Keep the trending ranking cached, so requests don't compute it
+	workers.Every("trending_refresh", cfg.Trending.RefreshInterval, trendingService.Refresh)
+	if cfg.Anomaly.Enabled {
+		workers.Every("anomaly_detection", cfg.Anomaly.Interval, func(ctx context.Context) error {
+			recorded, err := anomalyService.Detect(ctx)
+			if recorded > 0 {
+				logger.Warn("Score anomalies detected", "anomalies", recorded)
+			}
+			return err
+		})
+	}
+	if cfg.Retention.Enabled {
+		workers.Every("retention", cfg.Retention.Interval, func(ctx context.Context) error {
+			report, err := retentionService.Run(ctx, cfg.Retention.DryRun)
+			if err != nil {
+				return err
+			}
+			logger.Info("Retention rules applied",
+				"dryRun", report.DryRun,
+				"emptySessions", report.EmptySessions,
+				"anonymizedVotes", report.AnonymizedVotes,
+				"duration", report.FinishedAt.Sub(report.StartedAt),
+			)
+			return nil
+		})
 	}
-	logger.Info("Products fetched and stored successfully")
 
 	srv := &http.Server{
-		Addr:         cfg.HTTPServer.Address,
-		Handler:      router,
-		ReadTimeout:  cfg.HTTPServer.ReadTimeout,
-		WriteTimeout: cfg.HTTPServer.WriteTimeout,
-		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
+		Addr:           cfg.HTTPServer.Address,
+		Handler:        router,
+		ReadTimeout:    cfg.HTTPServer.ReadTimeout,
+		WriteTimeout:   cfg.HTTPServer.WriteTimeout,
+		IdleTimeout:    cfg.HTTPServer.IdleTimeout,
+		MaxHeaderBytes: cfg.HTTPServer.MaxHeaderBytes,
 	}
 
-	logger.Info(fmt.Sprintf("Server is running on %s", cfg.HTTPServer.Address))
-	if err := srv.ListenAndServe(); err != nil {
-		logger.Error("Failed to start server: %v", err)
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info(fmt.Sprintf("Server is running on %s", cfg.HTTPServer.Address))
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	select {
+	case <-ctx.Done():
+		logger.Info("Shutdown signal received")
+	case err := <-serverErr:
+		logger.Error("Failed to start server", "error", err)
+	}
+
+	shutdown(srv, workers, store, voteBuffer, eventPublisher, healthRegistry, cfg, logger)
+
+	// Flush the spans of the drained requests
+	tracingCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTPServer.ShutdownTimeout)
+	defer cancel()
+	if err := shutdownTracing(tracingCtx); err != nil {
+		logger.Error("Failed to flush traces", "error", err)
+	}
+}
+
+// shutdown flips readiness, drains in-flight requests, stops background workers and closes the vote buffer, the event publisher and the store
+func shutdown(srv *http.Server, workers worker.Group, store mongo.Store, voteBuffer votebuffer.Buffer, eventPublisher events.EventPublisher, healthRegistry health.Registry, cfg *config.EnvVars, logger *slog.Logger) {
+	healthRegistry.SetShuttingDown()
+	if cfg.Health.ShutdownDelay > 0 {
+		logger.Info("Waiting for load balancers to observe readiness change", "delay", cfg.Health.ShutdownDelay.String())
+		time.Sleep(cfg.Health.ShutdownDelay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTPServer.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Error("Failed to drain in-flight requests", "error", err)
+	}
+
+	if err := workers.Stop(ctx); err != nil {
+		logger.Error("Failed to stop background workers", "error", err)
+	}
+
+	// Buffered votes stay on disk and are replayed after the next start
+	if voteBuffer != nil {
+		if err := voteBuffer.Close(); err != nil {
+			logger.Error("Failed to close vote buffer", "error", err)
+		}
+	}
+
+	// Pending events stay in the outbox and are relayed by another replica or after the next start
+	if eventPublisher != nil {
+		if err := eventPublisher.Close(); err != nil {
+			logger.Error("Failed to close event publisher", "error", err)
+		}
+	}
+
+	// The store uses its own disconnect timeout so it is closed even if draining used up the shutdown timeout
+	if err := store.Close(); err != nil {
+		logger.Error("Failed to close MongoDB store", "error", err)
+	}
+
+	logger.Info("Server stopped")
+}
+
+// replicaID identifies this replica as holder of the leases of singleton jobs
+func replicaID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
 	}
+	return hostname + "/" + uuid.New().String()
 }
@@ -24,21 +24,38 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/aggregated-scores": {
+        "/admin/api-keys": {
             "get": {
-                "description": "Retrieves aggregated average scores for products across all session IDs.",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves the metadata of all API keys, including revoked ones.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "aggregation"
+                    "admin"
                 ],
-                "summary": "Get aggregated product scores",
+                "summary": "List API keys",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.GetAggregatedScoresResponse"
+                            "$ref": "#/definitions/models.GetAPIKeysResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
@@ -48,11 +65,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/sessions": {
+            },
             "post": {
-                "description": "Generates a unique session ID.",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Issues a new API key with the given scopes. The key is only returned in this response.",
                 "consumes": [
                     "application/json"
                 ],
@@ -60,24 +80,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "sessions"
+                    "admin"
                 ],
-                "summary": "Create a new session",
+                "summary": "Create an API key",
                 "parameters": [
                     {
-                        "description": "Session creation request",
-                        "name": "session",
+                        "description": "API key creation request",
+                        "name": "apiKey",
                         "in": "body",
+                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.CreateSessionRequest"
+                            "$ref": "#/definitions/models.CreateAPIKeyRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.CreateSessionResponse"
+                            "$ref": "#/definitions/models.CreateAPIKeyResponse"
                         }
                     },
                     "400": {
@@ -86,6 +107,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -95,9 +128,110 @@ const docTemplate = `{
                 }
             }
         },
-        "/votes": {
+        "/admin/api-keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Revokes the API key with the given ID, it is rejected from then on.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Revoke an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/experiments": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves all experiments, running or stopped, newest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List experiments",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GetExperimentsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "Stores or updates a product vote for a given session ID.",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Starts an A/B experiment on the presentation of products. Every session created while it runs is assigned to one of its variants in proportion to their weights, deterministically from the session ID, and gets the variant in the session creation response. The first variant is the control.",
                 "consumes": [
                     "application/json"
                 ],
@@ -105,17 +239,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "votes"
+                    "admin"
                 ],
-                "summary": "Save or update a vote",
+                "summary": "Create an experiment",
                 "parameters": [
                     {
-                        "description": "Vote object that needs to be added or updated",
-                        "name": "vote",
+                        "description": "Experiment creation request",
+                        "name": "experiment",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.SaveVoteRequest"
+                            "$ref": "#/definitions/models.CreateExperimentRequest"
                         }
                     }
                 ],
@@ -123,7 +257,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.EmptyResponse"
+                            "$ref": "#/definitions/models.Experiment"
                         }
                     },
                     "400": {
@@ -132,6 +266,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -141,21 +293,26 @@ const docTemplate = `{
                 }
             }
         },
-        "/votes/{session_id}": {
+        "/admin/experiments/{key}": {
             "get": {
-                "description": "Retrieves existing votes for products for a given session ID.",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves the experiment with the given key.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "votes"
+                    "admin"
                 ],
-                "summary": "Get votes by session ID",
+                "summary": "Get an experiment",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "The session ID",
-                        "name": "session_id",
+                        "description": "The experiment key",
+                        "name": "key",
                         "in": "path",
                         "required": true
                     }
@@ -164,11 +321,23 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.GetVotesResponse"
+                            "$ref": "#/definitions/models.Experiment"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -181,50 +350,2927 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.CreateSessionRequest": {
-            "type": "object"
         },
-        "models.CreateSessionResponse": {
-            "type": "object",
-            "properties": {
-                "session_id": {
-                    "description": "The unique session ID\nRequired: true",
-                    "type": "string"
+        "/admin/experiments/{key}/analysis": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Compares the counted votes cast on the products of an experiment while it ran by the sessions assigned to each variant. Each other variant is compared to the control with Welch's t-test on the average scores of the voting sessions, at EXPERIMENT_SIGNIFICANCE_LEVEL split across the comparisons. Variants with fewer than EXPERIMENT_MIN_SESSIONS voting sessions aren't tested.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Analyze an experiment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The experiment key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GetExperimentAnalysisResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "models.EmptyResponse": {
-            "type": "object"
-        },
-        "models.ErrorResponse": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "description": "Error message\nRequired: true",
-                    "type": "string"
+        "/admin/experiments/{key}/stop": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Stops assigning new sessions to the variants of an experiment. Sessions keep their variants and the analysis only counts votes cast until the experiment stopped. Stopping a stopped experiment has no effect.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Stop an experiment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The experiment key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Experiment"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "models.GetAggregatedScoresResponse": {
-            "type": "object",
-            "properties": {
-                "scores": {
-                    "description": "List of aggregated product scores\nRequired: true",
+        "/admin/products": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves all products, including retired ones.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List products",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GetProductsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates a manually managed product, which is left untouched by catalog syncs.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Create a product",
+                "parameters": [
+                    {
+                        "description": "Product creation request",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateProductRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Product"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/products/import": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates or updates manually managed products from a JSON array or a CSV file with a product_id,name,pinned header. Invalid rows are reported and skipped.",
+                "consumes": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Import products",
+                "parameters": [
+                    {
+                        "description": "Products to import",
+                        "name": "products",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.CreateProductRequest"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImportProductsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/products/{product_id}": {
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Updates the name and pin of a product, omitted fields are left unchanged.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update a product",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The product ID",
+                        "name": "product_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Product update request",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateProductRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Product"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/products/{product_id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Restores a retired product so it accepts votes again.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Restore a product",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The product ID",
+                        "name": "product_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Product"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/products/{product_id}/retire": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retires a product so it no longer accepts votes. Existing votes are kept.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Retire a product",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The product ID",
+                        "name": "product_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Product"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/retention/report": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Runs the retention rules as a dry run and reports how many sessions without votes would be deleted and how many votes would be anonymized.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Preview data retention",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RetentionReport"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/votes/flagged": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves votes flagged by the fraud detection, oldest first. Flagged votes are left out of the aggregated scores until approved.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List flagged votes",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of votes to return (default 100, max 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GetFlaggedVotesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/votes/import": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Imports votes from a CSV file with a session_id,product_id,score,timestamp header or from NDJSON objects with the same fields. Rows are validated like single votes, timestamps are RFC 3339 or YYYY-MM-DD. A stored vote is only replaced by an imported vote with a newer timestamp. Invalid rows are reported and skipped.",
+                "consumes": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Import votes",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Only validate the rows without writing them",
+                        "name": "dry_run",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImportVotesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/votes/{id}/approve": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Approves a flagged vote, so it counts in the aggregated scores.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Approve a flagged vote",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The vote ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Vote"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/votes/{id}/reject": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Rejects a flagged vote, so it stays out of the aggregated scores. A new vote by the session on the product is assessed again.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reject a flagged vote",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The vote ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Vote"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves all webhooks, active or not.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List webhooks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GetWebhooksResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Subscribes a URL to events, optionally only for some products. Payloads are signed with HMAC-SHA256 in the X-Foover-Signature header, the secret is only returned in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Create a webhook",
+                "parameters": [
+                    {
+                        "description": "Webhook creation request",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateWebhookResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/dead-letters": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves deliveries of all webhooks that failed their last attempt, newest first. They can be redelivered.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List dead letters",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of deliveries to return (default 100, max 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GetWebhookDeliveriesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/deliveries/{id}/redeliver": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Schedules a delivery that ran out of attempts for a new round of attempts right away.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Redeliver a dead letter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The delivery ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookDelivery"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves the webhook with the given ID.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes the webhook with the given ID along with its pending deliveries and delivery logs.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Delete a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Updates the URL, events, products or state of a webhook, omitted fields are left unchanged.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Webhook update request",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/{id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves the deliveries of a webhook with their attempts, newest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only deliveries with this status (pending, succeeded, dead)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of deliveries to return (default 100, max 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GetWebhookDeliveriesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/aggregated-scores": {
+            "get": {
+                "description": "Retrieves aggregated average scores for products across all session IDs. Responses are cached briefly and carry an ETag for conditional requests.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "aggregation"
+                ],
+                "summary": "Get aggregated product scores",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of a previous response, answered with 304 if the scores are unchanged",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GetAggregatedScoresResponse"
+                        }
+                    },
+                    "304": {
+                        "description": "Scores unchanged since the response with the given ETag"
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/anomalies": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves the anomalies found by the detection job, newest first. An anomaly is recorded when the average score of a product over the last ANOMALY_WINDOW lies at least ANOMALY_Z_THRESHOLD standard errors away from its average over the ANOMALY_BASELINE_WINDOW before it. Anomalies are also sent to webhooks subscribed to score.anomaly.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "votes"
+                ],
+                "summary": "List score anomalies",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only anomalies of this product",
+                        "name": "product_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only anomalies in this direction (drop, spike)",
+                        "name": "direction",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only anomalies detected at or after this RFC 3339 time",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of anomalies to return (default 100, max 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GetAnomaliesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/export/scores": {
+            "get": {
+                "description": "Streams the aggregated product scores as CSV, NDJSON or Parquet.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.apache.parquet"
+                ],
+                "tags": [
+                    "export"
+                ],
+                "summary": "Export aggregated scores",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson",
+                            "parquet"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "Export format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/export/votes": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Streams all raw votes as CSV, NDJSON or Parquet. Client fingerprints are not exported.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.apache.parquet"
+                ],
+                "tags": [
+                    "export"
+                ],
+                "summary": "Export votes",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson",
+                            "parquet"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "Export format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Reports that the process is alive and able to serve HTTP requests.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{product_id}/similar": {
+            "get": {
+                "description": "Returns the products most similar to a product, measured by how similarly the sessions that rated both scored them (\"people who liked this also liked\"). Only pairs rated by enough sessions are considered and retired products are left out. The similarity index is refreshed periodically.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get similar products",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The product ID",
+                        "name": "product_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of similar products (1-50, default 10)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GetSimilarProductsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Reports whether all registered dependency checks pass and the service is not shutting down. A service with a failed dependency covered by a fallback is degraded but ready.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.HealthResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sessions": {
+            "post": {
+                "description": "Generates a unique session ID and assigns the session to a variant of every running experiment.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Create a new session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Client chosen key making retries safe, the response of the first request is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Session creation request",
+                        "name": "session",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateSessionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateSessionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sessions/{session_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes a session, its votes and the webhook deliveries and published outbox events of its votes in one transaction to honor data subject erasure requests. Vote events not published yet are published without the session ID. Aggregated scores no longer include the erased votes. The erasure is audited with a hash of the session ID, which is returned as a receipt.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Erase session data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The session ID",
+                        "name": "session_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErasureAuditEntry"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sessions/{session_id}/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns everything stored for a session (the session record, its votes with their moderation state and the stored client fingerprints) to honor data subject access requests.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Export session data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The session ID",
+                        "name": "session_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SessionExport"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sessions/{session_id}/recommendations": {
+            "get": {
+                "description": "Returns products the session hasn't voted on, ranked by the score predicted from how sessions that rated the same products scored them. When there is too little to predict from, the list is filled in with the top-ranked products. Similarities are rebuilt periodically, the latest votes of the session are always taken into account.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Get recommendations for a session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The session ID",
+                        "name": "session_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of recommendations (1-50, default 10)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GetRecommendationsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/status": {
+            "get": {
+                "description": "Runs all registered dependency checks and reports their status and latencies.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Detailed service status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GetStatusResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.GetStatusResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/trending": {
+            "get": {
+                "description": "Ranks products by the change of their vote volume and average score in a recent window (TRENDING_WINDOW) compared to the baseline window before it (TRENDING_BASELINE_WINDOW). Recent votes weigh less as they age, halving every TRENDING_HALF_LIFE of Vote.UpdatedAt. Flagged and rejected votes don't count and retired products are left out. The ranking is cached for TRENDING_CACHE_TTL.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get trending products",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of products (1-50, default 10)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GetTrendingResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/votes": {
+            "post": {
+                "description": "Stores or updates a product vote for a given session ID.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "votes"
+                ],
+                "summary": "Save or update a vote",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Client chosen key making retries safe, the response of the first request is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Vote object that needs to be added or updated",
+                        "name": "vote",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SaveVoteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.EmptyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/votes/batch": {
+            "post": {
+                "description": "Stores or updates up to 50 product votes of a session with a single write and reports the outcome of each vote. Invalid votes are rejected individually, unless the batch is atomic, in which case nothing is stored.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "votes"
+                ],
+                "summary": "Save or update several votes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Client chosen key making retries safe, the response of the first request is replayed",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Votes that need to be added or updated",
+                        "name": "votes",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SaveVotesBatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "All votes were saved",
+                        "schema": {
+                            "$ref": "#/definitions/models.SaveVotesBatchResponse"
+                        }
+                    },
+                    "207": {
+                        "description": "Some votes were not saved",
+                        "schema": {
+                            "$ref": "#/definitions/models.SaveVotesBatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/votes/{session_id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves existing votes for products for a given session ID.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "votes"
+                ],
+                "summary": "Get votes by session ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "The session ID",
+                        "name": "session_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GetVotesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "hint": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.Anomaly": {
+            "type": "object",
+            "properties": {
+                "baseline_avg": {
+                    "type": "number"
+                },
+                "baseline_std_dev": {
+                    "type": "number"
+                },
+                "baseline_votes": {
+                    "type": "integer"
+                },
+                "detected_at": {
+                    "type": "string"
+                },
+                "direction": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "product_id": {
+                    "type": "string"
+                },
+                "recent_avg": {
+                    "type": "number"
+                },
+                "recent_votes": {
+                    "type": "integer"
+                },
+                "window_start": {
+                    "description": "recent votes were last updated after this",
+                    "type": "string"
+                },
+                "z_score": {
+                    "description": "standard errors between the recent and the baseline average",
+                    "type": "number"
+                }
+            }
+        },
+        "models.BatchVoteItem": {
+            "type": "object",
+            "required": [
+                "product_id",
+                "score"
+            ],
+            "properties": {
+                "product_id": {
+                    "description": "The product ID\nRequired: true",
+                    "type": "string"
+                },
+                "score": {
+                    "description": "The score (e.g., rating from 1 to 5)\nRequired: true",
+                    "type": "integer",
+                    "maximum": 5,
+                    "minimum": 1
+                }
+            }
+        },
+        "models.BatchVoteResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Why the vote wasn't saved",
+                    "type": "string"
+                },
+                "product_id": {
+                    "description": "The product ID\nRequired: true",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "One of saved, rejected, failed or skipped\nRequired: true",
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateAPIKeyRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "name": {
+                    "description": "Name of the consumer the key is issued to\nRequired: true",
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "scopes": {
+                    "description": "Scopes granted to the key (votes:read, privacy, admin)\nRequired: true",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.CreateAPIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "api_key": {
+                    "description": "The stored API key metadata\nRequired: true",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.APIKey"
+                        }
+                    ]
+                },
+                "key": {
+                    "description": "The API key, it is only returned once\nRequired: true",
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateExperimentRequest": {
+            "type": "object",
+            "required": [
+                "key",
+                "variants"
+            ],
+            "properties": {
+                "description": {
+                    "description": "What the experiment changes in the presentation",
+                    "type": "string",
+                    "maxLength": 1000
+                },
+                "key": {
+                    "description": "Unique key of the experiment, lowercase letters, digits, dashes and underscores\nRequired: true",
+                    "type": "string",
+                    "maxLength": 64
+                },
+                "product_ids": {
+                    "description": "Products whose votes are analyzed, all products if empty",
+                    "type": "array",
+                    "maxItems": 1000,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "variants": {
+                    "description": "Variants of the experiment, the first one is the control\nRequired: true",
+                    "type": "array",
+                    "maxItems": 10,
+                    "minItems": 2,
+                    "items": {
+                        "$ref": "#/definitions/models.ExperimentVariantRequest"
+                    }
+                }
+            }
+        },
+        "models.CreateProductRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "description": "The product name",
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "pinned": {
+                    "description": "Whether the product survives catalog syncs",
+                    "type": "boolean"
+                },
+                "product_id": {
+                    "description": "The product ID, generated if empty",
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateSessionRequest": {
+            "type": "object"
+        },
+        "models.CreateSessionResponse": {
+            "type": "object",
+            "properties": {
+                "session_id": {
+                    "description": "The unique session ID\nRequired: true",
+                    "type": "string"
+                },
+                "variants": {
+                    "description": "The variants of the running experiments the session is assigned to",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ExperimentAssignment"
+                    }
+                }
+            }
+        },
+        "models.CreateWebhookRequest": {
+            "type": "object",
+            "required": [
+                "events",
+                "url"
+            ],
+            "properties": {
+                "events": {
+                    "description": "Event types to send (vote.saved, score.below_threshold, votes.count_reached)\nRequired: true",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "product_ids": {
+                    "description": "Products to send events for, all products if empty",
+                    "type": "array",
+                    "maxItems": 1000,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "url": {
+                    "description": "The URL events are posted to\nRequired: true",
+                    "type": "string",
+                    "maxLength": 2000
+                }
+            }
+        },
+        "models.CreateWebhookResponse": {
+            "type": "object",
+            "properties": {
+                "secret": {
+                    "description": "The secret signing the payloads, it is only returned once\nRequired: true",
+                    "type": "string"
+                },
+                "webhook": {
+                    "description": "The stored webhook\nRequired: true",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.EmptyResponse": {
+            "type": "object"
+        },
+        "models.ErasureAuditEntry": {
+            "type": "object",
+            "properties": {
+                "erased_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "principal": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "session_id_hash": {
+                    "type": "string"
+                },
+                "votes_deleted": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "description": "Error message\nRequired: true",
+                    "type": "string"
+                }
+            }
+        },
+        "models.Experiment": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "product_ids": {
+                    "description": "products whose votes are analyzed, all products if empty",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "stopped_at": {
+                    "type": "string"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ExperimentVariant"
+                    }
+                }
+            }
+        },
+        "models.ExperimentAssignment": {
+            "type": "object",
+            "properties": {
+                "experiment": {
+                    "type": "string"
+                },
+                "variant": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ExperimentVariant": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "weight": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ExperimentVariantRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "description": "Name of the variant, unique within the experiment\nRequired: true",
+                    "type": "string",
+                    "maxLength": 64
+                },
+                "weight": {
+                    "description": "Share of the sessions assigned to the variant relative to the other variants, 1 if omitted",
+                    "type": "integer",
+                    "maximum": 1000,
+                    "minimum": 1
+                }
+            }
+        },
+        "models.GetAPIKeysResponse": {
+            "type": "object",
+            "properties": {
+                "api_keys": {
+                    "description": "List of API keys\nRequired: true",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.APIKey"
+                    }
+                }
+            }
+        },
+        "models.GetAggregatedScoresResponse": {
+            "type": "object",
+            "properties": {
+                "scores": {
+                    "description": "List of aggregated product scores\nRequired: true",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ProductScore"
+                    }
+                }
+            }
+        },
+        "models.GetAnomaliesResponse": {
+            "type": "object",
+            "properties": {
+                "anomalies": {
+                    "description": "List of anomalies, newest first\nRequired: true",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Anomaly"
+                    }
+                }
+            }
+        },
+        "models.GetExperimentAnalysisResponse": {
+            "type": "object",
+            "properties": {
+                "analyzed_at": {
+                    "description": "Required: true",
+                    "type": "string"
+                },
+                "comparisons": {
+                    "description": "Comparison of each other variant to the control\nRequired: true",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.VariantComparison"
+                    }
+                },
+                "control": {
+                    "description": "The control every other variant is compared to\nRequired: true",
+                    "type": "string"
+                },
+                "experiment": {
+                    "description": "The analyzed experiment\nRequired: true",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Experiment"
+                        }
+                    ]
+                },
+                "significance_level": {
+                    "description": "Significance level of each comparison, the configured level split across the comparisons\nRequired: true",
+                    "type": "number"
+                },
+                "variants": {
+                    "description": "Outcome of each variant\nRequired: true",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.VariantStats"
+                    }
+                }
+            }
+        },
+        "models.GetExperimentsResponse": {
+            "type": "object",
+            "properties": {
+                "experiments": {
+                    "description": "List of experiments, newest first\nRequired: true",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Experiment"
+                    }
+                }
+            }
+        },
+        "models.GetFlaggedVotesResponse": {
+            "type": "object",
+            "properties": {
+                "votes": {
+                    "description": "List of flagged votes, oldest first\nRequired: true",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Vote"
+                    }
+                }
+            }
+        },
+        "models.GetProductsResponse": {
+            "type": "object",
+            "properties": {
+                "products": {
+                    "description": "List of products\nRequired: true",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Product"
+                    }
+                }
+            }
+        },
+        "models.GetRecommendationsResponse": {
+            "type": "object",
+            "properties": {
+                "model_built_at": {
+                    "description": "When the model behind the recommendations was built\nRequired: true",
+                    "type": "string"
+                },
+                "recommendations": {
+                    "description": "Products the session hasn't rated, best first\nRequired: true",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Recommendation"
+                    }
+                },
+                "session_id": {
+                    "description": "The session ID\nRequired: true",
+                    "type": "string"
+                }
+            }
+        },
+        "models.GetSimilarProductsResponse": {
+            "type": "object",
+            "properties": {
+                "product_id": {
+                    "description": "The product ID\nRequired: true",
+                    "type": "string"
+                },
+                "similar": {
+                    "description": "Similar products, most similar first\nRequired: true",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SimilarProduct"
+                    }
+                }
+            }
+        },
+        "models.GetStatusResponse": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "description": "List of dependency checks\nRequired: true",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.HealthCheckResponse"
+                    }
+                },
+                "shutting_down": {
+                    "description": "Whether the service is shutting down\nRequired: true",
+                    "type": "boolean"
+                },
+                "status": {
+                    "description": "One of \"up\", \"degraded\" or \"down\"\nRequired: true",
+                    "type": "string"
+                }
+            }
+        },
+        "models.GetTrendingResponse": {
+            "type": "object",
+            "properties": {
+                "baseline_start": {
+                    "description": "Start of the baseline window, which ends where the recent window starts\nRequired: true",
+                    "type": "string"
+                },
+                "computed_at": {
+                    "description": "When the ranking was computed, it is cached for a while\nRequired: true",
+                    "type": "string"
+                },
+                "products": {
+                    "description": "Trending products, most trending first\nRequired: true",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TrendingProduct"
+                    }
+                },
+                "window_start": {
+                    "description": "Start of the window of recent votes\nRequired: true",
+                    "type": "string"
+                }
+            }
+        },
+        "models.GetVotesResponse": {
+            "type": "object",
+            "properties": {
+                "votes": {
+                    "description": "List of votes\nRequired: true",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Vote"
+                    }
+                }
+            }
+        },
+        "models.GetWebhookDeliveriesResponse": {
+            "type": "object",
+            "properties": {
+                "deliveries": {
+                    "description": "List of deliveries with their attempts, newest first\nRequired: true",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WebhookDelivery"
+                    }
+                }
+            }
+        },
+        "models.GetWebhooksResponse": {
+            "type": "object",
+            "properties": {
+                "webhooks": {
+                    "description": "List of webhooks\nRequired: true",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Webhook"
+                    }
+                }
+            }
+        },
+        "models.HealthCheckResponse": {
+            "type": "object",
+            "properties": {
+                "details": {
+                    "description": "Additional state reported by the check, e.g. the depth of the vote buffer",
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "error": {
+                    "description": "Failure reason if the dependency is degraded or down",
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "description": "Duration of the check in milliseconds\nRequired: true",
+                    "type": "number"
+                },
+                "name": {
+                    "description": "Name of the checked dependency\nRequired: true",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "One of \"up\", \"degraded\" or \"down\", a degraded dependency failed but is covered by a fallback\nRequired: true",
+                    "type": "string"
+                }
+            }
+        },
+        "models.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "description": "One of \"up\", \"degraded\" or \"down\"\nRequired: true",
+                    "type": "string"
+                }
+            }
+        },
+        "models.ImportProductsResponse": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "description": "Rows that were rejected\nRequired: true",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ImportRowError"
+                    }
+                },
+                "imported": {
+                    "description": "Number of imported products\nRequired: true",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ImportRowError": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "description": "Why the row was rejected\nRequired: true",
+                    "type": "string"
+                },
+                "row": {
+                    "description": "The 1-based row number, excluding the CSV header\nRequired: true",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ImportVotesResponse": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "description": "Whether the import only validated the rows without writing them\nRequired: true",
+                    "type": "boolean"
+                },
+                "errors": {
+                    "description": "Rejected rows, only the first 1000 are reported\nRequired: true",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ImportRowError"
+                    }
+                },
+                "imported": {
+                    "description": "Number of imported votes, or votes that would be imported in a dry run\nRequired: true",
+                    "type": "integer"
+                },
+                "rejected": {
+                    "description": "Number of rejected rows\nRequired: true",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Product": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "pinned": {
+                    "description": "pinned products survive catalog syncs",
+                    "type": "boolean"
+                },
+                "product_id": {
+                    "type": "string"
+                },
+                "retired_at": {
+                    "description": "retired products can't be voted on",
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ProductScore": {
+            "type": "object",
+            "properties": {
+                "avgScore": {
+                    "type": "number"
+                },
+                "productID": {
+                    "type": "string"
+                },
+                "voteCount": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Recommendation": {
+            "type": "object",
+            "properties": {
+                "predicted_score": {
+                    "description": "for popular products, their average pulled towards the global average",
+                    "type": "number"
+                },
+                "product_id": {
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RetentionReport": {
+            "type": "object",
+            "properties": {
+                "anonymized_votes": {
+                    "type": "integer"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "empty_session_cutoff": {
+                    "description": "sessions without votes created before are deleted",
+                    "type": "string"
+                },
+                "empty_sessions": {
+                    "type": "integer"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "vote_cutoff": {
+                    "description": "votes last updated before are anonymized",
+                    "type": "string"
+                }
+            }
+        },
+        "models.SaveVoteRequest": {
+            "type": "object",
+            "required": [
+                "product_id",
+                "score",
+                "session_id"
+            ],
+            "properties": {
+                "product_id": {
+                    "description": "The product ID\nRequired: true",
+                    "type": "string"
+                },
+                "score": {
+                    "description": "The score (e.g., rating from 1 to 5)\nRequired: true",
+                    "type": "integer",
+                    "maximum": 5,
+                    "minimum": 1
+                },
+                "session_id": {
+                    "description": "The session ID\nRequired: true",
+                    "type": "string"
+                }
+            }
+        },
+        "models.SaveVotesBatchRequest": {
+            "type": "object",
+            "required": [
+                "session_id",
+                "votes"
+            ],
+            "properties": {
+                "atomic": {
+                    "description": "Store all votes or none, requires MongoDB to run as a replica set",
+                    "type": "boolean"
+                },
+                "session_id": {
+                    "description": "The session ID\nRequired: true",
+                    "type": "string"
+                },
+                "votes": {
+                    "description": "The votes, at most one per product\nRequired: true",
                     "type": "array",
+                    "maxItems": 50,
+                    "minItems": 1,
                     "items": {
-                        "$ref": "#/definitions/models.ProductScore"
+                        "$ref": "#/definitions/models.BatchVoteItem"
                     }
                 }
             }
         },
-        "models.GetVotesResponse": {
+        "models.SaveVotesBatchResponse": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "description": "Results of the votes\nRequired: true",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BatchVoteResult"
+                    }
+                }
+            }
+        },
+        "models.Session": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "sessionID": {
+                    "type": "string"
+                },
+                "variants": {
+                    "description": "variants of the experiments running when the session was created",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ExperimentAssignment"
+                    }
+                }
+            }
+        },
+        "models.SessionExport": {
             "type": "object",
             "properties": {
+                "client_fingerprints": {
+                    "description": "ClientFingerprints are the hashes of client IP and user agent stored with the votes",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "exported_at": {
+                    "type": "string"
+                },
+                "session": {
+                    "$ref": "#/definitions/models.Session"
+                },
                 "votes": {
-                    "description": "List of votes\nRequired: true",
+                    "description": "Votes include their moderation state, the stored score is the latest one cast for each product",
                     "type": "array",
                     "items": {
                         "$ref": "#/definitions/models.Vote"
@@ -232,56 +3278,293 @@ const docTemplate = `{
                 }
             }
         },
-        "models.ProductScore": {
+        "models.SimilarProduct": {
             "type": "object",
             "properties": {
-                "avgScore": {
+                "product_id": {
+                    "type": "string"
+                },
+                "similarity": {
                     "type": "number"
                 },
-                "productID": {
+                "support": {
+                    "description": "sessions that rated both products",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.TrendingProduct": {
+            "type": "object",
+            "properties": {
+                "avg_score_change": {
+                    "type": "number"
+                },
+                "baseline_avg_score": {
+                    "description": "BaselineAvgScore is the average of all products in the baseline window if the product had no votes then",
+                    "type": "number"
+                },
+                "baseline_votes": {
+                    "type": "integer"
+                },
+                "product_id": {
                     "type": "string"
                 },
-                "voteCount": {
+                "recent_avg_score": {
+                    "description": "recency weighted",
+                    "type": "number"
+                },
+                "recent_votes": {
                     "type": "integer"
+                },
+                "trend_score": {
+                    "description": "the log of the volume growth plus the weighted change of the average",
+                    "type": "number"
+                },
+                "volume_growth": {
+                    "description": "recency weighted votes in the window over the ones expected at the baseline rate",
+                    "type": "number"
                 }
             }
         },
-        "models.SaveVoteRequest": {
+        "models.UpdateProductRequest": {
             "type": "object",
             "properties": {
-                "product_id": {
-                    "description": "The product ID\nRequired: true",
+                "name": {
+                    "description": "The product name",
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "pinned": {
+                    "description": "Whether the product survives catalog syncs",
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.UpdateWebhookRequest": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "description": "Whether events are sent, deliveries of inactive webhooks end up as dead letters",
+                    "type": "boolean"
+                },
+                "events": {
+                    "description": "Event types to send",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "product_ids": {
+                    "description": "Products to send events for, all products if empty",
+                    "type": "array",
+                    "maxItems": 1000,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "url": {
+                    "description": "The URL events are posted to",
+                    "type": "string",
+                    "maxLength": 2000
+                }
+            }
+        },
+        "models.VariantComparison": {
+            "type": "object",
+            "properties": {
+                "degrees_of_freedom": {
+                    "type": "number"
+                },
+                "difference": {
+                    "description": "Difference is the session average score of the variant minus the one of the control",
+                    "type": "number"
+                },
+                "p_value": {
+                    "description": "two-sided",
+                    "type": "number"
+                },
+                "significant": {
+                    "type": "boolean"
+                },
+                "t_statistic": {
+                    "description": "Welch's t, omitted when a variant has too few voting sessions",
+                    "type": "number"
+                },
+                "variant": {
                     "type": "string"
+                }
+            }
+        },
+        "models.VariantStats": {
+            "type": "object",
+            "properties": {
+                "avg_score": {
+                    "type": "number"
                 },
-                "score": {
-                    "description": "The score (e.g., rating from 1 to 5)\nRequired: true",
+                "session_avg_score": {
+                    "description": "SessionAvgScore and SessionStdDev are the mean and standard deviation of the average scores of the voting sessions",
+                    "type": "number"
+                },
+                "session_std_dev": {
+                    "type": "number"
+                },
+                "sessions": {
+                    "description": "sessions assigned to the variant, but empty sessions deleted by the retention",
                     "type": "integer"
                 },
-                "session_id": {
-                    "description": "The session ID\nRequired: true",
+                "variant": {
                     "type": "string"
+                },
+                "votes": {
+                    "type": "integer"
+                },
+                "voting_sessions": {
+                    "description": "sessions which cast at least one counted vote",
+                    "type": "integer"
                 }
             }
         },
         "models.Vote": {
             "type": "object",
             "properties": {
+                "anonymizedAt": {
+                    "description": "anonymized votes are no longer linked to their session",
+                    "type": "string"
+                },
+                "fraudReasons": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "fraudScore": {
+                    "type": "number"
+                },
                 "id": {
                     "type": "string"
                 },
                 "productID": {
                     "type": "string"
                 },
+                "reviewedAt": {
+                    "type": "string"
+                },
                 "score": {
                     "type": "integer"
                 },
                 "sessionID": {
                     "type": "string"
                 },
+                "status": {
+                    "type": "string"
+                },
                 "updatedAt": {
                     "type": "string"
                 }
             }
+        },
+        "models.Webhook": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "product_ids": {
+                    "description": "empty matches every product",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WebhookAttempt": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status_code": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WebhookAttempt"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "failed_attempts": {
+                    "description": "failed attempts since the delivery was queued or redelivered",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "product_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "webhook_id": {
+                    "type": "string"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
         }
     }
 }`
@@ -0,0 +1,123 @@
+package anomaly
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+
+	"foover/internal/config"
+	"foover/internal/models"
+)
+
+// minStdDev bounds the baseline standard deviation from below. Scores are integers, so a baseline of
+// identical scores would otherwise make any recent change infinitely significant.
+const minStdDev = 0.5
+
+// Detector accumulates the votes of the recent and baseline windows and finds the products
+// whose recent average score moved significantly away from their baseline
+type Detector struct {
+	cfg           config.Anomaly
+	windowStart   time.Time
+	baselineStart time.Time
+	products      map[string]*stats
+}
+
+// stats represents the rolling statistics of a product, the baseline variance is accumulated with Welford's method
+type stats struct {
+	recentVotes   int
+	recentSum     float64
+	baselineVotes int
+	baselineMean  float64
+	baselineM2    float64 // sum of squared deviations from the mean
+}
+
+// NewDetector creates a new Detector of the windows ending at now
+func NewDetector(cfg config.Anomaly, now time.Time) *Detector {
+	windowStart := now.Add(-cfg.Window)
+	return &Detector{
+		cfg:           cfg,
+		windowStart:   windowStart,
+		baselineStart: windowStart.Add(-cfg.BaselineWindow),
+		products:      make(map[string]*stats),
+	}
+}
+
+// BaselineStart returns the start of the baseline window, votes last updated before it are ignored
+func (d *Detector) BaselineStart() time.Time {
+	return d.baselineStart
+}
+
+// AddVote adds a counted vote to the window it was last updated in
+func (d *Detector) AddVote(vote models.Vote) {
+	if vote.UpdatedAt.Before(d.baselineStart) {
+		return
+	}
+
+	s, ok := d.products[vote.ProductID]
+	if !ok {
+		s = &stats{}
+		d.products[vote.ProductID] = s
+	}
+
+	score := float64(vote.Score)
+	if !vote.UpdatedAt.Before(d.windowStart) {
+		s.recentVotes++
+		s.recentSum += score
+		return
+	}
+
+	s.baselineVotes++
+	delta := score - s.baselineMean
+	s.baselineMean += delta / float64(s.baselineVotes)
+	s.baselineM2 += delta * (score - s.baselineMean)
+}
+
+// Detect returns the anomalies of the products with enough recent and baseline votes, most significant first.
+// The recent average is anomalous when it lies at least ZThreshold standard errors away from the baseline average,
+// the standard error being the baseline standard deviation over the square root of the recent votes.
+func (d *Detector) Detect() []models.Anomaly {
+	var anomalies []models.Anomaly
+	for productID, s := range d.products {
+		if s.recentVotes < d.cfg.MinVotes || s.baselineVotes < d.cfg.MinBaselineVotes || s.baselineVotes < 2 {
+			continue
+		}
+
+		stdDev := math.Sqrt(s.baselineM2 / float64(s.baselineVotes-1))
+		recentAvg := s.recentSum / float64(s.recentVotes)
+		z := (recentAvg - s.baselineMean) / (max(stdDev, minStdDev) / math.Sqrt(float64(s.recentVotes)))
+		if math.Abs(z) < d.cfg.ZThreshold {
+			continue
+		}
+
+		direction := models.AnomalySpike
+		if z < 0 {
+			direction = models.AnomalyDrop
+		}
+		anomalies = append(anomalies, models.Anomaly{
+			ProductID:      productID,
+			Direction:      direction,
+			ZScore:         round(z),
+			RecentAvg:      round(recentAvg),
+			RecentVotes:    s.recentVotes,
+			BaselineAvg:    round(s.baselineMean),
+			BaselineStdDev: round(stdDev),
+			BaselineVotes:  s.baselineVotes,
+			WindowStart:    d.windowStart,
+		})
+	}
+
+	slices.SortFunc(anomalies, func(a, b models.Anomaly) int {
+		return cmp.Or(
+			cmp.Compare(math.Abs(b.ZScore), math.Abs(a.ZScore)),
+			cmp.Compare(a.ProductID, b.ProductID),
+		)
+	})
+
+	return anomalies
+}
+
+// round rounds a value to two decimals
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,70 @@
+package anomaly
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"foover/internal/config"
+	"foover/internal/models"
+	"foover/internal/testutil"
+)
+
+func TestDetect(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	cfg := config.Anomaly{Window: time.Hour, BaselineWindow: 24 * time.Hour, ZThreshold: 3, MinVotes: 3, MinBaselineVotes: 3}
+	recent := now.Add(-10 * time.Minute)
+	baseline := now.Add(-2 * time.Hour)
+
+	tests := []struct {
+		name  string
+		votes [][]models.Vote
+		want  []models.Anomaly
+	}{
+		{
+			name: "drop and spike, most significant first",
+			votes: [][]models.Vote{
+				testutil.Votes("spike", baseline, 3, 3, 3),
+				// Older than the baseline window
+				testutil.Votes("spike", now.Add(-30*time.Hour), 1, 1),
+				testutil.Votes("spike", recent, 5, 5, 5, 5),
+				testutil.Votes("drop", baseline, 4, 5, 4, 5),
+				testutil.Votes("drop", recent, 1, 1, 2),
+			},
+			want: []models.Anomaly{
+				{ProductID: "drop", Direction: models.AnomalyDrop, ZScore: -9.5, RecentAvg: 1.33, RecentVotes: 3, BaselineAvg: 4.5, BaselineStdDev: 0.58, BaselineVotes: 4, WindowStart: now.Add(-time.Hour)},
+				// The baseline standard deviation is bounded by minStdDev
+				{ProductID: "spike", Direction: models.AnomalySpike, ZScore: 8, RecentAvg: 5, RecentVotes: 4, BaselineAvg: 3, BaselineStdDev: 0, BaselineVotes: 3, WindowStart: now.Add(-time.Hour)},
+			},
+		},
+		{
+			name: "below the threshold",
+			votes: [][]models.Vote{
+				testutil.Votes("steady", baseline, 3, 4, 3, 4),
+				testutil.Votes("steady", recent, 4, 4, 3),
+			},
+		},
+		{
+			name: "not enough votes",
+			votes: [][]models.Vote{
+				testutil.Votes("few_recent", baseline, 5, 5, 5),
+				testutil.Votes("few_recent", recent, 1, 1),
+				testutil.Votes("few_baseline", baseline, 5, 5),
+				testutil.Votes("few_baseline", recent, 1, 1, 1),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detector := NewDetector(cfg, now)
+			for _, productVotes := range tt.votes {
+				for _, vote := range productVotes {
+					detector.AddVote(vote)
+				}
+			}
+			if got := detector.Detect(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Detect() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
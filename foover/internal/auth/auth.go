@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+)
+
+const (
+	// ScopeVotesRead allows reading raw votes
+	ScopeVotesRead = "votes:read"
+	// ScopePrivacy allows exporting and erasing session data to honor data subject requests
+	ScopePrivacy = "privacy"
+	// ScopeAdmin allows admin operations and implies every other scope
+	ScopeAdmin = "admin"
+)
+
+const (
+	PrincipalAnonymous = "anonymous"
+	PrincipalAPIKey    = "api_key"
+)
+
+// keyPrefix marks API keys issued by this service so they are easy to recognize in leaks
+const keyPrefix = "fv_"
+
+// Principal represents the authenticated caller of a request
+type Principal struct {
+	Type   string
+	ID     string
+	Name   string
+	Scopes []string
+}
+
+// Anonymous is the principal of public kiosk clients that present no API key
+var Anonymous = Principal{Type: PrincipalAnonymous}
+
+// HasScope reports whether the principal was granted the scope, directly or through the admin scope
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope) || slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the principal
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal stored in ctx, anonymous if there is none
+func PrincipalFromContext(ctx context.Context) Principal {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	if !ok {
+		return Anonymous
+	}
+	return principal
+}
+
+// GenerateKey creates a new random API key
+func GenerateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return keyPrefix + hex.EncodeToString(b), nil
+}
+
+// HashKey returns the hash under which an API key is stored.
+// Keys are random 256-bit values, so a fast hash is sufficient and keeps lookups cheap.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// KeyHint returns a non-secret prefix of the key to help identify it
+func KeyHint(key string) string {
+	if len(key) <= len(keyPrefix)+6 {
+		return key
+	}
+	return key[:len(keyPrefix)+6]
+}
+
+// IsValidScope reports whether scope is known
+func IsValidScope(scope string) bool {
+	switch scope {
+	case ScopeVotesRead, ScopePrivacy, ScopeAdmin:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,16 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Backend defines behaviors of a key-value store with expiring entries.
+// The in-memory backend caches per process, a distributed backend (e.g. Redis with GET, SET EX and DEL)
+// can implement this interface to share entries and their invalidation across replicas.
+type Backend interface {
+	// Get returns the value of key, false if it is missing or expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
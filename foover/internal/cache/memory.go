@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often expired entries are evicted from memory
+const sweepInterval = time.Minute
+
+// entry represents a cached value and when it expires
+type entry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// memoryBackend implements the Backend interface with per-process entries
+type memoryBackend struct {
+	mu        sync.Mutex
+	entries   map[string]entry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryBackend creates a new in-memory Backend
+func NewMemoryBackend() Backend {
+	return &memoryBackend{
+		entries:   make(map[string]entry),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Get returns the value of key unless it expired
+func (m *memoryBackend) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok || !m.now().Before(e.expiresAt) {
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+// Set stores the value of key for ttl
+func (m *memoryBackend) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+	m.entries[key] = entry{value: value, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+// Delete removes the keys, missing keys are ignored
+func (m *memoryBackend) Delete(_ context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		delete(m.entries, key)
+	}
+	return nil
+}
+
+// sweep evicts expired entries, so keys that are never read again don't pile up
+func (m *memoryBackend) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for key, e := range m.entries {
+		if !now.Before(e.expiresAt) {
+			delete(m.entries, key)
+		}
+	}
+}
@@ -8,10 +8,27 @@ import (
 
 // EnvVars represents environment variables
 type EnvVars struct {
-	Service     Service
-	Mongo       Mongo
-	HTTPServer  HTTPServer
-	ExternalAPI ExternalAPIConfig
+	Service        Service
+	Mongo          Mongo
+	HTTPServer     HTTPServer
+	ExternalAPI    ExternalAPIConfig
+	Health         Health
+	Tracing        Tracing
+	RateLimit      RateLimit
+	Auth           Auth
+	Fraud          Fraud
+	Retention      Retention
+	Idempotency    Idempotency
+	ProductCache   ProductCache
+	ScoreCache     ScoreCache
+	VoteBuffer     VoteBuffer
+	Outbox         Outbox
+	Webhook        Webhook
+	Recommendation Recommendation
+	Similarity     Similarity
+	Trending       Trending
+	Anomaly        Anomaly
+	Experiment     Experiment
 }
 
 // Service represents service configurations
@@ -23,15 +40,17 @@ type Service struct {
 
 // Mongo represents mongo configurations
 type Mongo struct {
-	URI               string        `env:"MONGO_URI" default:"mongodb://localhost:27017"` // for demo purposes, otherwise required:"true"
-	Database          string        `env:"MONGO_DATABASE" default:"foover-db"`            // for demo purposes, otherwise required:"true"
-	ConnectTimeout    time.Duration `env:"MONGO_CONNECT_TIMEOUT" default:"10s"`
-	MinPoolSize       uint64        `env:"MONGO_MIN_POOL_SIZE" default:"4"`
-	MaxPoolSize       uint64        `env:"MONGO_MAX_POOL_SIZE" default:"100"`
-	PingTimeout       time.Duration `env:"MONGO_PING_TIMEOUT" default:"10s"`
-	ReadTimeout       time.Duration `env:"MONGO_READ_TIMEOUT" default:"10s"`
-	WriteTimeout      time.Duration `env:"MONGO_WRITE_TIMEOUT" default:"5s"`
-	DisconnectTimeout time.Duration `env:"MONGO_DISCONNECT_TIMEOUT" default:"5s"`
+	URI                    string        `env:"MONGO_URI" default:"mongodb://localhost:27017"` // for demo purposes, otherwise required:"true"
+	Database               string        `env:"MONGO_DATABASE" default:"foover-db"`            // for demo purposes, otherwise required:"true"
+	ConnectTimeout         time.Duration `env:"MONGO_CONNECT_TIMEOUT" default:"10s"`
+	MinPoolSize            uint64        `env:"MONGO_MIN_POOL_SIZE" default:"4"`
+	MaxPoolSize            uint64        `env:"MONGO_MAX_POOL_SIZE" default:"100"`
+	PingTimeout            time.Duration `env:"MONGO_PING_TIMEOUT" default:"10s"`
+	ReadTimeout            time.Duration `env:"MONGO_READ_TIMEOUT" default:"10s"`
+	WriteTimeout           time.Duration `env:"MONGO_WRITE_TIMEOUT" default:"5s"`
+	DisconnectTimeout      time.Duration `env:"MONGO_DISCONNECT_TIMEOUT" default:"5s"`
+	ServerSelectionTimeout time.Duration `env:"MONGO_SERVER_SELECTION_TIMEOUT" default:"5s"` // bounds every operation while no node is reachable
+	BreakerCooldown        time.Duration `env:"MONGO_BREAKER_COOLDOWN" default:"10s"`        // vote path operations fail fast this long after MongoDB was unreachable, 0 disables it
 }
 
 // HTTPServer represents http server configurations
@@ -42,11 +61,177 @@ type HTTPServer struct {
 	IdleTimeout     time.Duration `env:"HTTP_SERVER_IDLE_TIMEOUT" default:"15s"`
 	MaxHeaderBytes  int           `env:"HTTP_SERVER_MAX_HEADER_BYTES" default:"1048576"`
 	ShutdownTimeout time.Duration `env:"HTTP_SERVER_SHUTDOWN_TIMEOUT" default:"10s"`
+	// TrustedProxies is the number of proxies in front of the service appending to X-Forwarded-For,
+	// the client IP is the entry the outermost one appended. X-Forwarded-For is ignored with 0.
+	TrustedProxies int `env:"HTTP_SERVER_TRUSTED_PROXIES" default:"0"`
 }
 
 // ExternalAPIConfig represents external api configurations
 type ExternalAPIConfig struct {
-	ProductAPIURL string `env:"EXTERNAL_API_PRODUCT_URL" default:"https://amperoid.tenants.foodji.io/machines/4bf115ee-303a-4089-a3ea-f6e7aae0ab94"` // for demo purposes, otherwise required:"true"
+	ProductAPIURL       string        `env:"EXTERNAL_API_PRODUCT_URL" default:"https://amperoid.tenants.foodji.io/machines/4bf115ee-303a-4089-a3ea-f6e7aae0ab94"` // for demo purposes, otherwise required:"true"
+	ProductSyncInterval time.Duration `env:"EXTERNAL_API_PRODUCT_SYNC_INTERVAL" default:"1h"`
+}
+
+// Health represents health check configurations
+type Health struct {
+	CheckTimeout  time.Duration `env:"HEALTH_CHECK_TIMEOUT" default:"2s"`
+	ShutdownDelay time.Duration `env:"HEALTH_SHUTDOWN_DELAY" default:"0s"` // time to keep serving after readiness flips, so load balancers can deregister
+}
+
+// Tracing represents OpenTelemetry tracing configurations
+type Tracing struct {
+	Exporter     string  `env:"TRACING_EXPORTER" default:"none"` // one of none, stdout, otlp
+	OTLPEndpoint string  `env:"TRACING_OTLP_ENDPOINT" default:"localhost:4318"`
+	OTLPInsecure bool    `env:"TRACING_OTLP_INSECURE" default:"true"`
+	SampleRatio  float64 `env:"TRACING_SAMPLE_RATIO" default:"1"`
+}
+
+// RateLimit represents rate limiting configurations, rates are in requests per second
+type RateLimit struct {
+	Enabled            bool    `env:"RATE_LIMIT_ENABLED" default:"true"`
+	IPRate             float64 `env:"RATE_LIMIT_IP_RATE" default:"20"`
+	IPBurst            int     `env:"RATE_LIMIT_IP_BURST" default:"40"`
+	SessionRate        float64 `env:"RATE_LIMIT_SESSION_RATE" default:"2"`
+	SessionBurst       int     `env:"RATE_LIMIT_SESSION_BURST" default:"20"`
+	SessionCreateRate  float64 `env:"RATE_LIMIT_SESSION_CREATE_RATE" default:"0.1"`
+	SessionCreateBurst int     `env:"RATE_LIMIT_SESSION_CREATE_BURST" default:"5"`
+}
+
+// Auth represents authentication configurations
+type Auth struct {
+	BootstrapAdminKey string `env:"AUTH_BOOTSTRAP_ADMIN_KEY"` // admin key accepted without being stored, used to issue the first API keys
+}
+
+// Fraud represents vote fraud detection configurations
+type Fraud struct {
+	Enabled                  bool          `env:"FRAUD_ENABLED" default:"true"`
+	FlagThreshold            float64       `env:"FRAUD_FLAG_THRESHOLD" default:"1"` // votes scoring at least this are flagged
+	MinSessionAge            time.Duration `env:"FRAUD_MIN_SESSION_AGE" default:"5s"`
+	MaxVotesPerMinute        int64         `env:"FRAUD_MAX_VOTES_PER_MINUTE" default:"20"`
+	BurstWindow              time.Duration `env:"FRAUD_BURST_WINDOW" default:"10m"`
+	BurstThreshold           int64         `env:"FRAUD_BURST_THRESHOLD" default:"20"` // identical extreme votes on a product within the window
+	FingerprintWindow        time.Duration `env:"FRAUD_FINGERPRINT_WINDOW" default:"1h"`
+	MaxSessionsByFingerprint int64         `env:"FRAUD_MAX_SESSIONS_BY_FINGERPRINT" default:"30"` // kiosks are shared, keep this above the sessions a kiosk serves per window
+}
+
+// Retention represents data retention configurations, a zero age or month count disables the rule
+type Retention struct {
+	Enabled                  bool          `env:"RETENTION_ENABLED" default:"true"`
+	DryRun                   bool          `env:"RETENTION_DRY_RUN" default:"false"` // report what the rules would remove without changing data
+	Interval                 time.Duration `env:"RETENTION_INTERVAL" default:"24h"`
+	EmptySessionMaxAge       time.Duration `env:"RETENTION_EMPTY_SESSION_MAX_AGE" default:"168h"`     // sessions without votes are deleted after this age
+	VoteAnonymizeAfterMonths int           `env:"RETENTION_VOTE_ANONYMIZE_AFTER_MONTHS" default:"12"` // votes not updated for this many months are unlinked from their session
+	BatchSize                int64         `env:"RETENTION_BATCH_SIZE" default:"1000"`
+}
+
+// Idempotency represents Idempotency-Key configurations
+type Idempotency struct {
+	Enabled     bool          `env:"IDEMPOTENCY_ENABLED" default:"true"`
+	TTL         time.Duration `env:"IDEMPOTENCY_TTL" default:"24h"`         // how long keys and their responses are kept for replays
+	LockTimeout time.Duration `env:"IDEMPOTENCY_LOCK_TIMEOUT" default:"1m"` // requests in progress for longer are considered abandoned
+}
+
+// ProductCache represents configurations of the in-memory product set used to validate votes
+type ProductCache struct {
+	Enabled         bool          `env:"PRODUCT_CACHE_ENABLED" default:"true"`
+	RefreshInterval time.Duration `env:"PRODUCT_CACHE_REFRESH_INTERVAL" default:"1m"` // picks up product changes made through other replicas
+}
+
+// ScoreCache represents configurations of the aggregated score cache
+type ScoreCache struct {
+	Enabled bool          `env:"SCORE_CACHE_ENABLED" default:"true"`
+	TTL     time.Duration `env:"SCORE_CACHE_TTL" default:"30s"` // bounds staleness from votes changed outside the services, e.g. by another replica with the in-memory backend
+}
+
+// VoteBuffer represents configurations of the local buffer votes are written to while MongoDB is unavailable
+type VoteBuffer struct {
+	Enabled         bool          `env:"VOTE_BUFFER_ENABLED" default:"false"`
+	Dir             string        `env:"VOTE_BUFFER_DIR" default:"data/vote-buffer"` // must be on a persistent volume
+	MaxDepth        int64         `env:"VOTE_BUFFER_MAX_DEPTH" default:"100000"`     // votes are rejected once the buffer holds this many
+	ReplayInterval  time.Duration `env:"VOTE_BUFFER_REPLAY_INTERVAL" default:"10s"`
+	ReplayBatchSize int           `env:"VOTE_BUFFER_REPLAY_BATCH_SIZE" default:"500"`
+}
+
+// Outbox represents configurations of the vote event outbox and its relay, the outbox requires MongoDB to run as a replica set
+type Outbox struct {
+	Enabled            bool          `env:"OUTBOX_ENABLED" default:"false"`
+	Publisher          string        `env:"OUTBOX_PUBLISHER" default:"stdout"` // one of stdout, file, nats, kafka
+	FilePath           string        `env:"OUTBOX_FILE_PATH" default:"data/events.jsonl"`
+	NATSURL            string        `env:"OUTBOX_NATS_URL" default:"nats://localhost:4222"`
+	NATSSubject        string        `env:"OUTBOX_NATS_SUBJECT" default:"foover.events"` // must be bound to a JetStream stream
+	KafkaBrokers       []string      `env:"OUTBOX_KAFKA_BROKERS" default:"localhost:9092"`
+	KafkaTopic         string        `env:"OUTBOX_KAFKA_TOPIC" default:"foover.events"`
+	RelayInterval      time.Duration `env:"OUTBOX_RELAY_INTERVAL" default:"1s"`
+	BatchSize          int64         `env:"OUTBOX_BATCH_SIZE" default:"100"`
+	LeaseTTL           time.Duration `env:"OUTBOX_LEASE_TTL" default:"30s"`            // a single replica relays events, others take over after this
+	PublishedRetention time.Duration `env:"OUTBOX_PUBLISHED_RETENTION" default:"168h"` // published events are kept this long for inspection
+}
+
+// Webhook represents configurations of webhook alerts and deliveries
+type Webhook struct {
+	Enabled            bool          `env:"WEBHOOK_ENABLED" default:"true"`
+	ScoreThreshold     float64       `env:"WEBHOOK_SCORE_THRESHOLD" default:"3"`        // score.below_threshold is sent when an average drops below this
+	ScoreMinVotes      int           `env:"WEBHOOK_SCORE_MIN_VOTES" default:"10"`       // averages of fewer votes are too noisy to alert on
+	VoteCountThreshold int           `env:"WEBHOOK_VOTE_COUNT_THRESHOLD" default:"100"` // votes.count_reached is sent when a product reaches this many votes
+	CheckInterval      time.Duration `env:"WEBHOOK_CHECK_INTERVAL" default:"5m"`        // score alerts are raised and cleared by this periodic check
+	DeliveryInterval   time.Duration `env:"WEBHOOK_DELIVERY_INTERVAL" default:"1s"`
+	DeliveryBatchSize  int           `env:"WEBHOOK_DELIVERY_BATCH_SIZE" default:"50"`
+	Timeout            time.Duration `env:"WEBHOOK_TIMEOUT" default:"10s"`
+	MaxAttempts        int           `env:"WEBHOOK_MAX_ATTEMPTS" default:"8"` // deliveries failing this many times become dead letters
+	BackoffBase        time.Duration `env:"WEBHOOK_BACKOFF_BASE" default:"10s"`
+	BackoffMax         time.Duration `env:"WEBHOOK_BACKOFF_MAX" default:"1h"`
+	DeliveryRetention  time.Duration `env:"WEBHOOK_DELIVERY_RETENTION" default:"720h"` // finished deliveries and dead letters are kept this long
+}
+
+// Recommendation represents configurations of the item-item recommendation model
+type Recommendation struct {
+	Enabled         bool          `env:"RECOMMENDATION_ENABLED" default:"true"`
+	RefreshInterval time.Duration `env:"RECOMMENDATION_REFRESH_INTERVAL" default:"1h"`
+	MinSupport      int           `env:"RECOMMENDATION_MIN_SUPPORT" default:"3"`      // sessions that must have rated both products of a similarity
+	Neighbors       int           `env:"RECOMMENDATION_NEIGHBORS" default:"20"`       // most similar products kept per product
+	PopularityPrior float64       `env:"RECOMMENDATION_POPULARITY_PRIOR" default:"5"` // votes at the global average added to every product when ranking the fallback
+}
+
+// Similarity represents configurations of the product similarity index
+type Similarity struct {
+	Enabled             bool          `env:"SIMILARITY_ENABLED" default:"true"`
+	Method              string        `env:"SIMILARITY_METHOD" default:"pearson"`            // pearson or cosine
+	MinSupport          int           `env:"SIMILARITY_MIN_SUPPORT" default:"5"`             // sessions that must have rated both products of a similarity
+	RefreshInterval     time.Duration `env:"SIMILARITY_REFRESH_INTERVAL" default:"10m"`      // the pairs of products with changed or erased votes are recomputed
+	FullRebuildInterval time.Duration `env:"SIMILARITY_FULL_REBUILD_INTERVAL" default:"24h"` // bounds the drift of incremental refreshes
+	LeaseTTL            time.Duration `env:"SIMILARITY_LEASE_TTL" default:"30m"`             // a refresh is abandoned after this so another replica can take over
+}
+
+// Trending represents configurations of the trending products ranking
+type Trending struct {
+	Window          time.Duration `env:"TRENDING_WINDOW" default:"24h"`           // recent votes are compared to the votes of the baseline window before it
+	BaselineWindow  time.Duration `env:"TRENDING_BASELINE_WINDOW" default:"168h"` // how far back the baseline reaches before the window
+	HalfLife        time.Duration `env:"TRENDING_HALF_LIFE" default:"6h"`         // the weight of a recent vote halves with every half-life of age
+	MinVotes        int           `env:"TRENDING_MIN_VOTES" default:"5"`          // products with fewer votes in the window aren't ranked
+	ScoreWeight     float64       `env:"TRENDING_SCORE_WEIGHT" default:"1"`       // weight of a one point change of the average against the log of the volume growth
+	CacheTTL        time.Duration `env:"TRENDING_CACHE_TTL" default:"5m"`
+	RefreshInterval time.Duration `env:"TRENDING_REFRESH_INTERVAL" default:"4m"` // the cached ranking is recomputed in the background before it expires
+}
+
+// Anomaly represents configurations of the detection of anomalous score movements
+type Anomaly struct {
+	Enabled          bool          `env:"ANOMALY_ENABLED" default:"true"`
+	Interval         time.Duration `env:"ANOMALY_INTERVAL" default:"5m"`
+	Window           time.Duration `env:"ANOMALY_WINDOW" default:"1h"`            // recent votes are compared to the statistics of the baseline window before it
+	BaselineWindow   time.Duration `env:"ANOMALY_BASELINE_WINDOW" default:"168h"` // how far back the rolling statistics reach before the window
+	ZThreshold       float64       `env:"ANOMALY_Z_THRESHOLD" default:"3"`        // standard errors the recent average must move by
+	MinVotes         int           `env:"ANOMALY_MIN_VOTES" default:"5"`          // recent averages of fewer votes are too noisy
+	MinBaselineVotes int           `env:"ANOMALY_MIN_BASELINE_VOTES" default:"30"`
+	Cooldown         time.Duration `env:"ANOMALY_COOLDOWN" default:"6h"` // an anomaly of a product isn't recorded again in the same direction within this
+	Notify           bool          `env:"ANOMALY_NOTIFY" default:"true"` // forwards anomalies to webhooks subscribed to score.anomaly
+	Retention        time.Duration `env:"ANOMALY_RETENTION" default:"2160h"`
+}
+
+// Experiment represents configurations of the analysis of A/B experiments
+type Experiment struct {
+	SignificanceLevel float64       `env:"EXPERIMENT_SIGNIFICANCE_LEVEL" default:"0.05"` // family-wise, split across the comparisons to the control
+	MinSessions       int           `env:"EXPERIMENT_MIN_SESSIONS" default:"30"`         // variants with fewer voting sessions aren't tested
+	CacheTTL          time.Duration `env:"EXPERIMENT_CACHE_TTL" default:"30s"`           // how long the running experiments assigning new sessions are cached
 }
 
 // LoadEnvVars loads and returns environment variables
@@ -71,12 +256,145 @@ func LoadEnvVars() (*EnvVars, error) {
 		return nil, fmt.Errorf("loading external api environment variables failed, %s", err.Error())
 	}
 
+	h := Health{}
+	if err := env.Set(&h); err != nil {
+		return nil, fmt.Errorf("loading health environment variables failed, %s", err.Error())
+	}
+
+	t := Tracing{}
+	if err := env.Set(&t); err != nil {
+		return nil, fmt.Errorf("loading tracing environment variables failed, %s", err.Error())
+	}
+
+	rl := RateLimit{}
+	if err := env.Set(&rl); err != nil {
+		return nil, fmt.Errorf("loading rate limit environment variables failed, %s", err.Error())
+	}
+
+	a := Auth{}
+	if err := env.Set(&a); err != nil {
+		return nil, fmt.Errorf("loading auth environment variables failed, %s", err.Error())
+	}
+
+	f := Fraud{}
+	if err := env.Set(&f); err != nil {
+		return nil, fmt.Errorf("loading fraud environment variables failed, %s", err.Error())
+	}
+
+	rt := Retention{}
+	if err := env.Set(&rt); err != nil {
+		return nil, fmt.Errorf("loading retention environment variables failed, %s", err.Error())
+	}
+
+	id := Idempotency{}
+	if err := env.Set(&id); err != nil {
+		return nil, fmt.Errorf("loading idempotency environment variables failed, %s", err.Error())
+	}
+
+	pc := ProductCache{}
+	if err := env.Set(&pc); err != nil {
+		return nil, fmt.Errorf("loading product cache environment variables failed, %s", err.Error())
+	}
+
+	sc := ScoreCache{}
+	if err := env.Set(&sc); err != nil {
+		return nil, fmt.Errorf("loading score cache environment variables failed, %s", err.Error())
+	}
+
+	vb := VoteBuffer{}
+	if err := env.Set(&vb); err != nil {
+		return nil, fmt.Errorf("loading vote buffer environment variables failed, %s", err.Error())
+	}
+
+	o := Outbox{}
+	if err := env.Set(&o); err != nil {
+		return nil, fmt.Errorf("loading outbox environment variables failed, %s", err.Error())
+	}
+
+	wh := Webhook{}
+	if err := env.Set(&wh); err != nil {
+		return nil, fmt.Errorf("loading webhook environment variables failed, %s", err.Error())
+	}
+
+	rc := Recommendation{}
+	if err := env.Set(&rc); err != nil {
+		return nil, fmt.Errorf("loading recommendation environment variables failed, %s", err.Error())
+	}
+
+	si := Similarity{}
+	if err := env.Set(&si); err != nil {
+		return nil, fmt.Errorf("loading similarity environment variables failed, %s", err.Error())
+	}
+
+	tr := Trending{}
+	if err := env.Set(&tr); err != nil {
+		return nil, fmt.Errorf("loading trending environment variables failed, %s", err.Error())
+	}
+
+	an := Anomaly{}
+	if err := env.Set(&an); err != nil {
+		return nil, fmt.Errorf("loading anomaly environment variables failed, %s", err.Error())
+	}
+
+	ex := Experiment{}
+	if err := env.Set(&ex); err != nil {
+		return nil, fmt.Errorf("loading experiment environment variables failed, %s", err.Error())
+	}
+
 	ev := &EnvVars{
-		Service:     s,
-		Mongo:       m,
-		HTTPServer:  hs,
-		ExternalAPI: ea,
+		Service:        s,
+		Mongo:          m,
+		HTTPServer:     hs,
+		ExternalAPI:    ea,
+		Health:         h,
+		Tracing:        t,
+		RateLimit:      rl,
+		Auth:           a,
+		Fraud:          f,
+		Retention:      rt,
+		Idempotency:    id,
+		ProductCache:   pc,
+		ScoreCache:     sc,
+		VoteBuffer:     vb,
+		Outbox:         o,
+		Webhook:        wh,
+		Recommendation: rc,
+		Similarity:     si,
+		Trending:       tr,
+		Anomaly:        an,
+		Experiment:     ex,
+	}
+
+	if err := ev.validateIntervals(); err != nil {
+		return nil, err
 	}
 
 	return ev, nil
 }
+
+// validateIntervals rejects the intervals of background workers that aren't greater than 0, they can't tick at them
+func (ev *EnvVars) validateIntervals() error {
+	intervals := []struct {
+		name     string
+		interval time.Duration
+	}{
+		{"EXTERNAL_API_PRODUCT_SYNC_INTERVAL", ev.ExternalAPI.ProductSyncInterval},
+		{"PRODUCT_CACHE_REFRESH_INTERVAL", ev.ProductCache.RefreshInterval},
+		{"VOTE_BUFFER_REPLAY_INTERVAL", ev.VoteBuffer.ReplayInterval},
+		{"OUTBOX_RELAY_INTERVAL", ev.Outbox.RelayInterval},
+		{"WEBHOOK_CHECK_INTERVAL", ev.Webhook.CheckInterval},
+		{"WEBHOOK_DELIVERY_INTERVAL", ev.Webhook.DeliveryInterval},
+		{"RECOMMENDATION_REFRESH_INTERVAL", ev.Recommendation.RefreshInterval},
+		{"SIMILARITY_REFRESH_INTERVAL", ev.Similarity.RefreshInterval},
+		{"TRENDING_REFRESH_INTERVAL", ev.Trending.RefreshInterval},
+		{"ANOMALY_INTERVAL", ev.Anomaly.Interval},
+		{"RETENTION_INTERVAL", ev.Retention.Interval},
+	}
+	for _, i := range intervals {
+		if i.interval <= 0 {
+			return fmt.Errorf("%s must be greater than 0, got %s", i.name, i.interval)
+		}
+	}
+
+	return nil
+}
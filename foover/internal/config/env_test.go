@@ -0,0 +1,36 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadEnvVarsIntervals(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		value   string
+		wantErr bool
+	}{
+		{name: "defaults"},
+		{name: "positive", env: "OUTBOX_RELAY_INTERVAL", value: "250ms"},
+		{name: "zero", env: "RETENTION_INTERVAL", value: "0s", wantErr: true},
+		{name: "negative", env: "TRENDING_REFRESH_INTERVAL", value: "-1m", wantErr: true},
+		{name: "zero without unit", env: "ANOMALY_INTERVAL", value: "0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				t.Setenv(tt.env, tt.value)
+			}
+
+			_, err := LoadEnvVars()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadEnvVars() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.env) {
+				t.Errorf("LoadEnvVars() error = %v, want it to name %s", err, tt.env)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"foover/internal/config"
+	"foover/internal/models"
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+)
+
+// Supported publishers
+const (
+	PublisherStdout = "stdout"
+	PublisherFile   = "file"
+	PublisherNATS   = "nats"
+	PublisherKafka  = "kafka"
+)
+
+// Headers carried by messages of brokers supporting them
+const (
+	headerEventID   = "Event-Id"
+	headerEventType = "Event-Type"
+	headerSequence  = "Event-Sequence"
+)
+
+// EventPublisher defines behaviors of a destination outbox events are relayed to.
+// Publish returns once the destination acknowledged the event, events may be published more than once
+// so consumers deduplicate them by ID or by key and sequence.
+type EventPublisher interface {
+	Publish(ctx context.Context, event models.OutboxEvent) error
+	Close() error
+}
+
+// NewEventPublisher creates the publisher selected by the configurations
+func NewEventPublisher(cfg config.Outbox) (EventPublisher, error) {
+	switch cfg.Publisher {
+	case PublisherStdout:
+		return NewWriterPublisher(os.Stdout), nil
+	case PublisherFile:
+		return NewFilePublisher(cfg.FilePath)
+	case PublisherNATS:
+		return NewNATSPublisher(cfg.NATSURL, cfg.NATSSubject)
+	case PublisherKafka:
+		return NewKafkaPublisher(cfg.KafkaBrokers, cfg.KafkaTopic), nil
+	default:
+		return nil, fmt.Errorf("unknown event publisher %q", cfg.Publisher)
+	}
+}
+
+// writerPublisher implements the EventPublisher interface by writing events as JSON lines
+type writerPublisher struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// NewWriterPublisher creates an EventPublisher writing events as JSON lines to w
+func NewWriterPublisher(w io.Writer) EventPublisher {
+	return &writerPublisher{
+		w:   w,
+		enc: json.NewEncoder(w),
+	}
+}
+
+// Publish writes the event as a line
+func (p *writerPublisher) Publish(_ context.Context, event models.OutboxEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.enc.Encode(event)
+}
+
+// Close is a no-op, the writer is owned by the caller
+func (p *writerPublisher) Close() error {
+	return nil
+}
+
+// filePublisher implements the EventPublisher interface by appending events as JSON lines to a file
+type filePublisher struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewFilePublisher creates an EventPublisher appending events to the file at path, creating it if needed
+func NewFilePublisher(path string) (EventPublisher, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("creating event file directory failed, %s", err.Error())
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening event file failed, %s", err.Error())
+	}
+
+	return &filePublisher{
+		file: file,
+		enc:  json.NewEncoder(file),
+	}, nil
+}
+
+// Publish appends the event and syncs the file, so a published event survives a crash
+func (p *filePublisher) Publish(_ context.Context, event models.OutboxEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.enc.Encode(event); err != nil {
+		return err
+	}
+	return p.file.Sync()
+}
+
+// Close closes the file
+func (p *filePublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.file.Close()
+}
+
+// natsPublisher implements the EventPublisher interface with NATS JetStream
+type natsPublisher struct {
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+	subject string
+}
+
+// NewNATSPublisher creates an EventPublisher publishing events to a JetStream subject.
+// Events are published with their ID as message ID, so the stream drops republished events within its duplicate window.
+func NewNATSPublisher(url string, subject string) (EventPublisher, error) {
+	conn, err := nats.Connect(url, nats.Name("foover"))
+	if err != nil {
+		return nil, fmt.Errorf("connecting to nats failed, %s", err.Error())
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("creating jetstream context failed, %s", err.Error())
+	}
+
+	return &natsPublisher{
+		conn:    conn,
+		js:      js,
+		subject: subject,
+	}, nil
+}
+
+// Publish publishes the event and waits for the stream to acknowledge it
+func (p *natsPublisher) Publish(ctx context.Context, event models.OutboxEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(p.subject)
+	msg.Data = data
+	msg.Header.Set(headerEventType, event.Type)
+	msg.Header.Set(headerSequence, fmt.Sprint(event.Sequence))
+
+	_, err = p.js.PublishMsg(msg, nats.MsgId(event.ID.Hex()), nats.Context(ctx))
+	return err
+}
+
+// Close drains the connection
+func (p *natsPublisher) Close() error {
+	return p.conn.Drain()
+}
+
+// kafkaPublisher implements the EventPublisher interface with a Kafka compatible broker
+type kafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher creates an EventPublisher producing events to a topic.
+// Events are keyed by their key, so the events of a product land on one partition in the order they are published.
+func NewKafkaPublisher(brokers []string, topic string) EventPublisher {
+	return &kafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			// The relay publishes one event at a time, waiting for a batch to fill would only add latency
+			BatchSize: 1,
+		},
+	}
+}
+
+// Publish produces the event and waits for all in-sync replicas to acknowledge it
+func (p *kafkaPublisher) Publish(ctx context.Context, event models.OutboxEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return p.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(event.Key),
+		Value: data,
+		Headers: []kafka.Header{
+			{Key: headerEventID, Value: []byte(event.ID.Hex())},
+			{Key: headerEventType, Value: []byte(event.Type)},
+			{Key: headerSequence, Value: []byte(fmt.Sprint(event.Sequence))},
+		},
+	})
+}
+
+// Close flushes and closes the writer
+func (p *kafkaPublisher) Close() error {
+	return p.writer.Close()
+}
+
+// fanOutPublisher implements the EventPublisher interface by publishing events to several publishers in turn
+type fanOutPublisher struct {
+	publishers []EventPublisher
+}
+
+// NewFanOutPublisher creates an EventPublisher publishing each event to all publishers in the given order.
+// An event failing on one of them is published to all of them again on retry, which consumers deduplicate.
+func NewFanOutPublisher(publishers ...EventPublisher) EventPublisher {
+	return &fanOutPublisher{publishers: publishers}
+}
+
+// Publish publishes the event to each publisher, stopping at the first failure
+func (p *fanOutPublisher) Publish(ctx context.Context, event models.OutboxEvent) error {
+	for _, publisher := range p.publishers {
+		if err := publisher.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes all publishers
+func (p *fanOutPublisher) Close() error {
+	var errs []error
+	for _, publisher := range p.publishers {
+		errs = append(errs, publisher.Close())
+	}
+	return errors.Join(errs...)
+}
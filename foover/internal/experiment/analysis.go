@@ -0,0 +1,131 @@
+package experiment
+
+import (
+	"math"
+
+	"foover/internal/config"
+	"foover/internal/models"
+)
+
+// Analyzer accumulates the scores of the sessions assigned to the variants of an experiment and compares the variants.
+// Sessions are the unit of analysis since they are what is randomized: each voting session weighs its average score
+// once, however many products it voted on.
+type Analyzer struct {
+	cfg        config.Experiment
+	experiment models.Experiment
+	variants   map[string]*variantStats
+}
+
+// variantStats represents the rolling statistics of a variant, the variance of the session averages
+// is accumulated with Welford's method
+type variantStats struct {
+	sessions       int
+	votingSessions int
+	votes          int
+	scoreSum       int
+	mean           float64
+	m2             float64 // sum of squared deviations from the mean
+}
+
+// NewAnalyzer creates a new Analyzer of the experiment
+func NewAnalyzer(cfg config.Experiment, experiment models.Experiment) *Analyzer {
+	variants := make(map[string]*variantStats, len(experiment.Variants))
+	for _, variant := range experiment.Variants {
+		variants[variant.Name] = &variantStats{}
+	}
+	return &Analyzer{
+		cfg:        cfg,
+		experiment: experiment,
+		variants:   variants,
+	}
+}
+
+// AddSession adds the score of a session assigned to a variant, sessions of unknown variants are ignored
+func (a *Analyzer) AddSession(score models.ExperimentSessionScore) {
+	s, ok := a.variants[score.Variant]
+	if !ok {
+		return
+	}
+
+	s.sessions++
+	if score.Votes == 0 {
+		return
+	}
+	s.votingSessions++
+	s.votes += score.Votes
+	s.scoreSum += score.ScoreSum
+
+	avg := float64(score.ScoreSum) / float64(score.Votes)
+	delta := avg - s.mean
+	s.mean += delta / float64(s.votingSessions)
+	s.m2 += delta * (avg - s.mean)
+}
+
+// Analyze returns the outcome of every variant and the comparison of every other variant to the control.
+// Comparisons are Welch's t-tests of the session averages at the significance level split across them (Bonferroni),
+// so the chance of any false positive stays within the configured level. Variants with fewer than MinSessions voting
+// sessions aren't tested.
+func (a *Analyzer) Analyze() models.GetExperimentAnalysisResponse {
+	variants := a.experiment.Variants
+	response := models.GetExperimentAnalysisResponse{
+		Experiment:  a.experiment,
+		Control:     variants[0].Name,
+		Variants:    make([]models.VariantStats, 0, len(variants)),
+		Comparisons: make([]models.VariantComparison, 0, len(variants)-1),
+	}
+	if len(variants) > 1 {
+		response.SignificanceLevel = a.cfg.SignificanceLevel / float64(len(variants)-1)
+	}
+
+	for _, variant := range variants {
+		s := a.variants[variant.Name]
+		stats := models.VariantStats{
+			Variant:         variant.Name,
+			Sessions:        s.sessions,
+			VotingSessions:  s.votingSessions,
+			Votes:           s.votes,
+			SessionAvgScore: round(s.mean),
+			SessionStdDev:   round(math.Sqrt(s.variance())),
+		}
+		if s.votes > 0 {
+			stats.AvgScore = round(float64(s.scoreSum) / float64(s.votes))
+		}
+		response.Variants = append(response.Variants, stats)
+	}
+
+	control := a.variants[variants[0].Name]
+	for _, variant := range variants[1:] {
+		s := a.variants[variant.Name]
+		comparison := models.VariantComparison{Variant: variant.Name}
+		if s.votingSessions > 0 && control.votingSessions > 0 {
+			comparison.Difference = round(s.mean - control.mean)
+		}
+
+		minSessions := max(a.cfg.MinSessions, 2)
+		if s.votingSessions >= minSessions && control.votingSessions >= minSessions {
+			if t, df, p, ok := welch(s.mean, s.variance(), s.votingSessions, control.mean, control.variance(), control.votingSessions); ok {
+				comparison.Significant = p < response.SignificanceLevel
+				t, df, p = round(t), round(df), math.Round(p*1e4)/1e4
+				comparison.TStatistic = &t
+				comparison.DegreesOfFreedom = &df
+				comparison.PValue = &p
+			}
+		}
+		response.Comparisons = append(response.Comparisons, comparison)
+	}
+
+	return response
+}
+
+// variance returns the sample variance of the session averages
+func (s *variantStats) variance() float64 {
+	if s.votingSessions < 2 {
+		return 0
+	}
+	return s.m2 / float64(s.votingSessions-1)
+}
+
+// round rounds a value to two decimals
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
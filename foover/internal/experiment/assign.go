@@ -0,0 +1,50 @@
+package experiment
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"regexp"
+
+	"foover/internal/models"
+)
+
+// keyPattern restricts experiment keys to names safe in URLs and metric labels
+var keyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// IsValidKey reports whether key can identify an experiment
+func IsValidKey(key string) bool {
+	return keyPattern.MatchString(key)
+}
+
+// Assign returns the variant of each experiment the session is assigned to
+func Assign(experiments []models.Experiment, sessionID string) []models.ExperimentAssignment {
+	var assignments []models.ExperimentAssignment
+	for _, experiment := range experiments {
+		if variant, ok := Variant(experiment, sessionID); ok {
+			assignments = append(assignments, models.ExperimentAssignment{Experiment: experiment.Key, Variant: variant})
+		}
+	}
+	return assignments
+}
+
+// Variant returns the variant of the experiment the session is assigned to. The session ID is hashed with
+// the experiment key, so assignments are deterministic and independent across experiments.
+func Variant(experiment models.Experiment, sessionID string) (string, bool) {
+	total := 0
+	for _, variant := range experiment.Variants {
+		total += variant.Weight
+	}
+	if total <= 0 {
+		return "", false
+	}
+
+	sum := sha256.Sum256([]byte(experiment.Key + "/" + sessionID))
+	bucket := int(binary.BigEndian.Uint64(sum[:8]) % uint64(total))
+	for _, variant := range experiment.Variants {
+		if bucket < variant.Weight {
+			return variant.Name, true
+		}
+		bucket -= variant.Weight
+	}
+	return "", false
+}
@@ -0,0 +1,88 @@
+package experiment
+
+import "math"
+
+// welch returns Welch's t statistic, its degrees of freedom and the two-sided p-value of the difference between
+// the means of two samples of the given variances and sizes. It fails when the standard error is zero.
+func welch(mean1, variance1 float64, n1 int, mean0, variance0 float64, n0 int) (t, df, p float64, ok bool) {
+	se1 := variance1 / float64(n1)
+	se0 := variance0 / float64(n0)
+	se := se1 + se0
+	if se <= 0 {
+		return 0, 0, 0, false
+	}
+
+	t = (mean1 - mean0) / math.Sqrt(se)
+	df = se * se / (se1*se1/float64(n1-1) + se0*se0/float64(n0-1))
+	p = incompleteBeta(df/2, 0.5, df/(df+t*t))
+	return t, df, p, true
+}
+
+// incompleteBeta returns the regularized incomplete beta function I_x(a, b)
+func incompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+	// The continued fraction converges quickly below the mean of the distribution only
+	if x < (a+1)/(a+b+2) {
+		return front * betaFraction(a, b, x) / a
+	}
+	return 1 - front*betaFraction(b, a, 1-x)/b
+}
+
+// betaFraction evaluates the continued fraction of the incomplete beta function with the modified Lentz method
+func betaFraction(a, b, x float64) float64 {
+	const (
+		maxIterations = 300
+		epsilon       = 1e-14
+		tiny          = 1e-300
+	)
+
+	c := 1.0
+	d := 1 - (a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+		// Even step
+		num := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		// Odd step
+		num = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,88 @@
+package experiment
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIncompleteBeta(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b, x float64
+		want    float64
+	}{
+		{name: "uniform", a: 1, b: 1, x: 0.3, want: 0.3},
+		{name: "power", a: 3, b: 1, x: 0.5, want: 0.125},
+		{name: "symmetric at the mean", a: 4.5, b: 4.5, x: 0.5, want: 0.5},
+		{name: "below zero", a: 2, b: 3, x: -0.1, want: 0},
+		{name: "above one", a: 2, b: 3, x: 1.1, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := incompleteBeta(tt.a, tt.b, tt.x); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("incompleteBeta(%v, %v, %v) = %v, want %v", tt.a, tt.b, tt.x, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestStudentPValue checks the two-sided p-values of Student's t distribution welch derives from the incomplete beta function
+func TestStudentPValue(t *testing.T) {
+	tests := []struct {
+		t, df float64
+		want  float64
+	}{
+		{t: 2, df: 10, want: 0.073388},
+		{t: 2.228139, df: 10, want: 0.05},
+		{t: 1.5, df: 3.7, want: 0.213598},
+		{t: 0.3, df: 50, want: 0.765421},
+		{t: 4, df: 2, want: 0.057191},
+		{t: 0, df: 5, want: 1},
+	}
+	for _, tt := range tests {
+		if got := incompleteBeta(tt.df/2, 0.5, tt.df/(tt.df+tt.t*tt.t)); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("p-value of t = %v with %v degrees of freedom = %v, want %v", tt.t, tt.df, got, tt.want)
+		}
+	}
+}
+
+func TestWelch(t *testing.T) {
+	tests := []struct {
+		name                 string
+		mean1, variance1     float64
+		n1                   int
+		mean0, variance0     float64
+		n0                   int
+		wantT, wantDF, wantP float64
+		wantOK               bool
+	}{
+		{
+			name:  "unequal variances",
+			mean1: 5, variance1: 4, n1: 10,
+			mean0: 4, variance0: 9, n0: 12,
+			wantT: 0.932505, wantDF: 19.190546, wantP: 0.362660, wantOK: true,
+		},
+		{
+			name:  "equal means",
+			mean1: 3, variance1: 1, n1: 20,
+			mean0: 3, variance0: 1, n0: 20,
+			wantT: 0, wantDF: 38, wantP: 1, wantOK: true,
+		},
+		{
+			name:  "zero standard error",
+			mean1: 4, variance1: 0, n1: 10,
+			mean0: 3, variance0: 0, n0: 10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotT, gotDF, gotP, ok := welch(tt.mean1, tt.variance1, tt.n1, tt.mean0, tt.variance0, tt.n0)
+			if ok != tt.wantOK {
+				t.Fatalf("welch() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if math.Abs(gotT-tt.wantT) > 1e-6 || math.Abs(gotDF-tt.wantDF) > 1e-6 || math.Abs(gotP-tt.wantP) > 1e-6 {
+				t.Errorf("welch() = (%v, %v, %v), want (%v, %v, %v)", gotT, gotDF, gotP, tt.wantT, tt.wantDF, tt.wantP)
+			}
+		})
+	}
+}
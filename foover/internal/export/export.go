@@ -0,0 +1,192 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"foover/internal/models"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Supported export formats
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+// Formats lists the supported export formats
+var Formats = []string{FormatCSV, FormatNDJSON, FormatParquet}
+
+// parquetRowGroupSize bounds the rows the parquet writer buffers before flushing a row group
+const parquetRowGroupSize = 64 * 1024
+
+// IsValidFormat reports whether format is supported
+func IsValidFormat(format string) bool {
+	return slices.Contains(Formats, format)
+}
+
+// ContentType returns the media type of the format
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// Row represents an exported record, rows define their CSV columns and carry json and parquet tags for the other formats
+type Row interface {
+	VoteRow | ScoreRow
+	columns() []string
+	record() []string
+}
+
+// VoteRow represents an exported vote, client fingerprints are never exported
+type VoteRow struct {
+	SessionID  string    `json:"session_id" parquet:"session_id,dict"`
+	ProductID  string    `json:"product_id" parquet:"product_id,dict"`
+	Score      int32     `json:"score" parquet:"score"`
+	Status     string    `json:"status" parquet:"status,dict"`
+	FraudScore float64   `json:"fraud_score" parquet:"fraud_score"`
+	UpdatedAt  time.Time `json:"updated_at" parquet:"updated_at,timestamp(millisecond)"`
+}
+
+// NewVoteRow converts a vote into its exported form, votes stored before moderation are reported as approved
+func NewVoteRow(vote models.Vote) VoteRow {
+	status := vote.Status
+	if status == "" {
+		status = models.VoteStatusApproved
+	}
+	return VoteRow{
+		SessionID:  vote.SessionID,
+		ProductID:  vote.ProductID,
+		Score:      int32(vote.Score),
+		Status:     status,
+		FraudScore: vote.FraudScore,
+		UpdatedAt:  vote.UpdatedAt.UTC(),
+	}
+}
+
+func (VoteRow) columns() []string {
+	return []string{"session_id", "product_id", "score", "status", "fraud_score", "updated_at"}
+}
+
+func (v VoteRow) record() []string {
+	return []string{
+		v.SessionID,
+		v.ProductID,
+		strconv.Itoa(int(v.Score)),
+		v.Status,
+		strconv.FormatFloat(v.FraudScore, 'f', -1, 64),
+		v.UpdatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+// ScoreRow represents an exported aggregated product score
+type ScoreRow struct {
+	ProductID string  `json:"product_id" parquet:"product_id"`
+	AvgScore  float64 `json:"avg_score" parquet:"avg_score"`
+	VoteCount int64   `json:"vote_count" parquet:"vote_count"`
+}
+
+// NewScoreRow converts an aggregated product score into its exported form
+func NewScoreRow(score models.ProductScore) ScoreRow {
+	return ScoreRow{
+		ProductID: score.ProductID,
+		AvgScore:  score.AvgScore,
+		VoteCount: int64(score.VoteCount),
+	}
+}
+
+func (ScoreRow) columns() []string {
+	return []string{"product_id", "avg_score", "vote_count"}
+}
+
+func (s ScoreRow) record() []string {
+	return []string{
+		s.ProductID,
+		strconv.FormatFloat(s.AvgScore, 'f', -1, 64),
+		strconv.FormatInt(s.VoteCount, 10),
+	}
+}
+
+// Writer defines behaviors of a streaming export writer, Close must be called to complete the output
+type Writer[T Row] interface {
+	Write(row T) error
+	Close() error
+}
+
+// NewWriter creates a new Writer encoding rows in the given format to w
+func NewWriter[T Row](format string, w io.Writer) (Writer[T], error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter[T](w)
+	case FormatNDJSON:
+		return &ndjsonWriter[T]{encoder: json.NewEncoder(w)}, nil
+	case FormatParquet:
+		return &parquetWriter[T]{writer: parquet.NewGenericWriter[T](w, parquet.MaxRowsPerRowGroup(parquetRowGroupSize))}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// csvWriter writes rows as CSV with a header line
+type csvWriter[T Row] struct {
+	writer *csv.Writer
+}
+
+func newCSVWriter[T Row](w io.Writer) (*csvWriter[T], error) {
+	var zero T
+	writer := csv.NewWriter(w)
+	if err := writer.Write(zero.columns()); err != nil {
+		return nil, err
+	}
+	return &csvWriter[T]{writer: writer}, nil
+}
+
+func (c *csvWriter[T]) Write(row T) error {
+	return c.writer.Write(row.record())
+}
+
+func (c *csvWriter[T]) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// ndjsonWriter writes rows as newline delimited JSON
+type ndjsonWriter[T Row] struct {
+	encoder *json.Encoder
+}
+
+func (n *ndjsonWriter[T]) Write(row T) error {
+	return n.encoder.Encode(row)
+}
+
+func (n *ndjsonWriter[T]) Close() error {
+	return nil
+}
+
+// parquetWriter writes rows as an Apache Parquet file, row groups are flushed as they fill up
+type parquetWriter[T Row] struct {
+	writer *parquet.GenericWriter[T]
+}
+
+func (p *parquetWriter[T]) Write(row T) error {
+	_, err := p.writer.Write([]T{row})
+	return err
+}
+
+func (p *parquetWriter[T]) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,150 @@
+package fraud
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"time"
+
+	"foover/internal/config"
+	"foover/internal/models"
+)
+
+// Reasons reported for suspicious votes
+const (
+	ReasonYoungSession  = "young_session"
+	ReasonHighVoteRate  = "high_vote_rate"
+	ReasonExtremeBurst  = "extreme_score_burst"
+	ReasonClientCluster = "client_cluster"
+)
+
+// weights of the heuristics, a vote is flagged once the sum of the triggered weights reaches the flag threshold
+var weights = map[string]float64{
+	ReasonYoungSession:  0.4,
+	ReasonHighVoteRate:  0.6,
+	ReasonExtremeBurst:  0.4,
+	ReasonClientCluster: 0.6,
+}
+
+// Store defines the vote history lookups the heuristics need
+type Store interface {
+	GetSession(ctx context.Context, sessionID string) (models.Session, error)
+	CountVotesBySessionSince(ctx context.Context, sessionID string, since time.Time) (int64, error)
+	CountMatchingVotesSince(ctx context.Context, productID string, score int, since time.Time) (int64, error)
+	CountSessionsByFingerprintSince(ctx context.Context, fingerprint string, since time.Time) (int64, error)
+}
+
+// Assessment represents the fraud score of a vote and the heuristics that contributed to it
+type Assessment struct {
+	Score   float64
+	Reasons []string
+	Flagged bool
+}
+
+// Detector defines behaviors of the vote fraud detector
+type Detector interface {
+	Assess(ctx context.Context, vote models.Vote) (Assessment, error)
+	AssessBatch(ctx context.Context, votes []models.Vote) ([]Assessment, error)
+}
+
+// detector implements the Detector interface with heuristics over the vote history
+type detector struct {
+	store Store
+	cfg   config.Fraud
+	now   func() time.Time
+}
+
+// NewDetector creates a new Detector, a disabled detector approves every vote
+func NewDetector(store Store, cfg config.Fraud) Detector {
+	return &detector{
+		store: store,
+		cfg:   cfg,
+		now:   time.Now,
+	}
+}
+
+// Assess scores a vote before it is stored, so the history it is compared with excludes the vote itself
+func (d *detector) Assess(ctx context.Context, vote models.Vote) (Assessment, error) {
+	assessments, err := d.AssessBatch(ctx, []models.Vote{vote})
+	if err != nil {
+		return Assessment{}, err
+	}
+	return assessments[0], nil
+}
+
+// AssessBatch scores votes cast together by one session and client before they are stored.
+// The session and client heuristics are evaluated once for the batch and count the batch itself towards the vote rate.
+func (d *detector) AssessBatch(ctx context.Context, votes []models.Vote) ([]Assessment, error) {
+	assessments := make([]Assessment, len(votes))
+	if !d.cfg.Enabled || len(votes) == 0 {
+		return assessments, nil
+	}
+
+	now := d.now()
+	first := votes[0]
+	var sessionReasons []string
+
+	// Scripts create a session and vote right away, people need a moment to pick a product
+	session, err := d.store.GetSession(ctx, first.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if now.Sub(session.CreatedAt) < d.cfg.MinSessionAge {
+		sessionReasons = append(sessionReasons, ReasonYoungSession)
+	}
+
+	votesLastMinute, err := d.store.CountVotesBySessionSince(ctx, first.SessionID, now.Add(-time.Minute))
+	if err != nil {
+		return nil, err
+	}
+	if votesLastMinute+int64(len(votes)) > d.cfg.MaxVotesPerMinute {
+		sessionReasons = append(sessionReasons, ReasonHighVoteRate)
+	}
+
+	if first.Fingerprint != "" {
+		sessions, err := d.store.CountSessionsByFingerprintSince(ctx, first.Fingerprint, now.Add(-d.cfg.FingerprintWindow))
+		if err != nil {
+			return nil, err
+		}
+		if sessions >= d.cfg.MaxSessionsByFingerprint {
+			sessionReasons = append(sessionReasons, ReasonClientCluster)
+		}
+	}
+
+	for i, vote := range votes {
+		reasons := slices.Clone(sessionReasons)
+
+		// Bursts of identical extreme ratings on a product across sessions are typical for rating stuffing
+		if vote.Score == 1 || vote.Score == 5 {
+			matching, err := d.store.CountMatchingVotesSince(ctx, vote.ProductID, vote.Score, now.Add(-d.cfg.BurstWindow))
+			if err != nil {
+				return nil, err
+			}
+			if matching >= d.cfg.BurstThreshold {
+				reasons = append(reasons, ReasonExtremeBurst)
+			}
+		}
+
+		var score float64
+		for _, reason := range reasons {
+			score += weights[reason]
+		}
+		assessments[i] = Assessment{
+			Score:   score,
+			Reasons: reasons,
+			Flagged: score >= d.cfg.FlagThreshold,
+		}
+	}
+
+	return assessments, nil
+}
+
+// Fingerprint returns a hash identifying a client by IP and user agent without storing either
+func Fingerprint(clientIP, userAgent string) string {
+	if clientIP == "" && userAgent == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(clientIP + "|" + userAgent))
+	return hex.EncodeToString(sum[:16])
+}
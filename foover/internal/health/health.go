@@ -0,0 +1,198 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+	// StatusDegraded marks a failed dependency covered by a fallback, it doesn't fail readiness
+	StatusDegraded = "degraded"
+)
+
+// Checker defines behaviors of a dependency health check
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// DetailsReporter is implemented by checkers that report details alongside their status, e.g. a queue depth
+type DetailsReporter interface {
+	Details() map[string]interface{}
+}
+
+// CheckerFunc allows the use of ordinary functions as health checkers
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f(ctx)
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// WithFallback wraps a checker so its failure is reported as degraded rather than down while the fallback passes
+func WithFallback(checker, fallback Checker) Checker {
+	return &fallbackChecker{checker: checker, fallback: fallback}
+}
+
+// fallbackChecker implements the Checker interface for a dependency covered by a fallback
+type fallbackChecker struct {
+	checker  Checker
+	fallback Checker
+}
+
+// degradedError marks the failure of a check whose fallback passed
+type degradedError struct {
+	err error
+}
+
+func (e *degradedError) Error() string { return e.err.Error() }
+
+func (e *degradedError) Unwrap() error { return e.err }
+
+// Check runs the checker and, if it fails, the fallback
+func (c *fallbackChecker) Check(ctx context.Context) error {
+	err := c.checker.Check(ctx)
+	if err == nil {
+		return nil
+	}
+	if fallbackErr := c.fallback.Check(ctx); fallbackErr != nil {
+		return errors.Join(err, fallbackErr)
+	}
+	return &degradedError{err: err}
+}
+
+// Result represents the outcome of a single health check
+type Result struct {
+	Name    string
+	Status  string
+	Latency time.Duration
+	Error   string
+	Details map[string]interface{}
+}
+
+// Report represents the outcome of all registered health checks
+type Report struct {
+	Status       string
+	ShuttingDown bool
+	Checks       []Result
+}
+
+// Registry defines behaviors of the health check registry
+type Registry interface {
+	Register(name string, checker Checker)
+	Check(ctx context.Context) Report
+	SetShuttingDown()
+	IsShuttingDown() bool
+}
+
+// registry implements the Registry interface
+type registry struct {
+	mu           sync.RWMutex
+	checkers     map[string]Checker
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+// NewRegistry creates a new Registry, each check is bounded by the given timeout
+func NewRegistry(timeout time.Duration) Registry {
+	return &registry{
+		checkers: make(map[string]Checker),
+		timeout:  timeout,
+	}
+}
+
+// Register adds a named checker to the registry, replacing any checker with the same name
+func (r *registry) Register(name string, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkers[name] = checker
+}
+
+// Check runs all registered checkers concurrently and collects their results
+func (r *registry) Check(ctx context.Context) Report {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.checkers))
+	for name := range r.checkers {
+		names = append(names, name)
+	}
+	checkers := make(map[string]Checker, len(r.checkers))
+	for name, checker := range r.checkers {
+		checkers[name] = checker
+	}
+	r.mu.RUnlock()
+
+	sort.Strings(names)
+
+	results := make([]Result, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			results[i] = r.run(ctx, name, checkers[name])
+		}(i, name)
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:       StatusUp,
+		ShuttingDown: r.IsShuttingDown(),
+		Checks:       results,
+	}
+	for _, result := range results {
+		if result.Status == StatusDown {
+			report.Status = StatusDown
+		} else if result.Status == StatusDegraded && report.Status == StatusUp {
+			report.Status = StatusDegraded
+		}
+	}
+	if report.ShuttingDown {
+		report.Status = StatusDown
+	}
+
+	return report
+}
+
+// SetShuttingDown marks the service as shutting down so it reports as not ready
+func (r *registry) SetShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+// IsShuttingDown reports whether the service is shutting down
+func (r *registry) IsShuttingDown() bool {
+	return r.shuttingDown.Load()
+}
+
+// run executes a single checker within the registry timeout
+func (r *registry) run(ctx context.Context, name string, checker Checker) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := checker.Check(ctx)
+
+	result := Result{
+		Name:    name,
+		Status:  StatusUp,
+		Latency: time.Since(start),
+	}
+	var degraded *degradedError
+	switch {
+	case errors.As(err, &degraded):
+		result.Status = StatusDegraded
+		result.Error = err.Error()
+	case err != nil:
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	if reporter, ok := checker.(DetailsReporter); ok {
+		result.Details = reporter.Details()
+	}
+
+	return result
+}
@@ -0,0 +1,187 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"foover/internal/models"
+)
+
+// Supported import formats
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// Formats lists the supported import formats
+var Formats = []string{FormatCSV, FormatNDJSON}
+
+// maxLineBytes bounds a single NDJSON line
+const maxLineBytes = 64 * 1024
+
+// IsValidFormat reports whether format is supported
+func IsValidFormat(format string) bool {
+	return slices.Contains(Formats, format)
+}
+
+// VoteRecord represents a vote read from an import file, Err is set when the row couldn't be parsed
+type VoteRecord struct {
+	// Row is the 1-based row number, excluding the CSV header
+	Row       int
+	Vote      models.SaveVoteRequest
+	Timestamp time.Time
+	Err       error
+}
+
+// VoteReader defines behaviors of a streaming reader of imported votes
+type VoteReader interface {
+	// Next returns the next record, io.EOF once all records are read and any other error if the input is unreadable
+	Next() (VoteRecord, error)
+}
+
+// NewVoteReader creates a new VoteReader reading votes in the given format from r
+func NewVoteReader(format string, r io.Reader) (VoteReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 4096), maxLineBytes)
+		return &ndjsonReader{scanner: scanner}, nil
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+// csvReader reads votes from a CSV file whose header names the session_id, product_id, score and timestamp columns
+type csvReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+	row     int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading csv header failed, %s", err.Error())
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"session_id", "product_id", "score", "timestamp"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("csv header must contain a %s column", name)
+		}
+	}
+
+	return &csvReader{reader: reader, columns: columns}, nil
+}
+
+func (c *csvReader) Next() (VoteRecord, error) {
+	record, err := c.reader.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		// Malformed rows don't stop the import, the reader continues with the next row
+		c.row++
+		return VoteRecord{Row: c.row, Err: err}, nil
+	}
+	if err != nil {
+		return VoteRecord{}, err
+	}
+	c.row++
+
+	field := func(name string) string {
+		if i := c.columns[name]; i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	rec := VoteRecord{
+		Row: c.row,
+		Vote: models.SaveVoteRequest{
+			SessionID: field("session_id"),
+			ProductID: field("product_id"),
+		},
+	}
+	if rec.Vote.Score, err = strconv.Atoi(field("score")); err != nil {
+		rec.Err = fmt.Errorf("score %q is not a number", field("score"))
+		return rec, nil
+	}
+	rec.Timestamp, rec.Err = parseTimestamp(field("timestamp"))
+	return rec, nil
+}
+
+// ndjsonReader reads votes from newline delimited JSON objects, blank lines are skipped
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	row     int
+}
+
+// ndjsonVote represents a vote line of an NDJSON import
+type ndjsonVote struct {
+	SessionID string `json:"session_id"`
+	ProductID string `json:"product_id"`
+	Score     int    `json:"score"`
+	Timestamp string `json:"timestamp"`
+}
+
+func (n *ndjsonReader) Next() (VoteRecord, error) {
+	for n.scanner.Scan() {
+		line := bytes.TrimSpace(n.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		n.row++
+
+		var vote ndjsonVote
+		if err := json.Unmarshal(line, &vote); err != nil {
+			return VoteRecord{Row: n.row, Err: fmt.Errorf("invalid json, %s", err.Error())}, nil
+		}
+
+		rec := VoteRecord{
+			Row: n.row,
+			Vote: models.SaveVoteRequest{
+				SessionID: vote.SessionID,
+				ProductID: vote.ProductID,
+				Score:     vote.Score,
+			},
+		}
+		rec.Timestamp, rec.Err = parseTimestamp(vote.Timestamp)
+		return rec, nil
+	}
+	if err := n.scanner.Err(); err != nil {
+		return VoteRecord{}, err
+	}
+	return VoteRecord{}, io.EOF
+}
+
+// parseTimestamp accepts RFC 3339 timestamps and plain dates, which paper surveys usually only have
+func parseTimestamp(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("timestamp is required")
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("timestamp %q must be RFC 3339 or YYYY-MM-DD", value)
+}
@@ -31,6 +31,6 @@ func InitializeLogger(logLevel string) *slog.Logger {
 	if !ok {
 		panic(fmt.Sprintf("unknown log level: %s", logLevel))
 	}
-	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: ll}))
+	return slog.New(&ContextHandler{Handler: slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: ll})})
 
 }
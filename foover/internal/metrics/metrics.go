@@ -0,0 +1,526 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "foover"
+
+// Metrics holds the Prometheus collectors of the service.
+// Collectors are registered on a dedicated registry instead of the global one,
+// so tests can create isolated instances and inspect them with prometheus/testutil.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests        *prometheus.CounterVec
+	httpRequestDuration *prometheus.HistogramVec
+	httpResponseSize    *prometheus.HistogramVec
+
+	rateLimited        *prometheus.CounterVec
+	idempotentRequests *prometheus.CounterVec
+
+	storeOperationDuration *prometheus.HistogramVec
+	storeOperationErrors   *prometheus.CounterVec
+
+	votesSaved    *prometheus.CounterVec
+	votesFlagged  *prometheus.CounterVec
+	votesReviewed *prometheus.CounterVec
+
+	catalogSyncs        *prometheus.CounterVec
+	catalogProducts     prometheus.Gauge
+	catalogLastSyncTime prometheus.Gauge
+
+	productCacheLookups *prometheus.CounterVec
+	productCacheSize    prometheus.Gauge
+	scoreCacheLookups   *prometheus.CounterVec
+
+	voteBufferDepth prometheus.Gauge
+	voteBufferVotes *prometheus.CounterVec
+
+	outboxEvents *prometheus.CounterVec
+
+	webhookEvents     *prometheus.CounterVec
+	webhookDeliveries *prometheus.CounterVec
+
+	recommendationModelProducts prometheus.Gauge
+	recommendations             *prometheus.CounterVec
+	similarityRefreshes         *prometheus.CounterVec
+	trendingCacheLookups        *prometheus.CounterVec
+	anomalies                   *prometheus.CounterVec
+	experimentAssignments       *prometheus.CounterVec
+
+	retentionRuns        *prometheus.CounterVec
+	retentionRecords     *prometheus.CounterVec
+	retentionPending     *prometheus.GaugeVec
+	retentionLastRunTime prometheus.Gauge
+
+	mongoPoolConnections      prometheus.Gauge
+	mongoPoolConnectionsInUse prometheus.Gauge
+	mongoPoolEvents           *prometheus.CounterVec
+}
+
+// New creates a new Metrics with all collectors registered on a fresh registry
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Total number of HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		httpRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latencies by route, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		httpResponseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "response_size_bytes",
+			Help:      "HTTP response body sizes by route.",
+			Buckets:   prometheus.ExponentialBuckets(64, 4, 8),
+		}, []string{"route"}),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "rate_limited_total",
+			Help:      "Total number of requests rejected by the rate limiter by route and rule.",
+		}, []string{"route", "rule"}),
+		idempotentRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "idempotent_requests_total",
+			Help:      "Total number of requests with an Idempotency-Key by outcome.",
+		}, []string{"outcome"}),
+		storeOperationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "store",
+			Name:      "operation_duration_seconds",
+			Help:      "Store operation latencies by method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation"}),
+		storeOperationErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "store",
+			Name:      "operation_errors_total",
+			Help:      "Total number of failed store operations by method.",
+		}, []string{"operation"}),
+		votesSaved: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "votes",
+			Name:      "saved_total",
+			Help:      "Total number of saved votes by product.",
+		}, []string{"product_id"}),
+		votesFlagged: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "votes",
+			Name:      "flagged_total",
+			Help:      "Total number of reasons votes were flagged for, by reason.",
+		}, []string{"reason"}),
+		votesReviewed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "votes",
+			Name:      "reviewed_total",
+			Help:      "Total number of reviewed flagged votes by outcome.",
+		}, []string{"status"}),
+		catalogSyncs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "catalog",
+			Name:      "syncs_total",
+			Help:      "Total number of product catalog syncs by outcome.",
+		}, []string{"outcome"}),
+		catalogProducts: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "catalog",
+			Name:      "products",
+			Help:      "Number of products stored by the last successful catalog sync.",
+		}),
+		catalogLastSyncTime: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "catalog",
+			Name:      "last_success_timestamp_seconds",
+			Help:      "Unix time of the last successful catalog sync.",
+		}),
+		productCacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "product_cache",
+			Name:      "lookups_total",
+			Help:      "Total number of product ID lookups in the in-memory product set by result.",
+		}, []string{"result"}),
+		productCacheSize: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "product_cache",
+			Name:      "products",
+			Help:      "Number of votable products in the in-memory product set.",
+		}),
+		scoreCacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "score_cache",
+			Name:      "lookups_total",
+			Help:      "Total number of aggregated score lookups in the cache by result.",
+		}, []string{"result"}),
+		voteBufferDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "vote_buffer",
+			Name:      "depth",
+			Help:      "Number of votes in the local buffer waiting to be replayed into the store.",
+		}),
+		voteBufferVotes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "vote_buffer",
+			Name:      "votes_total",
+			Help:      "Total number of votes buffered, replayed or dropped by the local buffer by event.",
+		}, []string{"event"}),
+		outboxEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "outbox",
+			Name:      "events_total",
+			Help:      "Total number of outbox events published, failed or deferred behind an earlier event by outcome.",
+		}, []string{"outcome"}),
+		webhookEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "webhook",
+			Name:      "events_total",
+			Help:      "Total number of events queued for delivery to at least one webhook by type.",
+		}, []string{"type"}),
+		webhookDeliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "webhook",
+			Name:      "delivery_attempts_total",
+			Help:      "Total number of webhook delivery attempts by outcome.",
+		}, []string{"outcome"}),
+		recommendationModelProducts: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "recommendation",
+			Name:      "model_products",
+			Help:      "Number of products with at least one similar product in the recommendation model.",
+		}),
+		recommendations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "recommendation",
+			Name:      "recommendations_total",
+			Help:      "Total number of products recommended to sessions by source.",
+		}, []string{"source"}),
+		similarityRefreshes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "similarity_index",
+			Name:      "refreshes_total",
+			Help:      "Total number of completed product similarity index refreshes by mode.",
+		}, []string{"mode"}),
+		trendingCacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "trending",
+			Name:      "cache_lookups_total",
+			Help:      "Total number of trending products lookups in the cache by result.",
+		}, []string{"result"}),
+		anomalies: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "anomaly",
+			Name:      "anomalies_total",
+			Help:      "Total number of recorded score anomalies by direction.",
+		}, []string{"direction"}),
+		experimentAssignments: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "experiment",
+			Name:      "assignments_total",
+			Help:      "Total number of sessions assigned to experiment variants by experiment and variant.",
+		}, []string{"experiment", "variant"}),
+		retentionRuns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "retention",
+			Name:      "runs_total",
+			Help:      "Total number of retention runs by mode and outcome.",
+		}, []string{"mode", "outcome"}),
+		retentionRecords: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "retention",
+			Name:      "records_total",
+			Help:      "Total number of records deleted or anonymized by retention rule.",
+		}, []string{"rule"}),
+		retentionPending: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "retention",
+			Name:      "dry_run_records",
+			Help:      "Number of records the last dry run would delete or anonymize by retention rule.",
+		}, []string{"rule"}),
+		retentionLastRunTime: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "retention",
+			Name:      "last_success_timestamp_seconds",
+			Help:      "Unix time of the last successful retention run.",
+		}),
+		mongoPoolConnections: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "mongo_pool",
+			Name:      "connections",
+			Help:      "Number of open connections in the MongoDB connection pool.",
+		}),
+		mongoPoolConnectionsInUse: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "mongo_pool",
+			Name:      "connections_in_use",
+			Help:      "Number of MongoDB connections checked out of the pool.",
+		}),
+		mongoPoolEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "mongo_pool",
+			Name:      "events_total",
+			Help:      "Total number of MongoDB connection pool events by type.",
+		}, []string{"type"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpRequestDuration,
+		m.httpResponseSize,
+		m.rateLimited,
+		m.idempotentRequests,
+		m.storeOperationDuration,
+		m.storeOperationErrors,
+		m.votesSaved,
+		m.votesFlagged,
+		m.votesReviewed,
+		m.catalogSyncs,
+		m.catalogProducts,
+		m.catalogLastSyncTime,
+		m.productCacheLookups,
+		m.productCacheSize,
+		m.scoreCacheLookups,
+		m.voteBufferDepth,
+		m.voteBufferVotes,
+		m.outboxEvents,
+		m.webhookEvents,
+		m.webhookDeliveries,
+		m.recommendationModelProducts,
+		m.recommendations,
+		m.similarityRefreshes,
+		m.trendingCacheLookups,
+		m.anomalies,
+		m.experimentAssignments,
+		m.retentionRuns,
+		m.retentionRecords,
+		m.retentionPending,
+		m.retentionLastRunTime,
+		m.mongoPoolConnections,
+		m.mongoPoolConnectionsInUse,
+		m.mongoPoolEvents,
+	)
+
+	return m
+}
+
+// Registry returns the registry the collectors are registered on
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler returns an http.Handler exposing the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveHTTPRequest records a served HTTP request
+func (m *Metrics) ObserveHTTPRequest(route, method string, status, size int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(route, method, code).Inc()
+	m.httpRequestDuration.WithLabelValues(route, method, code).Observe(duration.Seconds())
+	m.httpResponseSize.WithLabelValues(route).Observe(float64(size))
+}
+
+// IncRateLimited records a request rejected by the rate limiter
+func (m *Metrics) IncRateLimited(route, rule string) {
+	m.rateLimited.WithLabelValues(route, rule).Inc()
+}
+
+// Outcomes of requests with an Idempotency-Key reported in the idempotency metrics
+const (
+	IdempotencyExecuted   = "executed"
+	IdempotencyReplayed   = "replayed"
+	IdempotencyInProgress = "in_progress"
+	IdempotencyMismatch   = "mismatch"
+)
+
+// IncIdempotentRequests records the outcome of a request with an Idempotency-Key
+func (m *Metrics) IncIdempotentRequests(outcome string) {
+	m.idempotentRequests.WithLabelValues(outcome).Inc()
+}
+
+// ObserveStoreOperation records the latency and outcome of a store operation
+func (m *Metrics) ObserveStoreOperation(operation string, duration time.Duration, err error) {
+	m.storeOperationDuration.WithLabelValues(operation).Observe(duration.Seconds())
+	if err != nil {
+		m.storeOperationErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// IncVotesSaved records a saved vote for the given product
+func (m *Metrics) IncVotesSaved(productID string) {
+	m.votesSaved.WithLabelValues(productID).Inc()
+}
+
+// IncVotesFlagged records a flagged vote for each reason it was flagged for
+func (m *Metrics) IncVotesFlagged(reasons []string) {
+	for _, reason := range reasons {
+		m.votesFlagged.WithLabelValues(reason).Inc()
+	}
+}
+
+// IncVotesReviewed records the review of a flagged vote
+func (m *Metrics) IncVotesReviewed(status string) {
+	m.votesReviewed.WithLabelValues(status).Inc()
+}
+
+// ObserveCatalogSync records the outcome of a product catalog sync
+func (m *Metrics) ObserveCatalogSync(productCount int, err error) {
+	if err != nil {
+		m.catalogSyncs.WithLabelValues("failure").Inc()
+		return
+	}
+	m.catalogSyncs.WithLabelValues("success").Inc()
+	m.catalogProducts.Set(float64(productCount))
+	m.catalogLastSyncTime.SetToCurrentTime()
+}
+
+// ObserveProductCacheLookups records product ID lookups answered by the in-memory product set
+// and those that fell back to the store
+func (m *Metrics) ObserveProductCacheLookups(hits, misses int) {
+	m.productCacheLookups.WithLabelValues("hit").Add(float64(hits))
+	m.productCacheLookups.WithLabelValues("miss").Add(float64(misses))
+}
+
+// SetProductCacheSize records the number of products in the in-memory product set
+func (m *Metrics) SetProductCacheSize(products int) {
+	m.productCacheSize.Set(float64(products))
+}
+
+// IncScoreCacheLookups records an aggregated score lookup answered by the cache or by the store
+func (m *Metrics) IncScoreCacheLookups(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.scoreCacheLookups.WithLabelValues(result).Inc()
+}
+
+// Vote buffer events reported in the vote buffer metrics
+const (
+	VoteBufferBuffered = "buffered"
+	VoteBufferReplayed = "replayed"
+	VoteBufferDropped  = "dropped"
+)
+
+// AddVoteBufferVotes records votes passing through the local vote buffer
+func (m *Metrics) AddVoteBufferVotes(event string, votes int) {
+	m.voteBufferVotes.WithLabelValues(event).Add(float64(votes))
+}
+
+// SetVoteBufferDepth records the number of votes in the local vote buffer
+func (m *Metrics) SetVoteBufferDepth(depth int64) {
+	m.voteBufferDepth.Set(float64(depth))
+}
+
+// Outcomes reported in the outbox metrics
+const (
+	OutboxPublished = "published"
+	OutboxFailed    = "failed"
+	OutboxDeferred  = "deferred"
+)
+
+// IncOutboxEvents records an attempt of the relay to publish an outbox event
+func (m *Metrics) IncOutboxEvents(outcome string) {
+	m.outboxEvents.WithLabelValues(outcome).Inc()
+}
+
+// Outcomes reported in the webhook delivery metrics
+const (
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryRetried   = "retried"
+	WebhookDeliveryDead      = "dead"
+)
+
+// IncWebhookEvents records an event queued for delivery to webhooks
+func (m *Metrics) IncWebhookEvents(eventType string) {
+	m.webhookEvents.WithLabelValues(eventType).Inc()
+}
+
+// IncWebhookDeliveries records a webhook delivery attempt
+func (m *Metrics) IncWebhookDeliveries(outcome string) {
+	m.webhookDeliveries.WithLabelValues(outcome).Inc()
+}
+
+// SetRecommendationModelProducts records the number of products with similar products in the recommendation model
+func (m *Metrics) SetRecommendationModelProducts(products int) {
+	m.recommendationModelProducts.Set(float64(products))
+}
+
+// IncRecommendations records a product recommended to a session
+func (m *Metrics) IncRecommendations(source string) {
+	m.recommendations.WithLabelValues(source).Inc()
+}
+
+// IncSimilarityRefreshes records a completed refresh of the similarity index, rebuilding all pairs or only the changed ones
+func (m *Metrics) IncSimilarityRefreshes(full bool) {
+	mode := "incremental"
+	if full {
+		mode = "full"
+	}
+	m.similarityRefreshes.WithLabelValues(mode).Inc()
+}
+
+// IncTrendingCacheLookups records a trending products lookup answered by the cache or computed
+func (m *Metrics) IncTrendingCacheLookups(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.trendingCacheLookups.WithLabelValues(result).Inc()
+}
+
+// IncAnomalies records a detected score anomaly
+func (m *Metrics) IncAnomalies(direction string) {
+	m.anomalies.WithLabelValues(direction).Inc()
+}
+
+// IncExperimentAssignments records a session assigned to a variant of an experiment
+func (m *Metrics) IncExperimentAssignments(experiment string, variant string) {
+	m.experimentAssignments.WithLabelValues(experiment, variant).Inc()
+}
+
+// Retention rules reported in the retention metrics
+const (
+	RetentionRuleEmptySessions  = "empty_sessions"
+	RetentionRuleAnonymizeVotes = "anonymize_votes"
+)
+
+// ObserveRetentionRun records a retention run and the records its rules deleted or anonymized,
+// or would have in a dry run. Records of a failed run count as far as they were processed.
+func (m *Metrics) ObserveRetentionRun(dryRun bool, records map[string]int64, err error) {
+	mode := "apply"
+	if dryRun {
+		mode = "dry_run"
+	}
+	for rule, count := range records {
+		if dryRun {
+			m.retentionPending.WithLabelValues(rule).Set(float64(count))
+		} else {
+			m.retentionRecords.WithLabelValues(rule).Add(float64(count))
+		}
+	}
+	if err != nil {
+		m.retentionRuns.WithLabelValues(mode, "failure").Inc()
+		return
+	}
+	m.retentionRuns.WithLabelValues(mode, "success").Inc()
+	m.retentionLastRunTime.SetToCurrentTime()
+}
@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// PoolMonitor returns a MongoDB pool monitor that tracks connection pool stats
+func (m *Metrics) PoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			m.mongoPoolEvents.WithLabelValues(e.Type).Inc()
+
+			switch e.Type {
+			case event.ConnectionCreated:
+				m.mongoPoolConnections.Inc()
+			case event.ConnectionClosed:
+				m.mongoPoolConnections.Dec()
+			case event.GetSucceeded:
+				m.mongoPoolConnectionsInUse.Inc()
+			case event.ConnectionReturned:
+				m.mongoPoolConnectionsInUse.Dec()
+			}
+		},
+	}
+}
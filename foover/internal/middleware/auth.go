@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"foover/internal/auth"
+	"foover/internal/log"
+	"foover/internal/models"
+	"foover/internal/service"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// APIKeyHeader is the header carrying the API key, "Authorization: Bearer <key>" is accepted as well
+const APIKeyHeader = "X-API-Key"
+
+// NewAuthMiddleware creates a new middleware resolving the API key of the request into a principal
+// stored in the request context, requests without a key continue as the anonymous kiosk principal
+func NewAuthMiddleware(authService service.AuthService, logger *slog.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			key := apiKeyFromRequest(r)
+			if key == "" {
+				next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(ctx, auth.Anonymous)))
+				return
+			}
+
+			principal, err := authService.Authenticate(ctx, key)
+			if errors.Is(err, service.ErrInvalidAPIKey) {
+				logger.WarnContext(ctx, "Invalid API key", "hint", auth.KeyHint(key))
+				writeError(w, http.StatusUnauthorized, "Invalid API key")
+				return
+			}
+			if err != nil {
+				logger.ErrorContext(ctx, "Failed to authenticate API key", "error", err)
+				writeError(w, http.StatusInternalServerError, "Failed to authenticate API key")
+				return
+			}
+
+			ctx = auth.WithPrincipal(ctx, principal)
+			ctx = log.WithAttrs(ctx, slog.String("principal", principal.Name))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireScope creates a new middleware rejecting requests whose principal lacks the scope,
+// with 401 for anonymous callers and 403 for API keys without the scope
+func RequireScope(scope string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := auth.PrincipalFromContext(r.Context())
+			if principal.Type == auth.PrincipalAnonymous {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="foover"`)
+				writeError(w, http.StatusUnauthorized, "API key required")
+				return
+			}
+			if !principal.HasScope(scope) {
+				writeError(w, http.StatusForbidden, "API key lacks scope "+scope)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// apiKeyFromRequest returns the API key of the request, if any
+func apiKeyFromRequest(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(models.ErrorResponse{Message: message})
+}
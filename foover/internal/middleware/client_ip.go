@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"github.com/gorilla/mux"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type clientIPKey struct{}
+
+// NewClientIPMiddleware creates a new middleware storing the IP of the client in the request context,
+// X-Forwarded-For is only used when the service runs behind trustedProxies trusted proxies
+func NewClientIPMiddleware(trustedProxies int) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), clientIPKey{}, clientIP(r, trustedProxies))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ClientIPFromContext returns the client IP stored in ctx, if any
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// clientIP returns the IP of the client. Behind trusted proxies it is the X-Forwarded-For entry appended by the
+// outermost one, counting from the right since clients can send any entries on the left.
+// If there are fewer entries than proxies, the leftmost one is taken, all of them were appended by proxies.
+func clientIP(r *http.Request, trustedProxies int) string {
+	if trustedProxies > 0 {
+		var entries []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			entries = append(entries, strings.Split(header, ",")...)
+		}
+		if len(entries) > 0 {
+			return strings.TrimSpace(entries[max(len(entries)-trustedProxies, 0)])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
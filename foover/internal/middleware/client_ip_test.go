@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies int
+		forwardedFor   []string
+		want           string
+	}{
+		{name: "direct", want: "192.0.2.1"},
+		{name: "untrusted forwarded for", forwardedFor: []string{"203.0.113.7"}, want: "192.0.2.1"},
+		{name: "one proxy", trustedProxies: 1, forwardedFor: []string{"203.0.113.7"}, want: "203.0.113.7"},
+		{name: "spoofed leftmost entry", trustedProxies: 1, forwardedFor: []string{"198.51.100.99, 203.0.113.7"}, want: "203.0.113.7"},
+		{name: "two proxies", trustedProxies: 2, forwardedFor: []string{"198.51.100.99, 203.0.113.7, 10.0.0.2"}, want: "203.0.113.7"},
+		{name: "repeated headers", trustedProxies: 1, forwardedFor: []string{"198.51.100.99", "203.0.113.7"}, want: "203.0.113.7"},
+		{name: "fewer entries than proxies", trustedProxies: 3, forwardedFor: []string{"203.0.113.7, 10.0.0.2"}, want: "203.0.113.7"},
+		{name: "no forwarded for behind proxy", trustedProxies: 1, want: "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = "192.0.2.1:4321"
+			for _, value := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+
+			if got := clientIP(r, tt.trustedProxies); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
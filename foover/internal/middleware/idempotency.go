@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"foover/internal/log"
+	"foover/internal/models"
+	"foover/internal/service"
+	"github.com/gorilla/mux"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+const (
+	// IdempotencyKeyHeader is the header carrying the client chosen key of a mutating request
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks responses replayed from an earlier request with the same key
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+const (
+	// maxIdempotencyKeyLength bounds client provided keys
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodyBytes bounds the request bodies read to fingerprint a request
+	maxIdempotentBodyBytes = 1 << 20
+	// maxIdempotentResponseBytes bounds the responses stored for replays, larger responses release the key
+	maxIdempotentResponseBytes = 1 << 20
+)
+
+// NewIdempotencyMiddleware creates a new middleware making mutating requests with an Idempotency-Key safe to retry.
+// The first request with a key is executed and its response stored, retries with the same method, URI and body
+// get the stored response replayed, retries while it is in progress get 409 and reusing the key for a different
+// request gets 422. Server errors aren't stored, so the request can be retried.
+func NewIdempotencyMiddleware(idempotencyService service.IdempotencyService, logger *slog.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || !isMutatingMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			if len(key) > maxIdempotencyKeyLength || !isPrintableASCII(key) {
+				writeError(w, http.StatusBadRequest, "Invalid Idempotency-Key")
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBodyBytes+1))
+			if err != nil {
+				logger.ErrorContext(ctx, "Failed to read request body", "error", err)
+				writeError(w, http.StatusBadRequest, "Failed to read request body")
+				return
+			}
+			if len(body) > maxIdempotentBodyBytes {
+				writeError(w, http.StatusRequestEntityTooLarge, "Request body too large for an Idempotency-Key")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx = log.WithAttrs(ctx, slog.String("idempotencyKey", key))
+			r = r.WithContext(ctx)
+
+			stored, err := idempotencyService.Begin(ctx, key, requestFingerprint(r, body))
+			switch {
+			case errors.Is(err, service.ErrIdempotencyKeyInProgress):
+				logger.WarnContext(ctx, "Request with the Idempotency-Key in progress")
+				writeError(w, http.StatusConflict, "A request with this Idempotency-Key is in progress")
+				return
+			case errors.Is(err, service.ErrIdempotencyKeyReused):
+				logger.WarnContext(ctx, "Idempotency-Key reused for a different request")
+				writeError(w, http.StatusUnprocessableEntity, "Idempotency-Key was used for a different request")
+				return
+			case err != nil:
+				logger.ErrorContext(ctx, "Failed to check Idempotency-Key", "error", err)
+				writeError(w, http.StatusInternalServerError, "Failed to check Idempotency-Key")
+				return
+			case stored != nil:
+				logger.InfoContext(ctx, "Replaying response of Idempotency-Key")
+				replayResponse(w, *stored)
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			// The response is sent already, the outcome is stored even if the client went away
+			ctx = context.WithoutCancel(ctx)
+			if rec.statusCode >= http.StatusInternalServerError || rec.overflow {
+				if err := idempotencyService.Release(ctx, key); err != nil {
+					logger.ErrorContext(ctx, "Failed to release Idempotency-Key", "error", err)
+				}
+				return
+			}
+
+			response := models.IdempotentResponse{
+				StatusCode:  rec.statusCode,
+				ContentType: rec.Header().Get("Content-Type"),
+				Body:        rec.body.Bytes(),
+			}
+			if err := idempotencyService.Complete(ctx, key, response); err != nil {
+				logger.ErrorContext(ctx, "Failed to store response of Idempotency-Key", "error", err)
+			}
+		})
+	}
+}
+
+// isMutatingMethod reports whether requests with the method change state
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// requestFingerprint hashes what identifies a request, so a key can't be reused for a different one
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replayResponse writes a stored response marked as replayed
+func replayResponse(w http.ResponseWriter, response models.IdempotentResponse) {
+	if response.ContentType != "" {
+		w.Header().Set("Content-Type", response.ContentType)
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(response.StatusCode)
+	w.Write(response.Body)
+}
+
+// responseRecorder passes a response through while keeping a copy of its status code and body
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+	overflow    bool
+}
+
+// WriteHeader captures the status code of the response
+func (rr *responseRecorder) WriteHeader(code int) {
+	if !rr.wroteHeader {
+		rr.statusCode = code
+		rr.wroteHeader = true
+	}
+	rr.ResponseWriter.WriteHeader(code)
+}
+
+// Write copies the body until it exceeds the stored response size
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.wroteHeader = true
+	if !rr.overflow {
+		if rr.body.Len()+len(b) > maxIdempotentResponseBytes {
+			rr.overflow = true
+			rr.body.Reset()
+		} else {
+			rr.body.Write(b)
+		}
+	}
+	return rr.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the underlying ResponseWriter to http.ResponseController
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
@@ -15,13 +15,13 @@ func NewLoggingMiddleware(logger *slog.Logger) mux.MiddlewareFunc {
 			start := time.Now()
 
 			// Create a response writer that captures the status code
-			ww := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			ww := wrapResponseWriter(w)
 
 			// Process the request
 			next.ServeHTTP(ww, r)
 
 			// Log the request details
-			logger.Info("HTTP request",
+			logger.InfoContext(r.Context(), "HTTP request",
 				"method", r.Method,
 				"uri", r.RequestURI,
 				"status", ww.statusCode,
@@ -31,10 +31,11 @@ func NewLoggingMiddleware(logger *slog.Logger) mux.MiddlewareFunc {
 	}
 }
 
-// responseWriter is a wrapper to capture the status code
+// responseWriter is a wrapper to capture the status code and response size
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	size       int
 }
 
 // WriteHeader captures the status code for logging
@@ -42,3 +43,23 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+// Write captures the number of bytes written to the response body
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.size += n
+	return n, err
+}
+
+// Unwrap exposes the underlying ResponseWriter to http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// wrapResponseWriter reuses the responseWriter of an outer middleware if there is one
+func wrapResponseWriter(w http.ResponseWriter) *responseWriter {
+	if rw, ok := w.(*responseWriter); ok {
+		return rw
+	}
+	return &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+}
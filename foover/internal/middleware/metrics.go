@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"foover/internal/metrics"
+	"github.com/gorilla/mux"
+	"net/http"
+	"time"
+)
+
+// NewMetricsMiddleware creates a new middleware recording request counts and latencies per route
+func NewMetricsMiddleware(m *metrics.Metrics) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := wrapResponseWriter(w)
+
+			next.ServeHTTP(ww, r)
+
+			m.ObserveHTTPRequest(routeTemplate(r), r.Method, ww.statusCode, ww.size, time.Since(start))
+		})
+	}
+}
+
+// routeTemplate returns the matched route template to keep the label cardinality bounded
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			return tmpl
+		}
+	}
+	return "unmatched"
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"foover/internal/metrics"
+	"foover/internal/ratelimit"
+	"github.com/gorilla/mux"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxPeekBodyBytes bounds how much of a request body is read to find its session ID
+const maxPeekBodyBytes = 1 << 20
+
+// RateLimitScope identifies what a rate limit rule is keyed by
+type RateLimitScope string
+
+const (
+	RateLimitByIP      RateLimitScope = "ip"
+	RateLimitBySession RateLimitScope = "session"
+)
+
+// RateLimitRule represents a token bucket limit applied per scope key and route
+type RateLimitRule struct {
+	Name   string // identifies the buckets of the rule, must be unique
+	Scope  RateLimitScope
+	Method string // empty matches all methods
+	Route  string // route template, empty matches all routes
+	Limit  ratelimit.Limit
+}
+
+// NewRateLimitMiddleware creates a new middleware that rejects requests exceeding any matching rule
+// with 429 and reports the most restrictive bucket in RateLimit-* headers
+func NewRateLimitMiddleware(backend ratelimit.Backend, rules []RateLimitRule, m *metrics.Metrics, logger *slog.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			route := routeTemplate(r)
+
+			var (
+				tightest *ratelimit.Decision
+				denied   *ratelimit.Decision
+				ruleName string
+			)
+			for _, rule := range rules {
+				if (rule.Method != "" && rule.Method != r.Method) || (rule.Route != "" && rule.Route != route) {
+					continue
+				}
+
+				subject, ok := rateLimitSubject(r, rule.Scope)
+				if !ok {
+					continue
+				}
+
+				key := fmt.Sprintf("%s:%s:%s %s", rule.Name, subject, r.Method, route)
+				decision, err := backend.Take(ctx, key, rule.Limit)
+				if err != nil {
+					// Fail open, an unavailable backend must not take the API down
+					logger.ErrorContext(ctx, "Failed to apply rate limit", "rule", rule.Name, "error", err)
+					continue
+				}
+
+				if !decision.Allowed && (denied == nil || decision.RetryAfter > denied.RetryAfter) {
+					denied = &decision
+					ruleName = rule.Name
+				}
+				if tightest == nil || decision.Remaining < tightest.Remaining {
+					tightest = &decision
+				}
+			}
+
+			if denied != nil {
+				tightest = denied
+			}
+			if tightest != nil {
+				w.Header().Set("RateLimit-Limit", strconv.Itoa(tightest.Limit))
+				w.Header().Set("RateLimit-Remaining", strconv.Itoa(tightest.Remaining))
+				w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(tightest.Reset.Seconds())))
+			}
+
+			if denied != nil {
+				m.IncRateLimited(route, ruleName)
+				logger.WarnContext(ctx, "Rate limit exceeded", "rule", ruleName)
+
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(denied.RetryAfter.Seconds())))
+				writeError(w, http.StatusTooManyRequests, "Too many requests")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitSubject returns the client IP or session ID the rule is keyed by, false if it is unknown
+func rateLimitSubject(r *http.Request, scope RateLimitScope) (string, bool) {
+	switch scope {
+	case RateLimitByIP:
+		ip := ClientIPFromContext(r.Context())
+		return ip, ip != ""
+	case RateLimitBySession:
+		sessionID := sessionIDFromRequest(r)
+		return sessionID, sessionID != ""
+	default:
+		return "", false
+	}
+}
+
+// sessionIDFromRequest finds the session ID in the path variables or the JSON body of the request,
+// the body is restored so the handler can read it again
+func sessionIDFromRequest(r *http.Request) string {
+	if sessionID := mux.Vars(r)["session_id"]; sessionID != "" {
+		return sessionID
+	}
+
+	if r.Body == nil || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPeekBodyBytes))
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+	if err != nil {
+		return ""
+	}
+
+	var payload struct {
+		SessionID string `json:"session_id"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	return payload.SessionID
+}
+
+func ceilSeconds(seconds float64) int {
+	return int(math.Ceil(seconds))
+}
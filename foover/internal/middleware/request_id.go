@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"foover/internal/log"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"net/http"
+)
+
+// RequestIDHeader is the header carrying the request ID
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client provided request IDs to keep log lines small
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// NewRequestIDMiddleware creates a new middleware that accepts the X-Request-ID of the client or generates one,
+// returns it in the response and attaches it with the matched route to the request context for logging
+func NewRequestIDMiddleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(RequestIDHeader)
+			if !isValidRequestID(requestID) {
+				requestID = uuid.New().String()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			ctx := context.WithValue(r.Context(), requestIDKey{}, requestID)
+			ctx = log.WithAttrs(ctx,
+				slog.String("requestID", requestID),
+				slog.String("route", routeTemplate(r)),
+			)
+			trace.SpanFromContext(ctx).SetAttributes(attribute.String("request.id", requestID))
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, if any
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// isValidRequestID accepts non-empty printable ASCII IDs of bounded length
+func isValidRequestID(id string) bool {
+	return id != "" && len(id) <= maxRequestIDLength && isPrintableASCII(id)
+}
+
+// isPrintableASCII reports whether s consists of printable ASCII characters other than space
+func isPrintableASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x21 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
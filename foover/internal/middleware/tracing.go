@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// NewTracingMiddleware creates a new middleware starting a server span per request,
+// continuing the trace of the caller if a W3C traceparent header is present
+func NewTracingMiddleware() mux.MiddlewareFunc {
+	tracer := otel.Tracer("foover/internal/middleware")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			route := routeTemplate(r)
+			ctx, span := tracer.Start(ctx, fmt.Sprintf("%s %s", r.Method, route),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.HTTPRoute(route),
+					semconv.URLPath(r.URL.Path),
+				),
+			)
+			defer span.End()
+
+			ww := wrapResponseWriter(w)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(ww.statusCode))
+			if ww.statusCode >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(ww.statusCode))
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -8,18 +9,118 @@ import (
 
 // Session represents a user session
 type Session struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty"`
-	SessionID string             `bson:"session_id"`
-	CreatedAt time.Time          `bson:"created_at"`
+	ID        primitive.ObjectID     `bson:"_id,omitempty"`
+	SessionID string                 `bson:"session_id"`
+	CreatedAt time.Time              `bson:"created_at"`
+	Variants  []ExperimentAssignment `bson:"variants,omitempty"` // variants of the experiments running when the session was created
 }
 
+const (
+	// VoteStatusApproved marks votes counted in the aggregated scores, votes without a status are approved
+	VoteStatusApproved = "approved"
+	// VoteStatusFlagged marks suspicious votes waiting for a review
+	VoteStatusFlagged = "flagged"
+	// VoteStatusRejected marks votes rejected by a reviewer
+	VoteStatusRejected = "rejected"
+)
+
 // Vote represents a vote on a product by a session
 type Vote struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty"`
-	SessionID string             `bson:"session_id"`
-	ProductID string             `bson:"product_id"`
-	Score     int                `bson:"score"`
-	UpdatedAt time.Time          `bson:"updated_at"`
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	SessionID    string             `bson:"session_id"`
+	ProductID    string             `bson:"product_id"`
+	Score        int                `bson:"score"`
+	UpdatedAt    time.Time          `bson:"updated_at"`
+	Status       string             `bson:"status,omitempty"`
+	FraudScore   float64            `bson:"fraud_score,omitempty"`
+	FraudReasons []string           `bson:"fraud_reasons,omitempty"`
+	ReviewedAt   *time.Time         `bson:"reviewed_at,omitempty"`
+	Fingerprint  string             `bson:"fingerprint,omitempty" json:"-"` // hash of the client IP and user agent
+	AnonymizedAt *time.Time         `bson:"anonymized_at,omitempty"`        // anonymized votes are no longer linked to their session
+	ClientIP     string             `bson:"-" json:"-"`
+	UserAgent    string             `bson:"-" json:"-"`
+}
+
+// SessionExport represents everything stored for a session, returned to honor data subject access requests
+type SessionExport struct {
+	Session Session `json:"session"`
+	// Votes include their moderation state, the stored score is the latest one cast for each product
+	Votes []Vote `json:"votes"`
+	// ClientFingerprints are the hashes of client IP and user agent stored with the votes
+	ClientFingerprints []string  `json:"client_fingerprints"`
+	ExportedAt         time.Time `json:"exported_at"`
+}
+
+// ErasureAuditEntry records the erasure of a session, it keeps a hash of the session ID only,
+// so the erasure can be proven to a data subject presenting the session ID without retaining it
+type ErasureAuditEntry struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SessionIDHash string             `bson:"session_id_hash" json:"session_id_hash"`
+	VotesDeleted  int64              `bson:"votes_deleted" json:"votes_deleted"`
+	Principal     string             `bson:"principal" json:"principal"`
+	RequestID     string             `bson:"request_id,omitempty" json:"request_id,omitempty"`
+	ErasedAt      time.Time          `bson:"erased_at" json:"erased_at"`
+	ProductIDs    []string           `bson:"product_ids,omitempty" json:"-"` // products of the erased votes, whose similarities are recomputed
+}
+
+// RetentionReport represents the outcome of a run of the retention rules, in a dry run the counts are what the rules would remove
+type RetentionReport struct {
+	DryRun             bool       `json:"dry_run"`
+	EmptySessionCutoff *time.Time `json:"empty_session_cutoff,omitempty"` // sessions without votes created before are deleted
+	EmptySessions      int64      `json:"empty_sessions"`
+	VoteCutoff         *time.Time `json:"vote_cutoff,omitempty"` // votes last updated before are anonymized
+	AnonymizedVotes    int64      `json:"anonymized_votes"`
+	StartedAt          time.Time  `json:"started_at"`
+	FinishedAt         time.Time  `json:"finished_at"`
+}
+
+// IdempotencyRecord represents a mutating request made with an Idempotency-Key and the response it produced
+type IdempotencyRecord struct {
+	Key         string              `bson:"_id"`                // hash of the key and the principal that used it
+	Fingerprint string              `bson:"fingerprint"`        // hash of the method, URI and body of the request
+	Response    *IdempotentResponse `bson:"response,omitempty"` // nil while the request is in progress
+	CreatedAt   time.Time           `bson:"created_at"`
+	ExpiresAt   time.Time           `bson:"expires_at"`
+}
+
+// IdempotentResponse represents a stored response replayed for retries of a request
+type IdempotentResponse struct {
+	StatusCode  int    `bson:"status_code"`
+	ContentType string `bson:"content_type,omitempty"`
+	Body        []byte `bson:"body"`
+}
+
+// Outbox event types
+const (
+	// EventTypeVoteSaved is the type of events published for saved, imported and replayed votes
+	EventTypeVoteSaved = "vote.saved"
+	// EventTypeVoteReviewed is the type of events published for approved or rejected flagged votes
+	EventTypeVoteReviewed = "vote.reviewed"
+)
+
+// OutboxEvent represents an event written in the transaction of the change it describes and relayed to downstream systems.
+// Events with the same key are numbered by Sequence and published in that order.
+type OutboxEvent struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Type        string             `bson:"type" json:"type"`
+	Key         string             `bson:"key" json:"key"`                // the product ID for vote events
+	SessionID   string             `bson:"session_id,omitempty" json:"-"` // the session of vote events, so erasing it reaches them
+	Sequence    int64              `bson:"sequence" json:"sequence"`
+	Payload     json.RawMessage    `bson:"payload" json:"payload"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	PublishedAt *time.Time         `bson:"published_at,omitempty" json:"-"`
+	ExpiresAt   *time.Time         `bson:"expires_at,omitempty" json:"-"` // published events are removed after this
+	Attempts    int                `bson:"attempts" json:"-"`
+	LastError   string             `bson:"last_error,omitempty" json:"-"`
+}
+
+// VoteEvent represents the payload of vote.saved and vote.reviewed events
+type VoteEvent struct {
+	SessionID string    `json:"session_id"`
+	ProductID string    `json:"product_id"`
+	Score     int       `json:"score"`
+	Status    string    `json:"status,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // ProductScore represents the aggregated score of a product
@@ -29,8 +130,254 @@ type ProductScore struct {
 	VoteCount int     `bson:"vote_count"`
 }
 
+const (
+	// ProductSourceCatalog marks products fetched from the external catalog
+	ProductSourceCatalog = "catalog"
+	// ProductSourceManual marks products created through the admin API, catalog syncs leave them untouched
+	ProductSourceManual = "manual"
+)
+
 // Product represents a product with an ID
 type Product struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"-"`
 	ProductID string             `bson:"product_id" json:"product_id"`
+	Name      string             `bson:"name,omitempty" json:"name,omitempty"`
+	Source    string             `bson:"source,omitempty" json:"source,omitempty"`
+	Pinned    bool               `bson:"pinned" json:"pinned"`                             // pinned products survive catalog syncs
+	RetiredAt *time.Time         `bson:"retired_at,omitempty" json:"retired_at,omitempty"` // retired products can't be voted on
+	CreatedAt time.Time          `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// APIKey represents a hashed API key granting scopes to an internal consumer
+type APIKey struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name"`
+	Hint      string             `bson:"hint" json:"hint"`
+	Hash      string             `bson:"hash" json:"-"`
+	Scopes    []string           `bson:"scopes" json:"scopes"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	RevokedAt *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+// Webhook event types
+const (
+	// WebhookEventVoteSaved is sent for every counted vote relayed from the outbox
+	WebhookEventVoteSaved = EventTypeVoteSaved
+	// WebhookEventScoreBelowThreshold is sent when the average score of a product drops below the threshold
+	WebhookEventScoreBelowThreshold = "score.below_threshold"
+	// WebhookEventVoteCountReached is sent when the vote count of a product reaches the threshold
+	WebhookEventVoteCountReached = "votes.count_reached"
+	// WebhookEventScoreAnomaly is sent when the recent average score of a product significantly drops or spikes
+	WebhookEventScoreAnomaly = "score.anomaly"
+)
+
+// WebhookEvents lists the event types webhooks can subscribe to
+var WebhookEvents = []string{WebhookEventVoteSaved, WebhookEventScoreBelowThreshold, WebhookEventVoteCountReached, WebhookEventScoreAnomaly}
+
+// Webhook represents a subscription of an endpoint to events
+type Webhook struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	URL        string             `bson:"url" json:"url"`
+	Events     []string           `bson:"events" json:"events"`
+	ProductIDs []string           `bson:"product_ids,omitempty" json:"product_ids,omitempty"` // empty matches every product
+	Secret     string             `bson:"secret" json:"-"`                                    // signs the payloads
+	Active     bool               `bson:"active" json:"active"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// WebhookPayload represents the body sent to webhooks
+type WebhookPayload struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data" swaggertype:"object"`
+}
+
+// WebhookVote represents the data of vote.saved webhook events, the session isn't shared with receivers
+type WebhookVote struct {
+	ProductID string    `json:"product_id"`
+	Score     int       `json:"score"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ScoreAlert represents the data of score.below_threshold and votes.count_reached events
+type ScoreAlert struct {
+	ProductID string  `json:"product_id"`
+	AvgScore  float64 `json:"avg_score"`
+	VoteCount int     `json:"vote_count"`
+	Threshold float64 `json:"threshold"`
+}
+
+const (
+	// WebhookDeliveryPending marks deliveries waiting for their next attempt
+	WebhookDeliveryPending = "pending"
+	// WebhookDeliverySucceeded marks deliveries acknowledged with a 2xx response
+	WebhookDeliverySucceeded = "succeeded"
+	// WebhookDeliveryDead marks deliveries that ran out of attempts, they are kept as dead letters until redelivered
+	WebhookDeliveryDead = "dead"
+)
+
+// WebhookDelivery represents an event to deliver to a webhook along with the log of its attempts
+type WebhookDelivery struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WebhookID      primitive.ObjectID `bson:"webhook_id" json:"webhook_id"`
+	EventID        string             `bson:"event_id" json:"event_id"`
+	EventType      string             `bson:"event_type" json:"event_type"`
+	ProductID      string             `bson:"product_id" json:"product_id"`
+	SessionID      string             `bson:"session_id,omitempty" json:"-"` // the session of vote events, not sent to receivers
+	Payload        json.RawMessage    `bson:"payload" json:"payload" swaggertype:"object"`
+	Status         string             `bson:"status" json:"status"`
+	Attempts       []WebhookAttempt   `bson:"attempts" json:"attempts"`
+	FailedAttempts int                `bson:"failed_attempts" json:"failed_attempts"` // failed attempts since the delivery was queued or redelivered
+	NextAttemptAt  *time.Time         `bson:"next_attempt_at,omitempty" json:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+	ExpiresAt      *time.Time         `bson:"expires_at,omitempty" json:"-"` // finished deliveries are removed after this
+}
+
+// WebhookAttempt represents an attempt to deliver an event
+type WebhookAttempt struct {
+	At         time.Time `bson:"at" json:"at"`
+	StatusCode int       `bson:"status_code,omitempty" json:"status_code,omitempty"`
+	Error      string    `bson:"error,omitempty" json:"error,omitempty"`
+	DurationMS int64     `bson:"duration_ms" json:"duration_ms"`
+}
+
+const (
+	// RecommendationSourceSimilar marks products predicted from the products a session rated
+	RecommendationSourceSimilar = "similar"
+	// RecommendationSourcePopular marks top-ranked products filling in when there is too little to predict from
+	RecommendationSourcePopular = "popular"
+)
+
+// Recommendation represents a product recommended to a session
+type Recommendation struct {
+	ProductID      string  `json:"product_id"`
+	PredictedScore float64 `json:"predicted_score"` // for popular products, their average pulled towards the global average
+	Source         string  `json:"source"`
+}
+
+// ProductSimilarity represents how similarly the sessions that rated two products scored them,
+// every pair is stored once in each direction
+type ProductSimilarity struct {
+	ProductID        string    `bson:"product_id"`
+	SimilarProductID string    `bson:"similar_product_id"`
+	Similarity       float64   `bson:"similarity"`
+	Support          int       `bson:"support"` // sessions that rated both products
+	BuiltAt          time.Time `bson:"built_at"`
+}
+
+// SimilarProduct represents a product similar to another one
+type SimilarProduct struct {
+	ProductID  string  `bson:"similar_product_id" json:"product_id"`
+	Similarity float64 `bson:"similarity" json:"similarity"`
+	Support    int     `bson:"support" json:"support"` // sessions that rated both products
+}
+
+// SimilarityIndexState represents the progress of the product similarity index
+type SimilarityIndexState struct {
+	ID          string    `bson:"_id"`
+	Method      string    `bson:"method"`
+	BuiltAt     time.Time `bson:"built_at"`      // votes changed since are picked up by the next refresh
+	FullBuiltAt time.Time `bson:"full_built_at"` // when every pair was last rebuilt
+}
+
+// TrendingProduct represents the momentum of a product, its votes in the trending window compared to the baseline window before it
+type TrendingProduct struct {
+	ProductID      string  `json:"product_id"`
+	TrendScore     float64 `json:"trend_score"` // the log of the volume growth plus the weighted change of the average
+	RecentVotes    int     `json:"recent_votes"`
+	BaselineVotes  int     `json:"baseline_votes"`
+	VolumeGrowth   float64 `json:"volume_growth"`    // recency weighted votes in the window over the ones expected at the baseline rate
+	RecentAvgScore float64 `json:"recent_avg_score"` // recency weighted
+	// BaselineAvgScore is the average of all products in the baseline window if the product had no votes then
+	BaselineAvgScore float64 `json:"baseline_avg_score"`
+	AvgScoreChange   float64 `json:"avg_score_change"`
+}
+
+const (
+	// AnomalyDrop marks recent average scores significantly below the baseline
+	AnomalyDrop = "drop"
+	// AnomalySpike marks recent average scores significantly above the baseline
+	AnomalySpike = "spike"
+)
+
+// Anomaly represents a statistically significant movement of the average score of a product,
+// it is also the data of score.anomaly events
+type Anomaly struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProductID      string             `bson:"product_id" json:"product_id"`
+	Direction      string             `bson:"direction" json:"direction"`
+	ZScore         float64            `bson:"z_score" json:"z_score"` // standard errors between the recent and the baseline average
+	RecentAvg      float64            `bson:"recent_avg" json:"recent_avg"`
+	RecentVotes    int                `bson:"recent_votes" json:"recent_votes"`
+	BaselineAvg    float64            `bson:"baseline_avg" json:"baseline_avg"`
+	BaselineStdDev float64            `bson:"baseline_std_dev" json:"baseline_std_dev"`
+	BaselineVotes  int                `bson:"baseline_votes" json:"baseline_votes"`
+	WindowStart    time.Time          `bson:"window_start" json:"window_start"` // recent votes were last updated after this
+	DetectedAt     time.Time          `bson:"detected_at" json:"detected_at"`
+	ExpiresAt      time.Time          `bson:"expires_at" json:"-"`
+}
+
+const (
+	// ExperimentStatusRunning marks experiments assigning new sessions to their variants
+	ExperimentStatusRunning = "running"
+	// ExperimentStatusStopped marks experiments no longer assigning sessions, their analysis is frozen at StoppedAt
+	ExperimentStatusStopped = "stopped"
+)
+
+// Experiment represents an A/B experiment on the presentation of products, the first variant is the control
+type Experiment struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Key         string              `bson:"key" json:"key"`
+	Description string              `bson:"description,omitempty" json:"description,omitempty"`
+	Variants    []ExperimentVariant `bson:"variants" json:"variants"`
+	ProductIDs  []string            `bson:"product_ids,omitempty" json:"product_ids,omitempty"` // products whose votes are analyzed, all products if empty
+	Status      string              `bson:"status" json:"status"`
+	StartedAt   time.Time           `bson:"started_at" json:"started_at"`
+	StoppedAt   *time.Time          `bson:"stopped_at,omitempty" json:"stopped_at,omitempty"`
+}
+
+// ExperimentVariant represents a variant of an experiment, sessions are assigned to it in proportion to its weight
+type ExperimentVariant struct {
+	Name   string `bson:"name" json:"name"`
+	Weight int    `bson:"weight" json:"weight"`
+}
+
+// ExperimentAssignment represents the variant of an experiment a session is assigned to
+type ExperimentAssignment struct {
+	Experiment string `bson:"experiment" json:"experiment"`
+	Variant    string `bson:"variant" json:"variant"`
+}
+
+// ExperimentSessionScore represents the counted votes a session assigned to a variant cast during an experiment
+type ExperimentSessionScore struct {
+	Variant  string `bson:"variant"`
+	Votes    int    `bson:"votes"`
+	ScoreSum int    `bson:"score_sum"`
+}
+
+// VariantStats represents the outcome of a variant of an experiment
+type VariantStats struct {
+	Variant        string  `json:"variant"`
+	Sessions       int     `json:"sessions"`        // sessions assigned to the variant, but empty sessions deleted by the retention
+	VotingSessions int     `json:"voting_sessions"` // sessions which cast at least one counted vote
+	Votes          int     `json:"votes"`
+	AvgScore       float64 `json:"avg_score"`
+	// SessionAvgScore and SessionStdDev are the mean and standard deviation of the average scores of the voting sessions
+	SessionAvgScore float64 `json:"session_avg_score"`
+	SessionStdDev   float64 `json:"session_std_dev"`
+}
+
+// VariantComparison represents the significance test of the difference between a variant and the control
+type VariantComparison struct {
+	Variant string `json:"variant"`
+	// Difference is the session average score of the variant minus the one of the control
+	Difference       float64  `json:"difference"`
+	TStatistic       *float64 `json:"t_statistic,omitempty"` // Welch's t, omitted when a variant has too few voting sessions
+	DegreesOfFreedom *float64 `json:"degrees_of_freedom,omitempty"`
+	PValue           *float64 `json:"p_value,omitempty"` // two-sided
+	Significant      bool     `json:"significant"`
 }
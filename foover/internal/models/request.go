@@ -4,9 +4,41 @@ package models
 //
 // swagger:model SaveVoteRequest
 type SaveVoteRequest struct {
+	// The session ID
+	// Required: true
 	SessionID string `json:"session_id" validate:"required,uuid4"`
+	// The product ID
+	// Required: true
 	ProductID string `json:"product_id" validate:"required,uuid4"`
-	Score     int    `json:"score" validate:"required,min=1,max=5"`
+	// The score (e.g., rating from 1 to 5)
+	// Required: true
+	Score int `json:"score" validate:"required,min=1,max=5"`
+}
+
+// SaveVotesBatchRequest represents the request payload for saving several votes of a session at once
+//
+// swagger:model SaveVotesBatchRequest
+type SaveVotesBatchRequest struct {
+	// The session ID
+	// Required: true
+	SessionID string `json:"session_id" validate:"required,uuid4"`
+	// The votes, at most one per product
+	// Required: true
+	Votes []BatchVoteItem `json:"votes" validate:"required,min=1,max=50"`
+	// Store all votes or none, requires MongoDB to run as a replica set
+	Atomic bool `json:"atomic"`
+}
+
+// BatchVoteItem represents a vote of a batch
+//
+// swagger:model BatchVoteItem
+type BatchVoteItem struct {
+	// The product ID
+	// Required: true
+	ProductID string `json:"product_id" validate:"required,uuid4"`
+	// The score (e.g., rating from 1 to 5)
+	// Required: true
+	Score int `json:"score" validate:"required,min=1,max=5"`
 }
 
 // CreateSessionRequest represents the request to create a session
@@ -15,3 +47,92 @@ type SaveVoteRequest struct {
 type CreateSessionRequest struct { // Created for extension purposes
 
 }
+
+// CreateAPIKeyRequest represents the request to create an API key
+//
+// swagger:model CreateAPIKeyRequest
+type CreateAPIKeyRequest struct {
+	// Name of the consumer the key is issued to
+	// Required: true
+	Name string `json:"name" validate:"required,max=100"`
+	// Scopes granted to the key (votes:read, privacy, admin)
+	// Required: true
+	Scopes []string `json:"scopes" validate:"required,min=1"`
+}
+
+// CreateProductRequest represents the request to create a product
+//
+// swagger:model CreateProductRequest
+type CreateProductRequest struct {
+	// The product ID, generated if empty
+	ProductID string `json:"product_id" validate:"omitempty,uuid4"`
+	// The product name
+	Name string `json:"name" validate:"max=200"`
+	// Whether the product survives catalog syncs
+	Pinned bool `json:"pinned"`
+}
+
+// UpdateProductRequest represents the request to update a product, omitted fields are left unchanged
+//
+// swagger:model UpdateProductRequest
+type UpdateProductRequest struct {
+	// The product name
+	Name *string `json:"name" validate:"omitempty,max=200"`
+	// Whether the product survives catalog syncs
+	Pinned *bool `json:"pinned"`
+}
+
+// CreateWebhookRequest represents the request to create a webhook
+//
+// swagger:model CreateWebhookRequest
+type CreateWebhookRequest struct {
+	// The URL events are posted to
+	// Required: true
+	URL string `json:"url" validate:"required,http_url,max=2000"`
+	// Event types to send (vote.saved, score.below_threshold, votes.count_reached)
+	// Required: true
+	Events []string `json:"events" validate:"required,min=1"`
+	// Products to send events for, all products if empty
+	ProductIDs []string `json:"product_ids" validate:"omitempty,max=1000,dive,uuid4"`
+}
+
+// UpdateWebhookRequest represents the request to update a webhook, omitted fields are left unchanged
+//
+// swagger:model UpdateWebhookRequest
+type UpdateWebhookRequest struct {
+	// The URL events are posted to
+	URL *string `json:"url" validate:"omitempty,http_url,max=2000"`
+	// Event types to send
+	Events *[]string `json:"events" validate:"omitempty,min=1"`
+	// Products to send events for, all products if empty
+	ProductIDs *[]string `json:"product_ids" validate:"omitempty,max=1000,dive,uuid4"`
+	// Whether events are sent, deliveries of inactive webhooks end up as dead letters
+	Active *bool `json:"active"`
+}
+
+// CreateExperimentRequest represents the request to create an experiment
+//
+// swagger:model CreateExperimentRequest
+type CreateExperimentRequest struct {
+	// Unique key of the experiment, lowercase letters, digits, dashes and underscores
+	// Required: true
+	Key string `json:"key" validate:"required,max=64"`
+	// What the experiment changes in the presentation
+	Description string `json:"description" validate:"max=1000"`
+	// Variants of the experiment, the first one is the control
+	// Required: true
+	Variants []ExperimentVariantRequest `json:"variants" validate:"required,min=2,max=10,dive"`
+	// Products whose votes are analyzed, all products if empty
+	ProductIDs []string `json:"product_ids" validate:"omitempty,max=1000,dive,uuid4"`
+}
+
+// ExperimentVariantRequest represents a variant of an experiment to create
+//
+// swagger:model ExperimentVariantRequest
+type ExperimentVariantRequest struct {
+	// Name of the variant, unique within the experiment
+	// Required: true
+	Name string `json:"name" validate:"required,max=64"`
+	// Share of the sessions assigned to the variant relative to the other variants, 1 if omitted
+	Weight int `json:"weight" validate:"omitempty,min=1,max=1000"`
+}
@@ -1,16 +1,24 @@
 package models
 
+import "time"
+
 // CreateSessionResponse represents the response for session creation
 //
 // swagger:model CreateSessionResponse
 type CreateSessionResponse struct {
+	// The unique session ID
+	// Required: true
 	SessionID string `json:"session_id"`
+	// The variants of the running experiments the session is assigned to
+	Variants []ExperimentAssignment `json:"variants,omitempty"`
 }
 
 // GetVotesResponse represents the response containing votes for a session
 //
 // swagger:model GetVotesResponse
 type GetVotesResponse struct {
+	// List of votes
+	// Required: true
 	Votes []Vote `json:"votes"`
 }
 
@@ -18,6 +26,8 @@ type GetVotesResponse struct {
 //
 // swagger:model GetAggregatedScoresResponse
 type GetAggregatedScoresResponse struct {
+	// List of aggregated product scores
+	// Required: true
 	Scores []ProductScore `json:"scores"`
 }
 
@@ -25,6 +35,8 @@ type GetAggregatedScoresResponse struct {
 //
 // swagger:model ErrorResponse
 type ErrorResponse struct {
+	// Error message
+	// Required: true
 	Message string `json:"message"`
 }
 
@@ -32,3 +44,277 @@ type ErrorResponse struct {
 //
 // swagger:model EmptyResponse
 type EmptyResponse struct{}
+
+// HealthResponse represents the response of the liveness and readiness probes
+//
+// swagger:model HealthResponse
+type HealthResponse struct {
+	// One of "up", "degraded" or "down"
+	// Required: true
+	Status string `json:"status"`
+}
+
+// HealthCheckResponse represents the result of a single dependency check
+//
+// swagger:model HealthCheckResponse
+type HealthCheckResponse struct {
+	// Name of the checked dependency
+	// Required: true
+	Name string `json:"name"`
+	// One of "up", "degraded" or "down", a degraded dependency failed but is covered by a fallback
+	// Required: true
+	Status string `json:"status"`
+	// Duration of the check in milliseconds
+	// Required: true
+	LatencyMs float64 `json:"latency_ms"`
+	// Failure reason if the dependency is degraded or down
+	Error string `json:"error,omitempty"`
+	// Additional state reported by the check, e.g. the depth of the vote buffer
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// GetStatusResponse represents the detailed service status
+//
+// swagger:model GetStatusResponse
+type GetStatusResponse struct {
+	// One of "up", "degraded" or "down"
+	// Required: true
+	Status string `json:"status"`
+	// Whether the service is shutting down
+	// Required: true
+	ShuttingDown bool `json:"shutting_down"`
+	// List of dependency checks
+	// Required: true
+	Checks []HealthCheckResponse `json:"checks"`
+}
+
+// CreateAPIKeyResponse represents the response for API key creation
+//
+// swagger:model CreateAPIKeyResponse
+type CreateAPIKeyResponse struct {
+	// The API key, it is only returned once
+	// Required: true
+	Key string `json:"key"`
+	// The stored API key metadata
+	// Required: true
+	APIKey APIKey `json:"api_key"`
+}
+
+// GetAPIKeysResponse represents the response containing API keys
+//
+// swagger:model GetAPIKeysResponse
+type GetAPIKeysResponse struct {
+	// List of API keys
+	// Required: true
+	APIKeys []APIKey `json:"api_keys"`
+}
+
+// GetProductsResponse represents the response containing products
+//
+// swagger:model GetProductsResponse
+type GetProductsResponse struct {
+	// List of products
+	// Required: true
+	Products []Product `json:"products"`
+}
+
+// GetFlaggedVotesResponse represents the response containing votes waiting for a review
+//
+// swagger:model GetFlaggedVotesResponse
+type GetFlaggedVotesResponse struct {
+	// List of flagged votes, oldest first
+	// Required: true
+	Votes []Vote `json:"votes"`
+}
+
+const (
+	// BatchVoteSaved marks a stored vote of a batch
+	BatchVoteSaved = "saved"
+	// BatchVoteRejected marks a vote of a batch that failed validation
+	BatchVoteRejected = "rejected"
+	// BatchVoteFailed marks a vote of a batch that failed to be stored
+	BatchVoteFailed = "failed"
+	// BatchVoteSkipped marks a valid vote of an atomic batch that wasn't stored because of other votes
+	BatchVoteSkipped = "skipped"
+)
+
+// BatchVoteResult represents the outcome of a vote of a batch
+//
+// swagger:model BatchVoteResult
+type BatchVoteResult struct {
+	// The product ID
+	// Required: true
+	ProductID string `json:"product_id"`
+	// One of saved, rejected, failed or skipped
+	// Required: true
+	Status string `json:"status"`
+	// Why the vote wasn't saved
+	Error string `json:"error,omitempty"`
+}
+
+// SaveVotesBatchResponse represents the per-vote results of a batch, in the order of the request
+//
+// swagger:model SaveVotesBatchResponse
+type SaveVotesBatchResponse struct {
+	// Results of the votes
+	// Required: true
+	Results []BatchVoteResult `json:"results"`
+}
+
+// ImportRowError represents a row rejected by a bulk import
+//
+// swagger:model ImportRowError
+type ImportRowError struct {
+	// The 1-based row number, excluding the CSV header
+	// Required: true
+	Row int `json:"row"`
+	// Why the row was rejected
+	// Required: true
+	Message string `json:"message"`
+}
+
+// ImportVotesResponse represents the result of a bulk vote import
+//
+// swagger:model ImportVotesResponse
+type ImportVotesResponse struct {
+	// Whether the import only validated the rows without writing them
+	// Required: true
+	DryRun bool `json:"dry_run"`
+	// Number of imported votes, or votes that would be imported in a dry run
+	// Required: true
+	Imported int `json:"imported"`
+	// Number of rejected rows
+	// Required: true
+	Rejected int `json:"rejected"`
+	// Rejected rows, only the first 1000 are reported
+	// Required: true
+	Errors []ImportRowError `json:"errors"`
+}
+
+// ImportProductsResponse represents the result of a bulk product import
+//
+// swagger:model ImportProductsResponse
+type ImportProductsResponse struct {
+	// Number of imported products
+	// Required: true
+	Imported int `json:"imported"`
+	// Rows that were rejected
+	// Required: true
+	Errors []ImportRowError `json:"errors"`
+}
+
+// CreateWebhookResponse represents the response for webhook creation
+//
+// swagger:model CreateWebhookResponse
+type CreateWebhookResponse struct {
+	// The secret signing the payloads, it is only returned once
+	// Required: true
+	Secret string `json:"secret"`
+	// The stored webhook
+	// Required: true
+	Webhook Webhook `json:"webhook"`
+}
+
+// GetWebhooksResponse represents the response containing webhooks
+//
+// swagger:model GetWebhooksResponse
+type GetWebhooksResponse struct {
+	// List of webhooks
+	// Required: true
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// GetWebhookDeliveriesResponse represents the response containing webhook deliveries
+//
+// swagger:model GetWebhookDeliveriesResponse
+type GetWebhookDeliveriesResponse struct {
+	// List of deliveries with their attempts, newest first
+	// Required: true
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
+
+// GetRecommendationsResponse represents the response containing the recommendations of a session
+//
+// swagger:model GetRecommendationsResponse
+type GetRecommendationsResponse struct {
+	// The session ID
+	// Required: true
+	SessionID string `json:"session_id"`
+	// Products the session hasn't rated, best first
+	// Required: true
+	Recommendations []Recommendation `json:"recommendations"`
+	// When the model behind the recommendations was built
+	// Required: true
+	ModelBuiltAt time.Time `json:"model_built_at"`
+}
+
+// GetSimilarProductsResponse represents the response containing the products similar to a product
+//
+// swagger:model GetSimilarProductsResponse
+type GetSimilarProductsResponse struct {
+	// The product ID
+	// Required: true
+	ProductID string `json:"product_id"`
+	// Similar products, most similar first
+	// Required: true
+	Similar []SimilarProduct `json:"similar"`
+}
+
+// GetTrendingResponse represents the response containing the trending products
+//
+// swagger:model GetTrendingResponse
+type GetTrendingResponse struct {
+	// Trending products, most trending first
+	// Required: true
+	Products []TrendingProduct `json:"products"`
+	// Start of the window of recent votes
+	// Required: true
+	WindowStart time.Time `json:"window_start"`
+	// Start of the baseline window, which ends where the recent window starts
+	// Required: true
+	BaselineStart time.Time `json:"baseline_start"`
+	// When the ranking was computed, it is cached for a while
+	// Required: true
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+// GetAnomaliesResponse represents the response containing detected anomalies
+//
+// swagger:model GetAnomaliesResponse
+type GetAnomaliesResponse struct {
+	// List of anomalies, newest first
+	// Required: true
+	Anomalies []Anomaly `json:"anomalies"`
+}
+
+// GetExperimentsResponse represents the response containing experiments
+//
+// swagger:model GetExperimentsResponse
+type GetExperimentsResponse struct {
+	// List of experiments, newest first
+	// Required: true
+	Experiments []Experiment `json:"experiments"`
+}
+
+// GetExperimentAnalysisResponse represents the comparison of the variants of an experiment
+//
+// swagger:model GetExperimentAnalysisResponse
+type GetExperimentAnalysisResponse struct {
+	// The analyzed experiment
+	// Required: true
+	Experiment Experiment `json:"experiment"`
+	// The control every other variant is compared to
+	// Required: true
+	Control string `json:"control"`
+	// Outcome of each variant
+	// Required: true
+	Variants []VariantStats `json:"variants"`
+	// Comparison of each other variant to the control
+	// Required: true
+	Comparisons []VariantComparison `json:"comparisons"`
+	// Significance level of each comparison, the configured level split across the comparisons
+	// Required: true
+	SignificanceLevel float64 `json:"significance_level"`
+	// Required: true
+	AnalyzedAt time.Time `json:"analyzed_at"`
+}
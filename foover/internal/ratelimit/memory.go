@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle buckets are evicted from memory
+const sweepInterval = time.Minute
+
+// bucket represents the state of a single token bucket
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+	full     time.Time
+}
+
+// memoryBackend implements the Backend interface with per-process buckets
+type memoryBackend struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryBackend creates a new in-memory Backend
+func NewMemoryBackend() Backend {
+	return &memoryBackend{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Take refills the bucket of key for the elapsed time and takes a token if one is available
+func (m *memoryBackend) Take(_ context.Context, key string, limit Limit) (Decision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	capacity := float64(limit.Burst)
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, lastSeen: now}
+		m.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.lastSeen).Seconds()
+	b.tokens = math.Min(capacity, b.tokens+elapsed*limit.Rate)
+	b.lastSeen = now
+
+	decision := Decision{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = secondsToDuration((1 - b.tokens) / limit.Rate)
+	}
+
+	decision.Remaining = int(b.tokens)
+	decision.Reset = secondsToDuration((capacity - b.tokens) / limit.Rate)
+	b.full = now.Add(decision.Reset)
+
+	return decision, nil
+}
+
+// sweep evicts buckets that have refilled completely, as they are equivalent to new buckets
+func (m *memoryBackend) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for key, b := range m.buckets {
+		if now.After(b.full) {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,30 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit represents a token bucket refilled at Rate tokens per second holding at most Burst tokens
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Decision represents the outcome of taking a token from a bucket
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is the time until a token is available, zero if the request is allowed
+	RetryAfter time.Duration
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+}
+
+// Backend defines behaviors of a token bucket store.
+// The in-memory backend limits per process, a distributed backend (e.g. Redis)
+// can implement this interface to share buckets across replicas.
+type Backend interface {
+	Take(ctx context.Context, key string, limit Limit) (Decision, error)
+}
@@ -0,0 +1,278 @@
+package recommend
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+
+	"foover/internal/config"
+	"foover/internal/models"
+)
+
+// Neighbor represents a product similar to another one
+type Neighbor struct {
+	ProductID  string
+	Similarity float64
+	Support    int // sessions that rated both products
+}
+
+// Model represents the product similarities and popularity ranking recommendations are computed from.
+// A model is immutable once built, so it is safe for concurrent use.
+type Model struct {
+	neighbors map[string][]Neighbor // most similar votable products first
+	popular   []models.Recommendation
+	builtAt   time.Time
+}
+
+// BuiltAt returns when the model was built
+func (m *Model) BuiltAt() time.Time {
+	return m.builtAt
+}
+
+// Products returns the number of products with at least one similar product
+func (m *Model) Products() int {
+	return len(m.neighbors)
+}
+
+// Builder accumulates votes into a Model
+type Builder struct {
+	cfg      config.Recommendation
+	sessions map[string]map[string]int // scores by product by session
+	totals   map[string]*total         // counted scores by product
+}
+
+// total represents the sum of the counted scores of a product
+type total struct {
+	sum   float64
+	count int
+}
+
+// NewBuilder creates a new Builder
+func NewBuilder(cfg config.Recommendation) *Builder {
+	return &Builder{
+		cfg:      cfg,
+		sessions: make(map[string]map[string]int),
+		totals:   make(map[string]*total),
+	}
+}
+
+// AddVote adds a vote to the model. Flagged and rejected votes are ignored like in the aggregated scores,
+// anonymized votes only count towards popularity since they are no longer linked to their session.
+func (b *Builder) AddVote(vote models.Vote) {
+	if vote.Status == models.VoteStatusFlagged || vote.Status == models.VoteStatusRejected {
+		return
+	}
+
+	t, ok := b.totals[vote.ProductID]
+	if !ok {
+		t = &total{}
+		b.totals[vote.ProductID] = t
+	}
+	t.sum += float64(vote.Score)
+	t.count++
+
+	if vote.AnonymizedAt != nil {
+		return
+	}
+	ratings, ok := b.sessions[vote.SessionID]
+	if !ok {
+		ratings = make(map[string]int)
+		b.sessions[vote.SessionID] = ratings
+	}
+	ratings[vote.ProductID] = vote.Score
+}
+
+// pair represents two products, a sorts before b
+type pair struct {
+	a, b string
+}
+
+// accumulator represents the sums of the adjusted cosine similarity of a pair over the sessions that rated both
+type accumulator struct {
+	dot, normA, normB float64
+	support           int
+}
+
+// Build computes the model, only products in votable are recommended.
+// Similarities are adjusted cosines, scores are centered on the average of their session
+// so sessions that score everything high don't make every product look alike.
+func (b *Builder) Build(votable map[string]struct{}) *Model {
+	pairs := make(map[pair]*accumulator)
+	for _, ratings := range b.sessions {
+		// A single rating is its session's average, it says nothing about similarity
+		if len(ratings) < 2 {
+			continue
+		}
+
+		var sum float64
+		products := make([]string, 0, len(ratings))
+		for productID, score := range ratings {
+			sum += float64(score)
+			products = append(products, productID)
+		}
+		mean := sum / float64(len(ratings))
+		slices.Sort(products)
+
+		for i, first := range products {
+			da := float64(ratings[first]) - mean
+			for _, second := range products[i+1:] {
+				db := float64(ratings[second]) - mean
+				acc, ok := pairs[pair{first, second}]
+				if !ok {
+					acc = &accumulator{}
+					pairs[pair{first, second}] = acc
+				}
+				acc.dot += da * db
+				acc.normA += da * da
+				acc.normB += db * db
+				acc.support++
+			}
+		}
+	}
+
+	neighbors := make(map[string][]Neighbor)
+	for p, acc := range pairs {
+		if acc.support < b.cfg.MinSupport || acc.normA == 0 || acc.normB == 0 {
+			continue
+		}
+		similarity := acc.dot / math.Sqrt(acc.normA*acc.normB)
+		if similarity <= 0 {
+			continue
+		}
+
+		if _, ok := votable[p.b]; ok {
+			neighbors[p.a] = append(neighbors[p.a], Neighbor{ProductID: p.b, Similarity: similarity, Support: acc.support})
+		}
+		if _, ok := votable[p.a]; ok {
+			neighbors[p.b] = append(neighbors[p.b], Neighbor{ProductID: p.a, Similarity: similarity, Support: acc.support})
+		}
+	}
+	for productID, list := range neighbors {
+		slices.SortFunc(list, compareNeighbors)
+		if len(list) > b.cfg.Neighbors {
+			list = slices.Clip(list[:b.cfg.Neighbors])
+		}
+		neighbors[productID] = list
+	}
+
+	return &Model{
+		neighbors: neighbors,
+		popular:   b.popular(votable),
+		builtAt:   time.Now(),
+	}
+}
+
+// popular ranks the votable products by their average pulled towards the global average by PopularityPrior votes,
+// so a single perfect vote doesn't outrank a product loved by hundreds
+func (b *Builder) popular(votable map[string]struct{}) []models.Recommendation {
+	var sum float64
+	var count int
+	for _, t := range b.totals {
+		sum += t.sum
+		count += t.count
+	}
+	if count == 0 {
+		return nil
+	}
+	globalMean := sum / float64(count)
+
+	popular := make([]models.Recommendation, 0, len(b.totals))
+	for productID, t := range b.totals {
+		if _, ok := votable[productID]; !ok {
+			continue
+		}
+		score := (t.sum + b.cfg.PopularityPrior*globalMean) / (float64(t.count) + b.cfg.PopularityPrior)
+		popular = append(popular, models.Recommendation{
+			ProductID:      productID,
+			PredictedScore: round(score),
+			Source:         models.RecommendationSourcePopular,
+		})
+	}
+	slices.SortFunc(popular, compareRecommendations)
+
+	return popular
+}
+
+// Recommend returns up to limit products the session of votes hasn't voted on, ranked by the score predicted
+// from the products it rated and most similar to them, and filled in with popular products.
+// Predictions are averages of the session's scores weighted by similarity, rejected votes aren't predicted from.
+func (m *Model) Recommend(votes []models.Vote, limit int) []models.Recommendation {
+	voted := make(map[string]struct{}, len(votes))
+	for _, vote := range votes {
+		voted[vote.ProductID] = struct{}{}
+	}
+
+	weighted := make(map[string]float64)
+	weights := make(map[string]float64)
+	for _, vote := range votes {
+		if vote.Status == models.VoteStatusRejected {
+			continue
+		}
+		for _, neighbor := range m.neighbors[vote.ProductID] {
+			if _, ok := voted[neighbor.ProductID]; ok {
+				continue
+			}
+			weighted[neighbor.ProductID] += neighbor.Similarity * float64(vote.Score)
+			weights[neighbor.ProductID] += neighbor.Similarity
+		}
+	}
+
+	predicted := make([]models.Recommendation, 0, len(weights))
+	for productID, weight := range weights {
+		predicted = append(predicted, models.Recommendation{
+			ProductID:      productID,
+			PredictedScore: round(weighted[productID] / weight),
+			Source:         models.RecommendationSourceSimilar,
+		})
+	}
+	// Equal predictions are ordered by the similarity backing them
+	slices.SortFunc(predicted, func(a, b models.Recommendation) int {
+		return cmp.Or(
+			cmp.Compare(b.PredictedScore, a.PredictedScore),
+			cmp.Compare(weights[b.ProductID], weights[a.ProductID]),
+			cmp.Compare(a.ProductID, b.ProductID),
+		)
+	})
+	if len(predicted) > limit {
+		predicted = predicted[:limit]
+	}
+
+	recommendations := predicted
+	for _, recommendation := range m.popular {
+		if len(recommendations) >= limit {
+			break
+		}
+		if _, ok := voted[recommendation.ProductID]; ok {
+			continue
+		}
+		if _, ok := weights[recommendation.ProductID]; ok {
+			continue
+		}
+		recommendations = append(recommendations, recommendation)
+	}
+
+	return recommendations
+}
+
+// compareNeighbors orders neighbors most similar first
+func compareNeighbors(a, b Neighbor) int {
+	return cmp.Or(
+		cmp.Compare(b.Similarity, a.Similarity),
+		cmp.Compare(b.Support, a.Support),
+		cmp.Compare(a.ProductID, b.ProductID),
+	)
+}
+
+// compareRecommendations orders recommendations best first
+func compareRecommendations(a, b models.Recommendation) int {
+	return cmp.Or(
+		cmp.Compare(b.PredictedScore, a.PredictedScore),
+		cmp.Compare(a.ProductID, b.ProductID),
+	)
+}
+
+// round rounds a score to two decimals
+func round(score float64) float64 {
+	return math.Round(score*100) / 100
+}
@@ -0,0 +1,150 @@
+package recommend
+
+import (
+	"math"
+	"slices"
+
+	"foover/internal/models"
+)
+
+// Similarity measures of the product similarity index
+const (
+	// SimilarityPearson correlates the scores of two products, centered on each product's average over their co-raters
+	SimilarityPearson = "pearson"
+	// SimilarityCosine compares the raw scores of two products
+	SimilarityCosine = "cosine"
+)
+
+// IsSimilarityMethod reports whether method is a supported similarity measure
+func IsSimilarityMethod(method string) bool {
+	return method == SimilarityPearson || method == SimilarityCosine
+}
+
+// SimilarityBuilder accumulates votes into the similarities of product pairs.
+// Both measures only depend on the scores of the sessions that rated both products of a pair,
+// so the pairs of products whose votes changed can be recomputed without the rest of the index.
+type SimilarityBuilder struct {
+	method     string
+	minSupport int
+	products   map[string]struct{}       // pairs involving one of these are built, nil builds all pairs
+	sessions   map[string]map[string]int // scores by product by session
+}
+
+// NewSimilarityBuilder creates a new SimilarityBuilder of the pairs involving one of productIDs, or of all pairs if productIDs is nil
+func NewSimilarityBuilder(method string, minSupport int, productIDs []string) *SimilarityBuilder {
+	var products map[string]struct{}
+	if productIDs != nil {
+		products = make(map[string]struct{}, len(productIDs))
+		for _, productID := range productIDs {
+			products[productID] = struct{}{}
+		}
+	}
+
+	return &SimilarityBuilder{
+		method:     method,
+		minSupport: minSupport,
+		products:   products,
+		sessions:   make(map[string]map[string]int),
+	}
+}
+
+// AddVote adds a vote, flagged, rejected and anonymized votes are ignored
+func (b *SimilarityBuilder) AddVote(vote models.Vote) {
+	if vote.Status == models.VoteStatusFlagged || vote.Status == models.VoteStatusRejected || vote.AnonymizedAt != nil {
+		return
+	}
+
+	ratings, ok := b.sessions[vote.SessionID]
+	if !ok {
+		ratings = make(map[string]int)
+		b.sessions[vote.SessionID] = ratings
+	}
+	ratings[vote.ProductID] = vote.Score
+}
+
+// pairSums represents the sums of the scores of a pair over the sessions that rated both
+type pairSums struct {
+	a, b, aa, bb, ab float64
+	support          int
+}
+
+// Build returns the positive similarities of the pairs supported by at least minSupport sessions, once in each direction
+func (b *SimilarityBuilder) Build() []models.ProductSimilarity {
+	pairs := make(map[pair]*pairSums)
+	for _, ratings := range b.sessions {
+		products := make([]string, 0, len(ratings))
+		for productID := range ratings {
+			products = append(products, productID)
+		}
+		slices.Sort(products)
+
+		for i, first := range products {
+			for _, second := range products[i+1:] {
+				if !b.builds(first, second) {
+					continue
+				}
+				sums, ok := pairs[pair{first, second}]
+				if !ok {
+					sums = &pairSums{}
+					pairs[pair{first, second}] = sums
+				}
+				x, y := float64(ratings[first]), float64(ratings[second])
+				sums.a += x
+				sums.b += y
+				sums.aa += x * x
+				sums.bb += y * y
+				sums.ab += x * y
+				sums.support++
+			}
+		}
+	}
+
+	var similarities []models.ProductSimilarity
+	for p, sums := range pairs {
+		if sums.support < b.minSupport {
+			continue
+		}
+		similarity, ok := b.similarity(sums)
+		if !ok || similarity <= 0 {
+			continue
+		}
+		similarity = math.Round(similarity*10000) / 10000
+		similarities = append(similarities,
+			models.ProductSimilarity{ProductID: p.a, SimilarProductID: p.b, Similarity: similarity, Support: sums.support},
+			models.ProductSimilarity{ProductID: p.b, SimilarProductID: p.a, Similarity: similarity, Support: sums.support},
+		)
+	}
+
+	return similarities
+}
+
+// builds reports whether the pair of first and second is part of the build
+func (b *SimilarityBuilder) builds(first, second string) bool {
+	if b.products == nil {
+		return true
+	}
+	_, ok := b.products[first]
+	if !ok {
+		_, ok = b.products[second]
+	}
+	return ok
+}
+
+// similarity computes the similarity of a pair from its sums, it returns false if it is undefined,
+// which happens with pearson when every co-rater gave one of the products the same score
+func (b *SimilarityBuilder) similarity(sums *pairSums) (float64, bool) {
+	if b.method == SimilarityCosine {
+		denominator := math.Sqrt(sums.aa * sums.bb)
+		if denominator == 0 {
+			return 0, false
+		}
+		return sums.ab / denominator, true
+	}
+
+	n := float64(sums.support)
+	denominator := math.Sqrt((n*sums.aa - sums.a*sums.a) * (n*sums.bb - sums.b*sums.b))
+	if denominator == 0 {
+		return 0, false
+	}
+	return (n*sums.ab - sums.a*sums.b) / denominator, true
+}
@@ -0,0 +1,88 @@
+package recommend
+
+import (
+	"cmp"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"foover/internal/models"
+	"foover/internal/testutil"
+)
+
+func TestSimilarityBuild(t *testing.T) {
+	flagged := models.Vote{SessionID: "s5", ProductID: "a", Score: 5, Status: models.VoteStatusFlagged}
+
+	tests := []struct {
+		name       string
+		method     string
+		minSupport int
+		productIDs []string
+		votes      [][]models.Vote
+		want       []models.ProductSimilarity
+	}{
+		{
+			name:       "pearson",
+			method:     SimilarityPearson,
+			minSupport: 3,
+			votes:      [][]models.Vote{testutil.Votes("a", time.Time{}, 1, 2, 3, 4, 5), testutil.Votes("b", time.Time{}, 2, 4, 5, 4, 5), {flagged, {SessionID: "s5", ProductID: "b", Score: 1}}},
+			want: []models.ProductSimilarity{
+				{ProductID: "a", SimilarProductID: "b", Similarity: 0.7746, Support: 5},
+				{ProductID: "b", SimilarProductID: "a", Similarity: 0.7746, Support: 5},
+			},
+		},
+		{
+			name:       "cosine",
+			method:     SimilarityCosine,
+			minSupport: 3,
+			votes:      [][]models.Vote{testutil.Votes("a", time.Time{}, 1, 2, 3, 4, 5), testutil.Votes("b", time.Time{}, 2, 4, 5, 4, 5)},
+			want: []models.ProductSimilarity{
+				{ProductID: "a", SimilarProductID: "b", Similarity: 0.9597, Support: 5},
+				{ProductID: "b", SimilarProductID: "a", Similarity: 0.9597, Support: 5},
+			},
+		},
+		{
+			name:       "pearson of negatively correlated and constant scores",
+			method:     SimilarityPearson,
+			minSupport: 3,
+			votes:      [][]models.Vote{testutil.Votes("a", time.Time{}, 1, 2, 3, 4, 5), testutil.Votes("b", time.Time{}, 5, 4, 3, 2, 1), testutil.Votes("c", time.Time{}, 3, 3, 3, 3, 3)},
+		},
+		{
+			name:       "below the minimum support",
+			method:     SimilarityPearson,
+			minSupport: 6,
+			votes:      [][]models.Vote{testutil.Votes("a", time.Time{}, 1, 2, 3, 4, 5), testutil.Votes("b", time.Time{}, 2, 4, 5, 4, 5)},
+		},
+		{
+			name:       "pairs of the given products",
+			method:     SimilarityPearson,
+			minSupport: 3,
+			productIDs: []string{"c"},
+			votes:      [][]models.Vote{testutil.Votes("a", time.Time{}, 1, 2, 3, 4, 5), testutil.Votes("b", time.Time{}, 2, 4, 5, 4, 5), testutil.Votes("c", time.Time{}, 1, 3, 4)},
+			want: []models.ProductSimilarity{
+				{ProductID: "a", SimilarProductID: "c", Similarity: 0.982, Support: 3},
+				{ProductID: "b", SimilarProductID: "c", Similarity: 1, Support: 3},
+				{ProductID: "c", SimilarProductID: "a", Similarity: 0.982, Support: 3},
+				{ProductID: "c", SimilarProductID: "b", Similarity: 1, Support: 3},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewSimilarityBuilder(tt.method, tt.minSupport, tt.productIDs)
+			for _, votes := range tt.votes {
+				for _, vote := range votes {
+					builder.AddVote(vote)
+				}
+			}
+			got := builder.Build()
+			slices.SortFunc(got, func(a, b models.ProductSimilarity) int {
+				return cmp.Or(cmp.Compare(a.ProductID, b.ProductID), cmp.Compare(a.SimilarProductID, b.SimilarProductID))
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Build() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"foover/internal/models"
 	"foover/internal/store/mongo"
+	"foover/internal/tracing"
 )
 
 // aggregationService implements the AggregationService interface
@@ -24,5 +25,8 @@ func NewAggregationService(store mongo.Store) AggregationService {
 
 // GetAggregatedProductScores retrieves aggregated average scores for all products
 func (a *aggregationService) GetAggregatedProductScores(ctx context.Context) ([]models.ProductScore, error) {
-	return a.store.GetAggregatedProductScores(ctx)
+	ctx, span := tracer.Start(ctx, "AggregationService.GetAggregatedProductScores")
+	scores, err := a.store.GetAggregatedProductScores(ctx)
+	tracing.End(span, err)
+	return scores, err
 }
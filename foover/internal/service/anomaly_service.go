@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"foover/internal/anomaly"
+	"foover/internal/config"
+	"foover/internal/metrics"
+	"foover/internal/models"
+	"foover/internal/store/mongo"
+	"foover/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// anomalyLease is the name of the lease held by the replica detecting anomalies
+const anomalyLease = "anomaly_detection"
+
+type AnomalyService interface {
+	Detect(ctx context.Context) (int, error)
+	GetAnomalies(ctx context.Context, productID string, direction string, since time.Time, limit int64) ([]models.Anomaly, error)
+}
+
+// anomalyService implements the AnomalyService interface
+type anomalyService struct {
+	store    mongo.Store
+	webhooks WebhookService
+	cfg      config.Anomaly
+	owner    string
+	metrics  *metrics.Metrics
+}
+
+// NewAnomalyService creates a new AnomalyService, owner identifies the replica.
+// Anomalies are forwarded to webhooks unless webhooks is nil.
+func NewAnomalyService(store mongo.Store, webhooks WebhookService, cfg config.Anomaly, owner string, m *metrics.Metrics) AnomalyService {
+	return &anomalyService{
+		store:    store,
+		webhooks: webhooks,
+		cfg:      cfg,
+		owner:    owner,
+		metrics:  m,
+	}
+}
+
+// Detect compares the recent votes of every product to the rolling statistics of its baseline, records the anomalies
+// not recorded within the cooldown and returns their number. Only the replica holding the detection lease detects.
+// An anomaly that can't be forwarded is removed again, so the next run detects and forwards it.
+func (a *anomalyService) Detect(ctx context.Context) (recorded int, err error) {
+	ctx, span := tracer.Start(ctx, "AnomalyService.Detect")
+	defer func() { tracing.End(span, err) }()
+
+	leader, err := a.store.AcquireLease(ctx, anomalyLease, a.owner, a.cfg.Interval)
+	if err != nil || !leader {
+		span.SetAttributes(attribute.Bool("anomaly.leader", leader))
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, a.cfg.Interval)
+	defer cancel()
+
+	now := time.Now()
+	detector := anomaly.NewDetector(a.cfg, now)
+	err = a.store.StreamCountedVotesSince(ctx, detector.BaselineStart(), func(vote models.Vote) error {
+		detector.AddVote(vote)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	anomalies := detector.Detect()
+	span.SetAttributes(attribute.Int("anomaly.detected", len(anomalies)))
+	for _, detected := range anomalies {
+		detected.DetectedAt = now
+		detected.ExpiresAt = now.Add(a.cfg.Retention)
+		stored, ok, err := a.store.RecordAnomaly(ctx, detected, now.Add(-a.cfg.Cooldown))
+		if err != nil {
+			return recorded, err
+		}
+		if !ok {
+			continue
+		}
+
+		if a.webhooks != nil {
+			if err := a.webhooks.Emit(ctx, models.WebhookEventScoreAnomaly, stored.ProductID, stored); err != nil {
+				span.RecordError(err)
+				if deleteErr := a.store.DeleteAnomaly(ctx, stored.ID); deleteErr != nil {
+					span.RecordError(deleteErr)
+				}
+				continue
+			}
+		}
+		a.metrics.IncAnomalies(stored.Direction)
+		recorded++
+	}
+
+	span.SetAttributes(attribute.Int("anomaly.recorded", recorded))
+	return recorded, nil
+}
+
+// GetAnomalies returns up to limit anomalies detected since the given time, newest first,
+// of a product unless productID is empty and in a direction unless direction is empty
+func (a *anomalyService) GetAnomalies(ctx context.Context, productID string, direction string, since time.Time, limit int64) (anomalies []models.Anomaly, err error) {
+	ctx, span := tracer.Start(ctx, "AnomalyService.GetAnomalies")
+	defer func() { tracing.End(span, err) }()
+
+	return a.store.GetAnomalies(ctx, productID, direction, since, limit)
+}
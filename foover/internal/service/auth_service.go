@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"foover/internal/auth"
+	"foover/internal/models"
+	"foover/internal/store/mongo"
+	"foover/internal/tracing"
+)
+
+var (
+	// ErrInvalidAPIKey is returned when an API key is unknown or revoked
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	// ErrInvalidScope is returned when an API key is requested with an unknown scope
+	ErrInvalidScope = errors.New("invalid scope")
+)
+
+type AuthService interface {
+	Authenticate(ctx context.Context, key string) (auth.Principal, error)
+	CreateAPIKey(ctx context.Context, name string, scopes []string) (string, models.APIKey, error)
+	GetAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+}
+
+// authService implements the AuthService interface
+type authService struct {
+	store                 mongo.Store
+	bootstrapAdminKeyHash string
+}
+
+// NewAuthService creates a new AuthService, an empty bootstrapAdminKey disables the bootstrap key
+func NewAuthService(store mongo.Store, bootstrapAdminKey string) AuthService {
+	s := &authService{
+		store: store,
+	}
+	if bootstrapAdminKey != "" {
+		s.bootstrapAdminKeyHash = auth.HashKey(bootstrapAdminKey)
+	}
+	return s
+}
+
+// Authenticate resolves the principal an API key was issued to
+func (a *authService) Authenticate(ctx context.Context, key string) (_ auth.Principal, err error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Authenticate")
+	defer func() { tracing.End(span, err) }()
+
+	hash := auth.HashKey(key)
+	if a.bootstrapAdminKeyHash != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(a.bootstrapAdminKeyHash)) == 1 {
+		return auth.Principal{
+			Type:   auth.PrincipalAPIKey,
+			ID:     "bootstrap",
+			Name:   "bootstrap",
+			Scopes: []string{auth.ScopeAdmin},
+		}, nil
+	}
+
+	apiKey, err := a.store.GetAPIKeyByHash(ctx, hash)
+	if errors.Is(err, mongo.ErrNotFound) {
+		return auth.Anonymous, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return auth.Anonymous, err
+	}
+
+	return auth.Principal{
+		Type:   auth.PrincipalAPIKey,
+		ID:     apiKey.ID.Hex(),
+		Name:   apiKey.Name,
+		Scopes: apiKey.Scopes,
+	}, nil
+}
+
+// CreateAPIKey issues a new API key and returns it with its stored metadata, the key itself is not stored
+func (a *authService) CreateAPIKey(ctx context.Context, name string, scopes []string) (_ string, _ models.APIKey, err error) {
+	ctx, span := tracer.Start(ctx, "AuthService.CreateAPIKey")
+	defer func() { tracing.End(span, err) }()
+
+	for _, scope := range scopes {
+		if !auth.IsValidScope(scope) {
+			return "", models.APIKey{}, fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+
+	key, err := auth.GenerateKey()
+	if err != nil {
+		return "", models.APIKey{}, err
+	}
+
+	apiKey, err := a.store.CreateAPIKey(ctx, models.APIKey{
+		Name:      name,
+		Hint:      auth.KeyHint(key),
+		Hash:      auth.HashKey(key),
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return "", models.APIKey{}, err
+	}
+
+	return key, apiKey, nil
+}
+
+// GetAPIKeys retrieves the metadata of all API keys
+func (a *authService) GetAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.GetAPIKeys")
+	apiKeys, err := a.store.GetAPIKeys(ctx)
+	tracing.End(span, err)
+	return apiKeys, err
+}
+
+// RevokeAPIKey revokes the API key with the given ID
+func (a *authService) RevokeAPIKey(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "AuthService.RevokeAPIKey")
+	err := a.store.RevokeAPIKey(ctx, id)
+	tracing.End(span, err)
+	return err
+}
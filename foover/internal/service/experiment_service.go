@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"foover/internal/config"
+	"foover/internal/experiment"
+	"foover/internal/models"
+	"foover/internal/store/mongo"
+	"foover/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrInvalidExperiment is returned when an experiment to create has an invalid key or duplicate variant names
+var ErrInvalidExperiment = errors.New("invalid experiment")
+
+type ExperimentService interface {
+	CreateExperiment(ctx context.Context, req models.CreateExperimentRequest) (models.Experiment, error)
+	GetExperiments(ctx context.Context) ([]models.Experiment, error)
+	GetExperiment(ctx context.Context, key string) (models.Experiment, error)
+	StopExperiment(ctx context.Context, key string) (models.Experiment, error)
+	AnalyzeExperiment(ctx context.Context, key string) (models.GetExperimentAnalysisResponse, error)
+}
+
+// experimentService implements the ExperimentService interface
+type experimentService struct {
+	store mongo.Store
+	cfg   config.Experiment
+}
+
+// NewExperimentService creates a new ExperimentService
+func NewExperimentService(store mongo.Store, cfg config.Experiment) ExperimentService {
+	return &experimentService{
+		store: store,
+		cfg:   cfg,
+	}
+}
+
+// CreateExperiment starts an experiment, sessions created from now on are assigned to its variants
+func (e *experimentService) CreateExperiment(ctx context.Context, req models.CreateExperimentRequest) (_ models.Experiment, err error) {
+	ctx, span := tracer.Start(ctx, "ExperimentService.CreateExperiment")
+	defer func() { tracing.End(span, err) }()
+
+	if !experiment.IsValidKey(req.Key) {
+		return models.Experiment{}, fmt.Errorf("%w: key must consist of lowercase letters, digits, dashes and underscores", ErrInvalidExperiment)
+	}
+
+	variants := make([]models.ExperimentVariant, 0, len(req.Variants))
+	names := make(map[string]bool, len(req.Variants))
+	for _, variant := range req.Variants {
+		if names[variant.Name] {
+			return models.Experiment{}, fmt.Errorf("%w: duplicate variant %s", ErrInvalidExperiment, variant.Name)
+		}
+		names[variant.Name] = true
+
+		weight := variant.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		variants = append(variants, models.ExperimentVariant{Name: variant.Name, Weight: weight})
+	}
+
+	return e.store.CreateExperiment(ctx, models.Experiment{
+		Key:         req.Key,
+		Description: req.Description,
+		Variants:    variants,
+		ProductIDs:  req.ProductIDs,
+		Status:      models.ExperimentStatusRunning,
+		StartedAt:   time.Now(),
+	})
+}
+
+// GetExperiments retrieves all experiments, newest first
+func (e *experimentService) GetExperiments(ctx context.Context) ([]models.Experiment, error) {
+	ctx, span := tracer.Start(ctx, "ExperimentService.GetExperiments")
+	experiments, err := e.store.GetExperiments(ctx)
+	tracing.End(span, err)
+	return experiments, err
+}
+
+// GetExperiment retrieves the experiment with the given key
+func (e *experimentService) GetExperiment(ctx context.Context, key string) (models.Experiment, error) {
+	ctx, span := tracer.Start(ctx, "ExperimentService.GetExperiment")
+	found, err := e.store.GetExperiment(ctx, key)
+	tracing.End(span, err)
+	return found, err
+}
+
+// StopExperiment stops assigning sessions to the variants of an experiment and freezes its analysis.
+// Sessions keep their assignments.
+func (e *experimentService) StopExperiment(ctx context.Context, key string) (models.Experiment, error) {
+	ctx, span := tracer.Start(ctx, "ExperimentService.StopExperiment")
+	stopped, err := e.store.StopExperiment(ctx, key, time.Now())
+	tracing.End(span, err)
+	return stopped, err
+}
+
+// AnalyzeExperiment compares the counted votes the sessions assigned to each variant cast on the products of the
+// experiment while it ran. Votes erased or anonymized since are left out, as they're no longer linked to a session.
+func (e *experimentService) AnalyzeExperiment(ctx context.Context, key string) (analysis models.GetExperimentAnalysisResponse, err error) {
+	ctx, span := tracer.Start(ctx, "ExperimentService.AnalyzeExperiment")
+	defer func() { tracing.End(span, err) }()
+
+	found, err := e.store.GetExperiment(ctx, key)
+	if err != nil {
+		return models.GetExperimentAnalysisResponse{}, err
+	}
+
+	analyzer := experiment.NewAnalyzer(e.cfg, found)
+	sessions := 0
+	err = e.store.StreamExperimentSessionScores(ctx, found, func(score models.ExperimentSessionScore) error {
+		analyzer.AddSession(score)
+		sessions++
+		return nil
+	})
+	if err != nil {
+		return models.GetExperimentAnalysisResponse{}, err
+	}
+	span.SetAttributes(attribute.Int("experiment.sessions", sessions))
+
+	analysis = analyzer.Analyze()
+	analysis.AnalyzedAt = time.Now()
+	return analysis, nil
+}
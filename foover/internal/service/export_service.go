@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"foover/internal/export"
+	"foover/internal/models"
+	"foover/internal/store/mongo"
+	"foover/internal/tracing"
+	"io"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrInvalidExportFormat is returned when an export is requested in an unsupported format
+var ErrInvalidExportFormat = errors.New("invalid export format")
+
+type ExportService interface {
+	ExportVotes(ctx context.Context, format string, w io.Writer) (int64, error)
+	ExportScores(ctx context.Context, format string, w io.Writer) (int64, error)
+}
+
+// exportService implements the ExportService interface
+type exportService struct {
+	store mongo.Store
+}
+
+// NewExportService creates a new ExportService
+func NewExportService(store mongo.Store) ExportService {
+	return &exportService{
+		store: store,
+	}
+}
+
+// ExportVotes streams all raw votes to w in the given format and returns the number of exported votes
+func (e *exportService) ExportVotes(ctx context.Context, format string, w io.Writer) (count int64, err error) {
+	ctx, span := tracer.Start(ctx, "ExportService.ExportVotes")
+	defer func() { tracing.End(span, err) }()
+
+	writer, err := newExportWriter[export.VoteRow](format, w)
+	if err != nil {
+		return 0, err
+	}
+
+	err = e.store.StreamVotes(ctx, func(vote models.Vote) error {
+		count++
+		return writer.Write(export.NewVoteRow(vote))
+	})
+	span.SetAttributes(attribute.String("export.format", format), attribute.Int64("export.rows", count))
+	// A failed export isn't completed, so output still buffered by the writer is never sent
+	if err != nil {
+		return count, err
+	}
+	return count, writer.Close()
+}
+
+// ExportScores streams the aggregated product scores to w in the given format and returns the number of exported scores
+func (e *exportService) ExportScores(ctx context.Context, format string, w io.Writer) (count int64, err error) {
+	ctx, span := tracer.Start(ctx, "ExportService.ExportScores")
+	defer func() { tracing.End(span, err) }()
+
+	writer, err := newExportWriter[export.ScoreRow](format, w)
+	if err != nil {
+		return 0, err
+	}
+
+	err = e.store.StreamAggregatedProductScores(ctx, func(score models.ProductScore) error {
+		count++
+		return writer.Write(export.NewScoreRow(score))
+	})
+	span.SetAttributes(attribute.String("export.format", format), attribute.Int64("export.rows", count))
+	// A failed export isn't completed, so output still buffered by the writer is never sent
+	if err != nil {
+		return count, err
+	}
+	return count, writer.Close()
+}
+
+// newExportWriter creates an export writer, reporting unsupported formats as ErrInvalidExportFormat
+func newExportWriter[T export.Row](format string, w io.Writer) (export.Writer[T], error) {
+	if !export.IsValidFormat(format) {
+		return nil, ErrInvalidExportFormat
+	}
+	return export.NewWriter[T](format, w)
+}
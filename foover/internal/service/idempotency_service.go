@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"foover/internal/auth"
+	"foover/internal/config"
+	"foover/internal/metrics"
+	"foover/internal/models"
+	"foover/internal/store/mongo"
+	"foover/internal/tracing"
+	"time"
+)
+
+var (
+	// ErrIdempotencyKeyInProgress is returned when a request with the same key hasn't completed yet
+	ErrIdempotencyKeyInProgress = errors.New("idempotency key in progress")
+	// ErrIdempotencyKeyReused is returned when a key is reused for a request with a different fingerprint
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused with a different request")
+)
+
+type IdempotencyService interface {
+	Begin(ctx context.Context, key, fingerprint string) (*models.IdempotentResponse, error)
+	Complete(ctx context.Context, key string, response models.IdempotentResponse) error
+	Release(ctx context.Context, key string) error
+}
+
+// idempotencyService implements the IdempotencyService interface
+type idempotencyService struct {
+	store   mongo.Store
+	cfg     config.Idempotency
+	metrics *metrics.Metrics
+}
+
+// NewIdempotencyService creates a new IdempotencyService keeping keys for the configured window
+func NewIdempotencyService(store mongo.Store, cfg config.Idempotency, m *metrics.Metrics) IdempotencyService {
+	return &idempotencyService{
+		store:   store,
+		cfg:     cfg,
+		metrics: m,
+	}
+}
+
+// Begin reserves a key for the request with the given fingerprint and returns nil, so the request can be executed.
+// A retry of a completed request returns the stored response, a retry of a request in progress or the reuse
+// of a key for a different request returns an error. Keys are scoped to the principal of the request.
+func (i *idempotencyService) Begin(ctx context.Context, key, fingerprint string) (response *models.IdempotentResponse, err error) {
+	ctx, span := tracer.Start(ctx, "IdempotencyService.Begin")
+	defer func() { tracing.End(span, err) }()
+
+	now := time.Now()
+	record := models.IdempotencyRecord{
+		Key:         scopedIdempotencyKey(ctx, key),
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(i.cfg.TTL),
+	}
+
+	// The stored record may expire between both calls, reserving the key again covers that
+	for attempt := 0; attempt < 2; attempt++ {
+		err = i.store.CreateIdempotencyRecord(ctx, record, now.Add(-i.cfg.LockTimeout))
+		if err == nil {
+			i.metrics.IncIdempotentRequests(metrics.IdempotencyExecuted)
+			return nil, nil
+		}
+		if !errors.Is(err, mongo.ErrDuplicate) {
+			return nil, err
+		}
+
+		existing, err := i.store.GetIdempotencyRecord(ctx, record.Key)
+		if errors.Is(err, mongo.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case existing.Fingerprint != fingerprint:
+			i.metrics.IncIdempotentRequests(metrics.IdempotencyMismatch)
+			return nil, ErrIdempotencyKeyReused
+		case existing.Response == nil:
+			i.metrics.IncIdempotentRequests(metrics.IdempotencyInProgress)
+			return nil, ErrIdempotencyKeyInProgress
+		default:
+			i.metrics.IncIdempotentRequests(metrics.IdempotencyReplayed)
+			return existing.Response, nil
+		}
+	}
+
+	return nil, ErrIdempotencyKeyInProgress
+}
+
+// Complete stores the response of a request begun with the key for replays
+func (i *idempotencyService) Complete(ctx context.Context, key string, response models.IdempotentResponse) error {
+	ctx, span := tracer.Start(ctx, "IdempotencyService.Complete")
+	err := i.store.CompleteIdempotencyRecord(ctx, scopedIdempotencyKey(ctx, key), response)
+	tracing.End(span, err)
+	return err
+}
+
+// Release frees a key begun for a request that failed, so a retry executes it again
+func (i *idempotencyService) Release(ctx context.Context, key string) error {
+	ctx, span := tracer.Start(ctx, "IdempotencyService.Release")
+	err := i.store.DeleteIdempotencyRecord(ctx, scopedIdempotencyKey(ctx, key))
+	tracing.End(span, err)
+	return err
+}
+
+// scopedIdempotencyKey hashes the key with the principal of the request, so clients can't replay each other's responses
+func scopedIdempotencyKey(ctx context.Context, key string) string {
+	principal := auth.PrincipalFromContext(ctx)
+	sum := sha256.Sum256([]byte(principal.Type + ":" + principal.ID + ":" + key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"foover/internal/config"
+	"foover/internal/events"
+	"foover/internal/metrics"
+	"foover/internal/models"
+	"foover/internal/store/mongo"
+	"foover/internal/tracing"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// outboxLease is the name of the lease held by the replica relaying outbox events
+const outboxLease = "outbox_relay"
+
+type OutboxService interface {
+	Relay(ctx context.Context) (int, error)
+}
+
+// outboxService implements the OutboxService interface
+type outboxService struct {
+	store     mongo.Store
+	publisher events.EventPublisher
+	cfg       config.Outbox
+	owner     string
+	metrics   *metrics.Metrics
+}
+
+// NewOutboxService creates a new OutboxService relaying outbox events to publisher, owner identifies the replica
+func NewOutboxService(store mongo.Store, publisher events.EventPublisher, cfg config.Outbox, owner string, m *metrics.Metrics) OutboxService {
+	return &outboxService{
+		store:     store,
+		publisher: publisher,
+		cfg:       cfg,
+		owner:     owner,
+		metrics:   m,
+	}
+}
+
+// Relay publishes batches of pending outbox events and returns the number of published events.
+// Only the replica holding the relay lease publishes, and it stops well before the lease expires.
+// Events of a key are published in sequence order, an event waits until the one before it is published,
+// so a failed event holds back the later events of its product and is retried on the next run.
+// Keys blocked by a failed event are left out of the following batches of the run, so they can't fill every batch.
+// An event published but not recorded as such is published again, consumers see every event at least once.
+func (o *outboxService) Relay(ctx context.Context) (published int, err error) {
+	ctx, span := tracer.Start(ctx, "OutboxService.Relay")
+	defer func() { tracing.End(span, err) }()
+
+	leader, err := o.store.AcquireLease(ctx, outboxLease, o.owner, o.cfg.LeaseTTL)
+	if err != nil || !leader {
+		span.SetAttributes(attribute.Bool("outbox.leader", leader))
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, o.cfg.LeaseTTL/2)
+	defer cancel()
+
+	var pending int
+	var blocked []string
+	defer func() {
+		span.SetAttributes(attribute.Int("outbox.pending", pending), attribute.Int("outbox.published", published))
+	}()
+	for {
+		batch, err := o.store.GetPendingOutboxEvents(ctx, blocked, o.cfg.BatchSize)
+		if err != nil {
+			return published, err
+		}
+		pending += len(batch)
+
+		batchPublished, failed, err := o.relayBatch(ctx, batch)
+		published += batchPublished
+		if err != nil {
+			return published, err
+		}
+
+		// Only a full batch can have left out the events of other keys behind the blocked ones
+		if len(failed) == 0 || int64(len(batch)) < o.cfg.BatchSize {
+			return published, nil
+		}
+		blocked = append(blocked, failed...)
+	}
+}
+
+// relayBatch publishes the events of a batch in sequence order per key and returns the number of published events
+// and the keys whose next event failed to be published
+func (o *outboxService) relayBatch(ctx context.Context, pending []models.OutboxEvent) (published int, failed []string, err error) {
+	if len(pending) == 0 {
+		return 0, nil, nil
+	}
+	span := trace.SpanFromContext(ctx)
+
+	byKey := make(map[string][]models.OutboxEvent)
+	var keys []string
+	for _, event := range pending {
+		if _, ok := byKey[event.Key]; !ok {
+			keys = append(keys, event.Key)
+		}
+		byKey[event.Key] = append(byKey[event.Key], event)
+	}
+	sequences, err := o.store.GetPublishedSequences(ctx, keys)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, key := range keys {
+		keyEvents := byKey[key]
+		slices.SortFunc(keyEvents, func(a, b models.OutboxEvent) int { return cmp.Compare(a.Sequence, b.Sequence) })
+
+		last := sequences[key]
+		for _, event := range keyEvents {
+			// The previous event isn't in this batch yet, it is published first on a later run
+			if event.Sequence > last+1 {
+				o.metrics.IncOutboxEvents(metrics.OutboxDeferred)
+				break
+			}
+
+			if err := o.publisher.Publish(ctx, event); err != nil {
+				span.RecordError(err)
+				o.metrics.IncOutboxEvents(metrics.OutboxFailed)
+				if recordErr := o.store.RecordOutboxEventFailure(ctx, event.ID, err.Error()); recordErr != nil {
+					span.RecordError(recordErr)
+				}
+				failed = append(failed, key)
+				break
+			}
+			o.metrics.IncOutboxEvents(metrics.OutboxPublished)
+
+			if err := o.store.MarkOutboxEventPublished(ctx, event, time.Now().Add(o.cfg.PublishedRetention)); err != nil {
+				return published, failed, err
+			}
+			published++
+			last = max(last, event.Sequence)
+		}
+	}
+
+	return published, failed, nil
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"foover/internal/config"
+	"foover/internal/metrics"
+	"foover/internal/models"
+	"foover/internal/store/mongo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// relayStore holds outbox events oldest first and the published sequence of each key
+type relayStore struct {
+	mongo.Store
+	events    []models.OutboxEvent
+	published map[string]int64
+}
+
+func (s *relayStore) AcquireLease(context.Context, string, string, time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (s *relayStore) GetPendingOutboxEvents(_ context.Context, excludedKeys []string, limit int64) ([]models.OutboxEvent, error) {
+	var pending []models.OutboxEvent
+	for _, event := range s.events {
+		if event.PublishedAt == nil && !slices.Contains(excludedKeys, event.Key) && int64(len(pending)) < limit {
+			pending = append(pending, event)
+		}
+	}
+	return pending, nil
+}
+
+func (s *relayStore) GetPublishedSequences(_ context.Context, keys []string) (map[string]int64, error) {
+	sequences := make(map[string]int64)
+	for _, key := range keys {
+		if sequence, ok := s.published[key]; ok {
+			sequences[key] = sequence
+		}
+	}
+	return sequences, nil
+}
+
+func (s *relayStore) MarkOutboxEventPublished(_ context.Context, event models.OutboxEvent, _ time.Time) error {
+	for i := range s.events {
+		if s.events[i].ID == event.ID {
+			now := time.Now()
+			s.events[i].PublishedAt = &now
+		}
+	}
+	s.published[event.Key] = max(s.published[event.Key], event.Sequence)
+	return nil
+}
+
+func (s *relayStore) RecordOutboxEventFailure(context.Context, primitive.ObjectID, string) error {
+	return nil
+}
+
+// keyFailingPublisher fails the events of one key and records the others
+type keyFailingPublisher struct {
+	failingKey string
+	published  []string
+}
+
+func (p *keyFailingPublisher) Publish(_ context.Context, event models.OutboxEvent) error {
+	if event.Key == p.failingKey {
+		return errors.New("publish failed")
+	}
+	p.published = append(p.published, event.Key)
+	return nil
+}
+
+func (p *keyFailingPublisher) Close() error {
+	return nil
+}
+
+func TestRelaySkipsBlockedKeys(t *testing.T) {
+	// The failing key has more pending events than fit in a batch, ahead of the events of the other key
+	store := &relayStore{published: make(map[string]int64)}
+	for sequence := int64(1); sequence <= 5; sequence++ {
+		store.events = append(store.events, models.OutboxEvent{ID: primitive.NewObjectID(), Key: "a", Sequence: sequence})
+	}
+	for sequence := int64(1); sequence <= 2; sequence++ {
+		store.events = append(store.events, models.OutboxEvent{ID: primitive.NewObjectID(), Key: "b", Sequence: sequence})
+	}
+	publisher := &keyFailingPublisher{failingKey: "a"}
+	cfg := config.Outbox{BatchSize: 3, LeaseTTL: time.Minute, PublishedRetention: time.Hour}
+	outboxService := NewOutboxService(store, publisher, cfg, "replica", metrics.New())
+
+	published, err := outboxService.Relay(context.Background())
+	if err != nil {
+		t.Fatalf("Relay() error = %v", err)
+	}
+	if published != 2 {
+		t.Errorf("Relay() = %d, want 2", published)
+	}
+	if want := []string{"b", "b"}; !reflect.DeepEqual(publisher.published, want) {
+		t.Errorf("published keys = %v, want %v", publisher.published, want)
+	}
+	if sequence := store.published["a"]; sequence != 0 {
+		t.Errorf("published sequence of the failing key = %d, want 0", sequence)
+	}
+}
@@ -3,37 +3,154 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sync/atomic"
+	"time"
 
+	"foover/internal/config"
+	"foover/internal/metrics"
 	"foover/internal/models"
 	"foover/internal/store/mongo"
+	"foover/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
 )
 
+// maxProductRefreshAttempts bounds the reloads of a refresh racing with product mutations
+const maxProductRefreshAttempts = 3
+
+// ErrProductRefreshContended is returned when product mutations kept changing the product set while refreshing it
+var ErrProductRefreshContended = errors.New("product set changed by mutations in every refresh attempt")
+
 type ProductService interface {
 	FetchAndStoreProducts(ctx context.Context, productAPIURL string) error
+	RefreshProducts(ctx context.Context) error
 	IsValidProductID(ctx context.Context, productID string) (bool, error)
+	GetValidProductIDs(ctx context.Context, productIDs []string) (map[string]bool, error)
+	CheckCatalog(ctx context.Context) error
+	GetProducts(ctx context.Context) ([]models.Product, error)
+	CreateProduct(ctx context.Context, req models.CreateProductRequest) (models.Product, error)
+	UpdateProduct(ctx context.Context, productID string, req models.UpdateProductRequest) (models.Product, error)
+	RetireProduct(ctx context.Context, productID string) (models.Product, error)
+	RestoreProduct(ctx context.Context, productID string) (models.Product, error)
+	ImportProducts(ctx context.Context, reqs []models.CreateProductRequest) (int, error)
 }
 
 type productService struct {
-	store mongo.Store
+	store        mongo.Store
+	cfg          config.ProductCache
+	metrics      *metrics.Metrics
+	lastSyncedAt atomic.Pointer[time.Time]
+	// products is the set of votable product IDs, replaced as a whole on change and nil until it is loaded
+	products atomic.Pointer[map[string]struct{}]
 }
 
-func NewProductService(store mongo.Store) ProductService {
+func NewProductService(store mongo.Store, cfg config.ProductCache, m *metrics.Metrics) ProductService {
 	return &productService{
-		store: store,
+		store:   store,
+		cfg:     cfg,
+		metrics: m,
 	}
 }
 
-func (p *productService) FetchAndStoreProducts(ctx context.Context, productAPIURL string) error {
-	resp, err := http.Get(productAPIURL)
+func (p *productService) FetchAndStoreProducts(ctx context.Context, productAPIURL string) (err error) {
+	ctx, span := tracer.Start(ctx, "ProductService.FetchAndStoreProducts")
+	defer func() { tracing.End(span, err) }()
+
+	products, err := p.fetchProducts(ctx, productAPIURL)
+	if err == nil {
+		err = p.store.SaveProducts(ctx, products)
+	}
+	p.metrics.ObserveCatalogSync(len(products), err)
 	if err != nil {
 		return err
 	}
+
+	now := time.Now()
+	p.lastSyncedAt.Store(&now)
+
+	return p.RefreshProducts(ctx)
+}
+
+// RefreshProducts reloads the in-memory set of votable products from the store.
+// If loading fails the set is dropped, so validation falls back to the store instead of using a stale set.
+// A set changed by a product mutation while loading is loaded again, since the loaded products may predate
+// the mutation. If mutations keep coming the set is dropped as well and ErrProductRefreshContended returned.
+func (p *productService) RefreshProducts(ctx context.Context) (err error) {
+	if !p.cfg.Enabled {
+		return nil
+	}
+
+	ctx, span := tracer.Start(ctx, "ProductService.RefreshProducts")
+	defer func() { tracing.End(span, err) }()
+
+	for attempt := 1; attempt <= maxProductRefreshAttempts; attempt++ {
+		current := p.products.Load()
+		products, err := p.store.GetProducts(ctx)
+		if err != nil {
+			p.products.Store(nil)
+			return err
+		}
+
+		set := make(map[string]struct{}, len(products))
+		for _, product := range products {
+			if product.RetiredAt == nil {
+				set[product.ProductID] = struct{}{}
+			}
+		}
+		if p.products.CompareAndSwap(current, &set) {
+			p.metrics.SetProductCacheSize(len(set))
+			return nil
+		}
+		span.SetAttributes(attribute.Int("products.refresh_attempts", attempt))
+	}
+
+	// The set may predate changes made through other replicas, only the store is known to be up to date
+	p.products.Store(nil)
+	return ErrProductRefreshContended
+}
+
+// updateProducts applies a change to a copy of the in-memory product set, readers keep using the previous set
+func (p *productService) updateProducts(change func(set map[string]struct{})) {
+	for {
+		current := p.products.Load()
+		if current == nil {
+			return
+		}
+
+		set := make(map[string]struct{}, len(*current)+1)
+		for productID := range *current {
+			set[productID] = struct{}{}
+		}
+		change(set)
+
+		if p.products.CompareAndSwap(current, &set) {
+			p.metrics.SetProductCacheSize(len(set))
+			return
+		}
+	}
+}
+
+// fetchProducts retrieves the product catalog from the external API
+func (p *productService) fetchProducts(ctx context.Context, productAPIURL string) ([]models.Product, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, productAPIURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to fetch products: status code %d", resp.StatusCode)
+		return nil, fmt.Errorf("failed to fetch products: status code %d", resp.StatusCode)
 	}
 
 	var apiResponse struct {
@@ -45,7 +162,7 @@ func (p *productService) FetchAndStoreProducts(ctx context.Context, productAPIUR
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-		return err
+		return nil, err
 	}
 
 	var products []models.Product
@@ -55,13 +172,170 @@ func (p *productService) FetchAndStoreProducts(ctx context.Context, productAPIUR
 		})
 	}
 
-	if err := p.store.SaveProducts(ctx, products); err != nil {
-		return err
+	return products, nil
+}
+
+// IsValidProductID checks the in-memory product set first and falls back to the store for unknown IDs,
+// which covers products created through other replicas since the last refresh
+func (p *productService) IsValidProductID(ctx context.Context, productID string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.IsValidProductID")
+	if p.isCachedProduct(productID) {
+		tracing.End(span, nil)
+		return true, nil
 	}
 
+	valid, err := p.store.IsValidProductID(ctx, productID)
+	tracing.End(span, err)
+	return valid, err
+}
+
+// GetValidProductIDs checks several product IDs with at most a single lookup and returns the set of valid ones
+func (p *productService) GetValidProductIDs(ctx context.Context, productIDs []string) (map[string]bool, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.GetValidProductIDs")
+
+	valid := make(map[string]bool, len(productIDs))
+	var uncached []string
+	for _, productID := range productIDs {
+		if p.isCachedProduct(productID) {
+			valid[productID] = true
+		} else {
+			uncached = append(uncached, productID)
+		}
+	}
+	if len(uncached) == 0 {
+		tracing.End(span, nil)
+		return valid, nil
+	}
+
+	ids, err := p.store.GetValidProductIDs(ctx, uncached)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		valid[id] = true
+	}
+	return valid, nil
+}
+
+// isCachedProduct reports whether the product is in the in-memory product set and records the lookup
+func (p *productService) isCachedProduct(productID string) bool {
+	set := p.products.Load()
+	if set == nil {
+		return false
+	}
+
+	_, ok := (*set)[productID]
+	if ok {
+		p.metrics.ObserveProductCacheLookups(1, 0)
+	} else {
+		p.metrics.ObserveProductCacheLookups(0, 1)
+	}
+	return ok
+}
+
+// CheckCatalog reports an error until the product catalog has been synced at least once
+func (p *productService) CheckCatalog(ctx context.Context) error {
+	if p.lastSyncedAt.Load() == nil {
+		return errors.New("product catalog has not been loaded yet")
+	}
 	return nil
 }
 
-func (p *productService) IsValidProductID(ctx context.Context, productID string) (bool, error) {
-	return p.store.IsValidProductID(ctx, productID)
+// GetProducts retrieves all products, including retired ones
+func (p *productService) GetProducts(ctx context.Context) ([]models.Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.GetProducts")
+	products, err := p.store.GetProducts(ctx)
+	tracing.End(span, err)
+	return products, err
+}
+
+// CreateProduct creates a manually managed product, generating its ID if none is given
+func (p *productService) CreateProduct(ctx context.Context, req models.CreateProductRequest) (models.Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.CreateProduct")
+	product, err := p.store.CreateProduct(ctx, newManualProduct(req))
+	tracing.End(span, err)
+	if err == nil {
+		p.updateProducts(func(set map[string]struct{}) { set[product.ProductID] = struct{}{} })
+	}
+	return product, err
+}
+
+// UpdateProduct updates the fields of a product set in the request
+func (p *productService) UpdateProduct(ctx context.Context, productID string, req models.UpdateProductRequest) (_ models.Product, err error) {
+	ctx, span := tracer.Start(ctx, "ProductService.UpdateProduct")
+	defer func() { tracing.End(span, err) }()
+
+	product, err := p.store.GetProduct(ctx, productID)
+	if err != nil {
+		return models.Product{}, err
+	}
+
+	if req.Name != nil {
+		product.Name = *req.Name
+	}
+	if req.Pinned != nil {
+		product.Pinned = *req.Pinned
+	}
+
+	return p.store.UpdateProduct(ctx, product)
+}
+
+// RetireProduct retires a product so it no longer accepts votes
+func (p *productService) RetireProduct(ctx context.Context, productID string) (models.Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.RetireProduct")
+	product, err := p.store.SetProductRetired(ctx, productID, true)
+	tracing.End(span, err)
+	if err == nil {
+		p.updateProducts(func(set map[string]struct{}) { delete(set, productID) })
+	}
+	return product, err
+}
+
+// RestoreProduct restores a retired product
+func (p *productService) RestoreProduct(ctx context.Context, productID string) (models.Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.RestoreProduct")
+	product, err := p.store.SetProductRetired(ctx, productID, false)
+	tracing.End(span, err)
+	if err == nil {
+		p.updateProducts(func(set map[string]struct{}) { set[productID] = struct{}{} })
+	}
+	return product, err
+}
+
+// ImportProducts creates or updates manually managed products in bulk
+func (p *productService) ImportProducts(ctx context.Context, reqs []models.CreateProductRequest) (int, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.ImportProducts")
+
+	products := make([]models.Product, 0, len(reqs))
+	for _, req := range reqs {
+		products = append(products, newManualProduct(req))
+	}
+
+	imported, err := p.store.ImportProducts(ctx, products)
+	tracing.End(span, err)
+	if err != nil {
+		return 0, err
+	}
+
+	// Imports keep the retired state of existing products, so the set is reloaded rather than extended.
+	// A failed reload drops the set and validation falls back to the store until the next refresh.
+	p.RefreshProducts(ctx)
+	return imported, nil
+}
+
+// newManualProduct creates a manually managed product from a request
+func newManualProduct(req models.CreateProductRequest) models.Product {
+	productID := req.ProductID
+	if productID == "" {
+		productID = uuid.New().String()
+	}
+
+	return models.Product{
+		ProductID: productID,
+		Name:      req.Name,
+		Source:    models.ProductSourceManual,
+		Pinned:    req.Pinned,
+	}
 }
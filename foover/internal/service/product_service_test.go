@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"foover/internal/config"
+	"foover/internal/metrics"
+	"foover/internal/models"
+	"foover/internal/store/mongo"
+)
+
+// productStore serves products, calling loaded after every load
+type productStore struct {
+	mongo.Store
+	products []models.Product
+	loaded   func()
+}
+
+func (s *productStore) GetProducts(context.Context) ([]models.Product, error) {
+	if s.loaded != nil {
+		s.loaded()
+	}
+	return s.products, nil
+}
+
+func TestRefreshProductsContended(t *testing.T) {
+	store := &productStore{products: []models.Product{{ProductID: "a"}, {ProductID: "b"}}}
+	p := NewProductService(store, config.ProductCache{Enabled: true}, metrics.New()).(*productService)
+	if err := p.RefreshProducts(context.Background()); err != nil {
+		t.Fatalf("RefreshProducts() error = %v", err)
+	}
+	if set := p.products.Load(); set == nil || len(*set) != 2 {
+		t.Fatalf("product set = %v, want products a and b", set)
+	}
+
+	// A mutation lands while every attempt loads the products
+	store.loaded = func() {
+		p.updateProducts(func(set map[string]struct{}) { set["c"] = struct{}{} })
+	}
+	if err := p.RefreshProducts(context.Background()); !errors.Is(err, ErrProductRefreshContended) {
+		t.Fatalf("RefreshProducts() error = %v, want %v", err, ErrProductRefreshContended)
+	}
+	if set := p.products.Load(); set != nil {
+		t.Errorf("product set = %v, want it dropped", *set)
+	}
+}
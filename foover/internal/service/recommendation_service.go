@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"foover/internal/config"
+	"foover/internal/metrics"
+	"foover/internal/models"
+	"foover/internal/recommend"
+	"foover/internal/store/mongo"
+	"foover/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrRecommendationsUnavailable is returned while the recommendation model hasn't been built yet
+var ErrRecommendationsUnavailable = errors.New("recommendations unavailable")
+
+type RecommendationService interface {
+	Refresh(ctx context.Context) error
+	GetRecommendations(ctx context.Context, sessionID string, limit int) (models.GetRecommendationsResponse, error)
+}
+
+// recommendationService implements the RecommendationService interface
+type recommendationService struct {
+	store   mongo.Store
+	cfg     config.Recommendation
+	metrics *metrics.Metrics
+	// model is replaced as a whole on refresh and nil until it is built
+	model atomic.Pointer[recommend.Model]
+}
+
+// NewRecommendationService creates a new RecommendationService
+func NewRecommendationService(store mongo.Store, cfg config.Recommendation, m *metrics.Metrics) RecommendationService {
+	return &recommendationService{
+		store:   store,
+		cfg:     cfg,
+		metrics: m,
+	}
+}
+
+// Refresh rebuilds the recommendation model from all stored votes, the previous model keeps serving until it is done.
+// The ratings of every session are held in memory while the model is built.
+func (r *recommendationService) Refresh(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "RecommendationService.Refresh")
+	defer func() { tracing.End(span, err) }()
+
+	products, err := r.store.GetProducts(ctx)
+	if err != nil {
+		return err
+	}
+	votable := make(map[string]struct{}, len(products))
+	for _, product := range products {
+		if product.RetiredAt == nil {
+			votable[product.ProductID] = struct{}{}
+		}
+	}
+
+	builder := recommend.NewBuilder(r.cfg)
+	err = r.store.StreamVotes(ctx, func(vote models.Vote) error {
+		builder.AddVote(vote)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	model := builder.Build(votable)
+	r.model.Store(model)
+	r.metrics.SetRecommendationModelProducts(model.Products())
+	span.SetAttributes(attribute.Int("recommendation.products", model.Products()))
+
+	return nil
+}
+
+// GetRecommendations returns up to limit products the session hasn't voted on, best first.
+// mongo.ErrNotFound is returned if the session doesn't exist.
+func (r *recommendationService) GetRecommendations(ctx context.Context, sessionID string, limit int) (response models.GetRecommendationsResponse, err error) {
+	ctx, span := tracer.Start(ctx, "RecommendationService.GetRecommendations")
+	defer func() { tracing.End(span, err) }()
+
+	model := r.model.Load()
+	if model == nil {
+		return models.GetRecommendationsResponse{}, ErrRecommendationsUnavailable
+	}
+
+	exists, err := r.store.SessionExists(ctx, sessionID)
+	if err != nil {
+		return models.GetRecommendationsResponse{}, err
+	}
+	if !exists {
+		return models.GetRecommendationsResponse{}, mongo.ErrNotFound
+	}
+
+	votes, err := r.store.GetVotesBySessionID(ctx, sessionID)
+	if err != nil {
+		return models.GetRecommendationsResponse{}, err
+	}
+
+	recommendations := model.Recommend(votes, limit)
+	for _, recommendation := range recommendations {
+		r.metrics.IncRecommendations(recommendation.Source)
+	}
+
+	return models.GetRecommendationsResponse{
+		SessionID:       sessionID,
+		Recommendations: recommendations,
+		ModelBuiltAt:    model.BuiltAt(),
+	}, nil
+}
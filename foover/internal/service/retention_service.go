@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"foover/internal/config"
+	"foover/internal/metrics"
+	"foover/internal/models"
+	"foover/internal/store/mongo"
+	"foover/internal/tracing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type RetentionService interface {
+	Run(ctx context.Context, dryRun bool) (models.RetentionReport, error)
+}
+
+// retentionService implements the RetentionService interface
+type retentionService struct {
+	store   mongo.Store
+	cfg     config.Retention
+	metrics *metrics.Metrics
+}
+
+// NewRetentionService creates a new RetentionService applying the configured retention rules
+func NewRetentionService(store mongo.Store, cfg config.Retention, m *metrics.Metrics) RetentionService {
+	return &retentionService{
+		store:   store,
+		cfg:     cfg,
+		metrics: m,
+	}
+}
+
+// Run applies the retention rules, or only counts what they would remove in a dry run.
+// Sessions without votes are deleted and old votes are anonymized in batches, aggregated scores are kept
+// because anonymized votes keep their product and score. Anonymizing votes runs first,
+// so sessions left without votes are deleted once they reach the empty session age.
+func (r *retentionService) Run(ctx context.Context, dryRun bool) (report models.RetentionReport, err error) {
+	ctx, span := tracer.Start(ctx, "RetentionService.Run")
+	defer func() { tracing.End(span, err) }()
+	span.SetAttributes(attribute.Bool("retention.dry_run", dryRun))
+
+	report = models.RetentionReport{
+		DryRun:    dryRun,
+		StartedAt: time.Now(),
+	}
+	defer func() {
+		report.FinishedAt = time.Now()
+		r.metrics.ObserveRetentionRun(dryRun, map[string]int64{
+			metrics.RetentionRuleEmptySessions:  report.EmptySessions,
+			metrics.RetentionRuleAnonymizeVotes: report.AnonymizedVotes,
+		}, err)
+	}()
+
+	if r.cfg.VoteAnonymizeAfterMonths > 0 {
+		cutoff := report.StartedAt.AddDate(0, -r.cfg.VoteAnonymizeAfterMonths, 0)
+		report.VoteCutoff = &cutoff
+		if dryRun {
+			report.AnonymizedVotes, err = r.store.CountVotesToAnonymize(ctx, cutoff)
+		} else {
+			report.AnonymizedVotes, err = r.inBatches(ctx, func(ctx context.Context) (int64, error) {
+				return r.store.AnonymizeVotes(ctx, cutoff, r.cfg.BatchSize)
+			})
+		}
+		if err != nil {
+			return report, err
+		}
+	}
+
+	if r.cfg.EmptySessionMaxAge > 0 {
+		cutoff := report.StartedAt.Add(-r.cfg.EmptySessionMaxAge)
+		report.EmptySessionCutoff = &cutoff
+		if dryRun {
+			report.EmptySessions, err = r.store.CountEmptySessions(ctx, cutoff)
+		} else {
+			report.EmptySessions, err = r.inBatches(ctx, func(ctx context.Context) (int64, error) {
+				return r.store.DeleteEmptySessions(ctx, cutoff, r.cfg.BatchSize)
+			})
+		}
+		if err != nil {
+			return report, err
+		}
+	}
+
+	span.SetAttributes(
+		attribute.Int64("retention.empty_sessions", report.EmptySessions),
+		attribute.Int64("retention.anonymized_votes", report.AnonymizedVotes),
+	)
+	return report, nil
+}
+
+// inBatches calls fn until a batch processes fewer records than the batch size and returns the total processed
+func (r *retentionService) inBatches(ctx context.Context, fn func(ctx context.Context) (int64, error)) (int64, error) {
+	var total int64
+	for {
+		n, err := fn(ctx)
+		total += n
+		if err != nil || n < r.cfg.BatchSize {
+			return total, err
+		}
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"foover/internal/cache"
+	"foover/internal/metrics"
+	"foover/internal/models"
+	"foover/internal/tracing"
+	"io"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// aggregatedScoresCacheKey is the cache key of the aggregated scores of all products
+const aggregatedScoresCacheKey = "aggregated_scores"
+
+// cachedAggregationService implements the AggregationService interface by reading through a cache
+type cachedAggregationService struct {
+	next    AggregationService
+	backend cache.Backend
+	ttl     time.Duration
+	metrics *metrics.Metrics
+}
+
+// NewCachedAggregationService creates a new AggregationService caching the aggregated scores of next for ttl.
+// Votes saved, moderated, erased or anonymized through the score invalidating services on the same backend invalidate the cache.
+func NewCachedAggregationService(next AggregationService, backend cache.Backend, ttl time.Duration, m *metrics.Metrics) AggregationService {
+	return &cachedAggregationService{
+		next:    next,
+		backend: backend,
+		ttl:     ttl,
+		metrics: m,
+	}
+}
+
+// GetAggregatedProductScores returns the cached scores or computes and caches them.
+// Cache failures are recorded and the scores are computed, the cache must not take the endpoint down.
+func (c *cachedAggregationService) GetAggregatedProductScores(ctx context.Context) (scores []models.ProductScore, err error) {
+	ctx, span := tracer.Start(ctx, "CachedAggregationService.GetAggregatedProductScores")
+	defer func() { tracing.End(span, err) }()
+
+	cached, ok, cacheErr := c.backend.Get(ctx, aggregatedScoresCacheKey)
+	if cacheErr != nil {
+		span.RecordError(cacheErr)
+	}
+	if ok && json.Unmarshal(cached, &scores) == nil {
+		span.SetAttributes(attribute.Bool("cache.hit", true))
+		c.metrics.IncScoreCacheLookups(true)
+		return scores, nil
+	}
+	span.SetAttributes(attribute.Bool("cache.hit", false))
+	c.metrics.IncScoreCacheLookups(false)
+
+	scores, err = c.next.GetAggregatedProductScores(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// A vote saved while the scores were computed may be missing until the entry expires
+	value, err := json.Marshal(scores)
+	if err != nil {
+		return nil, err
+	}
+	if cacheErr := c.backend.Set(ctx, aggregatedScoresCacheKey, value, c.ttl); cacheErr != nil {
+		span.RecordError(cacheErr)
+	}
+
+	return scores, nil
+}
+
+// scoreInvalidatingVoteService implements the VoteService interface by invalidating cached aggregated scores
+// after votes that count towards them changed
+type scoreInvalidatingVoteService struct {
+	VoteService
+	backend cache.Backend
+}
+
+// NewScoreInvalidatingVoteService creates a new VoteService invalidating the aggregated scores cached on backend
+// after next saved, imported or moderated votes
+func NewScoreInvalidatingVoteService(next VoteService, backend cache.Backend) VoteService {
+	return &scoreInvalidatingVoteService{
+		VoteService: next,
+		backend:     backend,
+	}
+}
+
+func (s *scoreInvalidatingVoteService) SaveVote(ctx context.Context, vote models.Vote) error {
+	if err := s.VoteService.SaveVote(ctx, vote); err != nil {
+		return err
+	}
+	s.invalidate(ctx)
+	return nil
+}
+
+func (s *scoreInvalidatingVoteService) SaveVotes(ctx context.Context, votes []models.Vote, atomic bool) (map[int]string, error) {
+	failed, err := s.VoteService.SaveVotes(ctx, votes, atomic)
+	if err != nil {
+		return nil, err
+	}
+	if len(failed) < len(votes) {
+		s.invalidate(ctx)
+	}
+	return failed, nil
+}
+
+func (s *scoreInvalidatingVoteService) ApproveVote(ctx context.Context, id string) (models.Vote, error) {
+	vote, err := s.VoteService.ApproveVote(ctx, id)
+	if err != nil {
+		return models.Vote{}, err
+	}
+	s.invalidate(ctx)
+	return vote, nil
+}
+
+func (s *scoreInvalidatingVoteService) RejectVote(ctx context.Context, id string) (models.Vote, error) {
+	vote, err := s.VoteService.RejectVote(ctx, id)
+	if err != nil {
+		return models.Vote{}, err
+	}
+	s.invalidate(ctx)
+	return vote, nil
+}
+
+func (s *scoreInvalidatingVoteService) ImportVotes(ctx context.Context, format string, r io.Reader, dryRun bool) (models.ImportVotesResponse, error) {
+	response, err := s.VoteService.ImportVotes(ctx, format, r, dryRun)
+	// A failed import may have stored some batches already
+	if !dryRun && (err != nil || response.Imported > 0) {
+		s.invalidate(ctx)
+	}
+	return response, err
+}
+
+func (s *scoreInvalidatingVoteService) ReplayBufferedVotes(ctx context.Context) (int, error) {
+	replayed, err := s.VoteService.ReplayBufferedVotes(ctx)
+	if replayed > 0 {
+		s.invalidate(ctx)
+	}
+	return replayed, err
+}
+
+func (s *scoreInvalidatingVoteService) invalidate(ctx context.Context) {
+	invalidateScores(ctx, s.backend)
+}
+
+// scoreInvalidatingSessionService implements the SessionService interface by invalidating cached aggregated scores
+// after the votes of a session were erased
+type scoreInvalidatingSessionService struct {
+	SessionService
+	backend cache.Backend
+}
+
+// NewScoreInvalidatingSessionService creates a new SessionService invalidating the aggregated scores cached on backend
+// after next erased a session, erased votes must not be served from the cache
+func NewScoreInvalidatingSessionService(next SessionService, backend cache.Backend) SessionService {
+	return &scoreInvalidatingSessionService{
+		SessionService: next,
+		backend:        backend,
+	}
+}
+
+func (s *scoreInvalidatingSessionService) EraseSession(ctx context.Context, sessionID string, requestID string) (models.ErasureAuditEntry, error) {
+	entry, err := s.SessionService.EraseSession(ctx, sessionID, requestID)
+	if err != nil {
+		return models.ErasureAuditEntry{}, err
+	}
+	invalidateScores(ctx, s.backend)
+	return entry, nil
+}
+
+// scoreInvalidatingRetentionService implements the RetentionService interface by invalidating cached aggregated scores
+// after a retention run changed stored votes or sessions
+type scoreInvalidatingRetentionService struct {
+	RetentionService
+	backend cache.Backend
+}
+
+// NewScoreInvalidatingRetentionService creates a new RetentionService invalidating the aggregated scores cached on backend
+// after next anonymized votes or deleted sessions
+func NewScoreInvalidatingRetentionService(next RetentionService, backend cache.Backend) RetentionService {
+	return &scoreInvalidatingRetentionService{
+		RetentionService: next,
+		backend:          backend,
+	}
+}
+
+func (s *scoreInvalidatingRetentionService) Run(ctx context.Context, dryRun bool) (models.RetentionReport, error) {
+	report, err := s.RetentionService.Run(ctx, dryRun)
+	// A failed run may have applied some of the rules already
+	if !dryRun && (err != nil || report.AnonymizedVotes > 0 || report.EmptySessions > 0) {
+		invalidateScores(ctx, s.backend)
+	}
+	return report, err
+}
+
+// invalidateScores removes the cached aggregated scores, failures are recorded and left to the cache TTL,
+// the changes are stored already
+func invalidateScores(ctx context.Context, backend cache.Backend) {
+	if err := backend.Delete(ctx, aggregatedScoresCacheKey); err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+	}
+}
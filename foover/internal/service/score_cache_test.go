@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"foover/internal/cache"
+	"foover/internal/metrics"
+	"foover/internal/models"
+)
+
+// countingAggregationService counts how often the aggregated scores were computed
+type countingAggregationService struct {
+	computed int
+}
+
+func (a *countingAggregationService) GetAggregatedProductScores(_ context.Context) ([]models.ProductScore, error) {
+	a.computed++
+	return []models.ProductScore{}, nil
+}
+
+// stubVoteService moderates every vote, the other methods are not called
+type stubVoteService struct {
+	VoteService
+}
+
+func (stubVoteService) ApproveVote(_ context.Context, _ string) (models.Vote, error) {
+	return models.Vote{Status: models.VoteStatusApproved}, nil
+}
+
+// stubSessionService erases every session, the other methods are not called
+type stubSessionService struct {
+	SessionService
+}
+
+func (stubSessionService) EraseSession(_ context.Context, _ string, requestID string) (models.ErasureAuditEntry, error) {
+	return models.ErasureAuditEntry{VotesDeleted: 1, RequestID: requestID}, nil
+}
+
+// stubRetentionService reports the given number of anonymized votes
+type stubRetentionService struct {
+	anonymized int64
+}
+
+func (s stubRetentionService) Run(_ context.Context, dryRun bool) (models.RetentionReport, error) {
+	return models.RetentionReport{DryRun: dryRun, AnonymizedVotes: s.anonymized}, nil
+}
+
+func TestScoreCacheInvalidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		change      func(ctx context.Context, backend cache.Backend) error
+		invalidated bool
+	}{
+		{
+			name: "approved vote",
+			change: func(ctx context.Context, backend cache.Backend) error {
+				_, err := NewScoreInvalidatingVoteService(stubVoteService{}, backend).ApproveVote(ctx, "v1")
+				return err
+			},
+			invalidated: true,
+		},
+		{
+			name: "erased session",
+			change: func(ctx context.Context, backend cache.Backend) error {
+				_, err := NewScoreInvalidatingSessionService(stubSessionService{}, backend).EraseSession(ctx, "s1", "r1")
+				return err
+			},
+			invalidated: true,
+		},
+		{
+			name: "retention run anonymizing votes",
+			change: func(ctx context.Context, backend cache.Backend) error {
+				_, err := NewScoreInvalidatingRetentionService(stubRetentionService{anonymized: 3}, backend).Run(ctx, false)
+				return err
+			},
+			invalidated: true,
+		},
+		{
+			name: "retention dry run",
+			change: func(ctx context.Context, backend cache.Backend) error {
+				_, err := NewScoreInvalidatingRetentionService(stubRetentionService{anonymized: 3}, backend).Run(ctx, true)
+				return err
+			},
+			invalidated: false,
+		},
+		{
+			name: "retention run without changes",
+			change: func(ctx context.Context, backend cache.Backend) error {
+				_, err := NewScoreInvalidatingRetentionService(stubRetentionService{}, backend).Run(ctx, false)
+				return err
+			},
+			invalidated: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			backend := cache.NewMemoryBackend()
+			next := &countingAggregationService{}
+			aggregationService := NewCachedAggregationService(next, backend, time.Hour, metrics.New())
+
+			if _, err := aggregationService.GetAggregatedProductScores(ctx); err != nil {
+				t.Fatalf("GetAggregatedProductScores() error = %v", err)
+			}
+			if err := tt.change(ctx, backend); err != nil {
+				t.Fatalf("change error = %v", err)
+			}
+			if _, err := aggregationService.GetAggregatedProductScores(ctx); err != nil {
+				t.Fatalf("GetAggregatedProductScores() error = %v", err)
+			}
+
+			want := 1
+			if tt.invalidated {
+				want = 2
+			}
+			if next.computed != want {
+				t.Errorf("scores computed %d times, want %d", next.computed, want)
+			}
+		})
+	}
+}
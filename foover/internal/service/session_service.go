@@ -2,32 +2,153 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"foover/internal/auth"
+	"foover/internal/config"
+	"foover/internal/experiment"
+	"foover/internal/metrics"
+	"foover/internal/models"
 	"foover/internal/store/mongo"
+	"foover/internal/tracing"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type SessionService interface {
-	CreateSession(ctx context.Context) (string, error)
+	CreateSession(ctx context.Context) (models.Session, error)
 	SessionExists(ctx context.Context, sessionID string) (bool, error)
+	ExportSession(ctx context.Context, sessionID string) (models.SessionExport, error)
+	EraseSession(ctx context.Context, sessionID string, requestID string) (models.ErasureAuditEntry, error)
 }
 
 // sessionService implements the SessionService interface
 type sessionService struct {
-	store mongo.Store
+	store   mongo.Store
+	cfg     config.Experiment
+	metrics *metrics.Metrics
+	// experiments holds the running experiments, replaced as a whole once it is older than the cache TTL
+	experiments atomic.Pointer[runningExperiments]
+}
+
+// runningExperiments is the cached set of running experiments and when it was loaded
+type runningExperiments struct {
+	experiments []models.Experiment
+	loadedAt    time.Time
 }
 
 // NewSessionService creates a new SessionService
-func NewSessionService(store mongo.Store) SessionService {
+func NewSessionService(store mongo.Store, cfg config.Experiment, m *metrics.Metrics) SessionService {
 	return &sessionService{
-		store: store,
+		store:   store,
+		cfg:     cfg,
+		metrics: m,
+	}
+}
+
+// CreateSession creates a new session with a unique session ID, assigned to a variant of every running experiment
+func (s *sessionService) CreateSession(ctx context.Context) (session models.Session, err error) {
+	ctx, span := tracer.Start(ctx, "SessionService.CreateSession")
+	defer func() { tracing.End(span, err) }()
+
+	experiments, err := s.runningExperiments(ctx)
+	if err != nil {
+		return models.Session{}, err
+	}
+
+	sessionID := uuid.New().String()
+	session = models.Session{
+		SessionID: sessionID,
+		CreatedAt: time.Now(),
+		Variants:  experiment.Assign(experiments, sessionID),
+	}
+	if err := s.store.CreateSession(ctx, session); err != nil {
+		return models.Session{}, err
+	}
+
+	for _, assignment := range session.Variants {
+		s.metrics.IncExperimentAssignments(assignment.Experiment, assignment.Variant)
 	}
+	return session, nil
 }
 
-// CreateSession creates a new session
-func (s *sessionService) CreateSession(ctx context.Context) (string, error) {
-	return s.store.CreateSession(ctx)
+// runningExperiments returns the running experiments, loading them from the store once the cached ones are older
+// than the cache TTL, so starting or stopping an experiment affects new sessions within the TTL.
+func (s *sessionService) runningExperiments(ctx context.Context) ([]models.Experiment, error) {
+	if cached := s.experiments.Load(); cached != nil && time.Since(cached.loadedAt) < s.cfg.CacheTTL {
+		return cached.experiments, nil
+	}
+
+	experiments, err := s.store.GetRunningExperiments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.experiments.Store(&runningExperiments{experiments: experiments, loadedAt: time.Now()})
+	return experiments, nil
 }
 
 // SessionExists checks if a session with the given sessionID exists
 func (s *sessionService) SessionExists(ctx context.Context, sessionID string) (bool, error) {
-	return s.store.SessionExists(ctx, sessionID)
+	ctx, span := tracer.Start(ctx, "SessionService.SessionExists")
+	exists, err := s.store.SessionExists(ctx, sessionID)
+	tracing.End(span, err)
+	return exists, err
+}
+
+// ExportSession collects everything stored for a session
+func (s *sessionService) ExportSession(ctx context.Context, sessionID string) (export models.SessionExport, err error) {
+	ctx, span := tracer.Start(ctx, "SessionService.ExportSession")
+	defer func() { tracing.End(span, err) }()
+
+	session, err := s.store.GetSession(ctx, sessionID)
+	if err != nil {
+		return models.SessionExport{}, err
+	}
+
+	votes, err := s.store.GetVotesBySessionID(ctx, sessionID)
+	if err != nil {
+		return models.SessionExport{}, err
+	}
+
+	// Returning empty arrays if the session has no votes
+	if votes == nil {
+		votes = []models.Vote{}
+	}
+
+	export = models.SessionExport{
+		Session:            session,
+		Votes:              votes,
+		ClientFingerprints: []string{},
+		ExportedAt:         time.Now(),
+	}
+	seen := make(map[string]bool)
+	for _, vote := range votes {
+		if vote.Fingerprint != "" && !seen[vote.Fingerprint] {
+			seen[vote.Fingerprint] = true
+			export.ClientFingerprints = append(export.ClientFingerprints, vote.Fingerprint)
+		}
+	}
+
+	return export, nil
+}
+
+// EraseSession deletes a session and its votes and records the erasure with the principal requesting it
+func (s *sessionService) EraseSession(ctx context.Context, sessionID string, requestID string) (models.ErasureAuditEntry, error) {
+	ctx, span := tracer.Start(ctx, "SessionService.EraseSession")
+	entry := models.ErasureAuditEntry{
+		SessionIDHash: hashSessionID(sessionID),
+		Principal:     auth.PrincipalFromContext(ctx).Name,
+		RequestID:     requestID,
+	}
+	entry, err := s.store.EraseSession(ctx, sessionID, entry)
+	tracing.End(span, err)
+	return entry, err
+}
+
+// hashSessionID returns the hash under which erased sessions are audited
+func hashSessionID(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"foover/internal/config"
+	"foover/internal/metrics"
+	"foover/internal/models"
+	"foover/internal/recommend"
+	"foover/internal/store/mongo"
+	"foover/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// similarityLease is the name of the lease held by the replica refreshing the similarity index
+const similarityLease = "similarity_index"
+
+// similarityRefreshOverlap is scanned again before the previous refresh, so votes stamped before it started
+// but committed after it read them are picked up
+const similarityRefreshOverlap = time.Minute
+
+type SimilarityService interface {
+	Refresh(ctx context.Context) error
+	GetSimilarProducts(ctx context.Context, productID string, limit int) ([]models.SimilarProduct, error)
+}
+
+// similarityService implements the SimilarityService interface
+type similarityService struct {
+	store   mongo.Store
+	cfg     config.Similarity
+	owner   string
+	metrics *metrics.Metrics
+}
+
+// NewSimilarityService creates a new SimilarityService, owner identifies the replica
+func NewSimilarityService(store mongo.Store, cfg config.Similarity, owner string, m *metrics.Metrics) SimilarityService {
+	return &similarityService{
+		store:   store,
+		cfg:     cfg,
+		owner:   owner,
+		metrics: m,
+	}
+}
+
+// Refresh brings the similarity index up to date. Only the pairs involving a product whose votes changed
+// since the last refresh are recomputed, all pairs are every FullRebuildInterval or when the method changes.
+// Recomputed similarities replace the stored ones, then the ones that no longer qualify are deleted,
+// so lookups never see the index empty. Only the replica holding the similarity lease refreshes.
+func (s *similarityService) Refresh(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "SimilarityService.Refresh")
+	defer func() { tracing.End(span, err) }()
+
+	leader, err := s.store.AcquireLease(ctx, similarityLease, s.owner, s.cfg.LeaseTTL)
+	if err != nil || !leader {
+		span.SetAttributes(attribute.Bool("similarity.leader", leader))
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.LeaseTTL)
+	defer cancel()
+
+	startedAt := time.Now()
+	state, err := s.store.GetSimilarityIndexState(ctx)
+	if err != nil && !errors.Is(err, mongo.ErrNotFound) {
+		return err
+	}
+	full := state.Method != s.cfg.Method || startedAt.Sub(state.FullBuiltAt) >= s.cfg.FullRebuildInterval
+
+	// A nil list of products rebuilds all pairs
+	var productIDs []string
+	if !full {
+		productIDs, err = s.store.GetChangedVoteProductIDs(ctx, state.BuiltAt.Add(-similarityRefreshOverlap))
+		if err != nil {
+			return err
+		}
+		span.SetAttributes(attribute.Int("similarity.changed_products", len(productIDs)))
+	}
+	span.SetAttributes(attribute.Bool("similarity.full", full))
+
+	if full || len(productIDs) > 0 {
+		builder := recommend.NewSimilarityBuilder(s.cfg.Method, s.cfg.MinSupport, productIDs)
+		err = s.store.StreamCoRatingVotes(ctx, productIDs, func(vote models.Vote) error {
+			builder.AddVote(vote)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		similarities := builder.Build()
+		if err := s.store.SaveProductSimilarities(ctx, similarities, startedAt); err != nil {
+			return err
+		}
+		deleted, err := s.store.DeleteStaleProductSimilarities(ctx, productIDs, startedAt)
+		if err != nil {
+			return err
+		}
+		span.SetAttributes(attribute.Int("similarity.saved", len(similarities)), attribute.Int64("similarity.deleted", deleted))
+	}
+
+	state = models.SimilarityIndexState{
+		Method:      s.cfg.Method,
+		BuiltAt:     startedAt,
+		FullBuiltAt: state.FullBuiltAt,
+	}
+	if full {
+		state.FullBuiltAt = startedAt
+	}
+	if err := s.store.SaveSimilarityIndexState(ctx, state); err != nil {
+		return err
+	}
+	s.metrics.IncSimilarityRefreshes(full)
+
+	return nil
+}
+
+// GetSimilarProducts returns up to limit products similar to a product, most similar first.
+// mongo.ErrNotFound is returned if the product doesn't exist.
+func (s *similarityService) GetSimilarProducts(ctx context.Context, productID string, limit int) (similar []models.SimilarProduct, err error) {
+	ctx, span := tracer.Start(ctx, "SimilarityService.GetSimilarProducts")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.store.GetProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	return s.store.GetSimilarProducts(ctx, productID, int64(limit))
+}
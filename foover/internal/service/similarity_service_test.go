@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"foover/internal/config"
+	"foover/internal/metrics"
+	"foover/internal/models"
+	"foover/internal/recommend"
+	"foover/internal/store/mongo"
+	"foover/internal/testutil"
+)
+
+// similarityStore is an in-memory store of votes, erasures and product similarities
+type similarityStore struct {
+	mongo.Store
+	votes        []models.Vote
+	erasures     []models.ErasureAuditEntry
+	similarities map[[2]string]models.ProductSimilarity
+	state        *models.SimilarityIndexState
+}
+
+func (s *similarityStore) AcquireLease(context.Context, string, string, time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (s *similarityStore) EraseSession(_ context.Context, sessionID string, entry models.ErasureAuditEntry) (models.ErasureAuditEntry, error) {
+	kept := s.votes[:0]
+	for _, vote := range s.votes {
+		if vote.SessionID != sessionID {
+			kept = append(kept, vote)
+		} else if !slices.Contains(entry.ProductIDs, vote.ProductID) {
+			entry.ProductIDs = append(entry.ProductIDs, vote.ProductID)
+		}
+	}
+	entry.VotesDeleted = int64(len(s.votes) - len(kept))
+	entry.ErasedAt = time.Now()
+	s.votes = kept
+	s.erasures = append(s.erasures, entry)
+	return entry, nil
+}
+
+func (s *similarityStore) GetChangedVoteProductIDs(_ context.Context, since time.Time) ([]string, error) {
+	var productIDs []string
+	for _, vote := range s.votes {
+		if !vote.UpdatedAt.Before(since) && !slices.Contains(productIDs, vote.ProductID) {
+			productIDs = append(productIDs, vote.ProductID)
+		}
+	}
+	for _, erasure := range s.erasures {
+		for _, productID := range erasure.ProductIDs {
+			if !erasure.ErasedAt.Before(since) && !slices.Contains(productIDs, productID) {
+				productIDs = append(productIDs, productID)
+			}
+		}
+	}
+	return productIDs, nil
+}
+
+func (s *similarityStore) StreamCoRatingVotes(_ context.Context, productIDs []string, fn func(models.Vote) error) error {
+	sessions := make(map[string]bool)
+	for _, vote := range s.votes {
+		if productIDs == nil || slices.Contains(productIDs, vote.ProductID) {
+			sessions[vote.SessionID] = true
+		}
+	}
+	for _, vote := range s.votes {
+		if sessions[vote.SessionID] {
+			if err := fn(vote); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *similarityStore) SaveProductSimilarities(_ context.Context, similarities []models.ProductSimilarity, builtAt time.Time) error {
+	for _, similarity := range similarities {
+		similarity.BuiltAt = builtAt
+		s.similarities[[2]string{similarity.ProductID, similarity.SimilarProductID}] = similarity
+	}
+	return nil
+}
+
+func (s *similarityStore) DeleteStaleProductSimilarities(_ context.Context, productIDs []string, builtBefore time.Time) (int64, error) {
+	var deleted int64
+	for key, similarity := range s.similarities {
+		involved := productIDs == nil || slices.Contains(productIDs, key[0]) || slices.Contains(productIDs, key[1])
+		if involved && similarity.BuiltAt.Before(builtBefore) {
+			delete(s.similarities, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s *similarityStore) GetSimilarityIndexState(context.Context) (models.SimilarityIndexState, error) {
+	if s.state == nil {
+		return models.SimilarityIndexState{}, mongo.ErrNotFound
+	}
+	return *s.state, nil
+}
+
+func (s *similarityStore) SaveSimilarityIndexState(_ context.Context, state models.SimilarityIndexState) error {
+	s.state = &state
+	return nil
+}
+
+func TestSimilarityRefreshAfterErasure(t *testing.T) {
+	ctx := context.Background()
+	votedAt := time.Now().Add(-time.Hour)
+
+	// Products a and b are rated by the sessions s0 to s4, c and d by other sessions
+	store := &similarityStore{similarities: make(map[[2]string]models.ProductSimilarity)}
+	store.votes = append(store.votes, testutil.Votes("a", votedAt, 1, 2, 3, 4, 5)...)
+	store.votes = append(store.votes, testutil.Votes("b", votedAt, 2, 4, 5, 4, 5)...)
+	for _, productVotes := range [][]models.Vote{testutil.Votes("c", votedAt, 1, 3, 5), testutil.Votes("d", votedAt, 2, 3, 5)} {
+		for _, vote := range productVotes {
+			vote.SessionID = "other_" + vote.SessionID
+			store.votes = append(store.votes, vote)
+		}
+	}
+
+	cfg := config.Similarity{Method: recommend.SimilarityPearson, MinSupport: 3, LeaseTTL: time.Minute, FullRebuildInterval: time.Hour}
+	similarityService := NewSimilarityService(store, cfg, "replica", metrics.New())
+	if err := similarityService.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	built := store.similarities[[2]string{"c", "d"}].BuiltAt
+
+	if _, err := store.EraseSession(ctx, "s0", models.ErasureAuditEntry{}); err != nil {
+		t.Fatalf("EraseSession() error = %v", err)
+	}
+	if err := similarityService.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	// The pairs of the erased votes' products are recomputed without them, the others are left as they were
+	for _, key := range [][2]string{{"a", "b"}, {"b", "a"}} {
+		got := store.similarities[key]
+		if got.Similarity != 0.4472 || got.Support != 4 || !got.BuiltAt.After(built) {
+			t.Errorf("similarity of %v = %+v, want 0.4472 of 4 sessions rebuilt after %v", key, got, built)
+		}
+	}
+	if got := store.similarities[[2]string{"c", "d"}]; got.Similarity != 0.982 || !got.BuiltAt.Equal(built) {
+		t.Errorf("similarity of c and d = %+v, want 0.982 built at %v", got, built)
+	}
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"foover/internal/cache"
+	"foover/internal/config"
+	"foover/internal/metrics"
+	"foover/internal/models"
+	"foover/internal/store/mongo"
+	"foover/internal/tracing"
+	"foover/internal/trending"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
+)
+
+// trendingCacheKey is the cache key of the ranking of trending products
+const trendingCacheKey = "trending"
+
+type TrendingService interface {
+	GetTrending(ctx context.Context, limit int) (models.GetTrendingResponse, error)
+	Refresh(ctx context.Context) error
+}
+
+// trendingService implements the TrendingService interface
+type trendingService struct {
+	store   mongo.Store
+	backend cache.Backend
+	cfg     config.Trending
+	metrics *metrics.Metrics
+	// ranking lets concurrent cache misses share one computation of the ranking
+	ranking singleflight.Group
+}
+
+// NewTrendingService creates a new TrendingService caching the computed ranking on backend for CacheTTL
+func NewTrendingService(store mongo.Store, backend cache.Backend, cfg config.Trending, m *metrics.Metrics) TrendingService {
+	return &trendingService{
+		store:   store,
+		backend: backend,
+		cfg:     cfg,
+		metrics: m,
+	}
+}
+
+// GetTrending returns up to limit of the most trending products. The whole ranking is cached and isn't invalidated
+// by new votes, it moves with time anyway. It is kept cached by Refresh, a cache miss computes it once for all
+// concurrent requests. Cache failures are recorded and the ranking is computed.
+func (t *trendingService) GetTrending(ctx context.Context, limit int) (response models.GetTrendingResponse, err error) {
+	ctx, span := tracer.Start(ctx, "TrendingService.GetTrending")
+	defer func() { tracing.End(span, err) }()
+
+	cached, ok, cacheErr := t.backend.Get(ctx, trendingCacheKey)
+	if cacheErr != nil {
+		span.RecordError(cacheErr)
+	}
+	if ok && json.Unmarshal(cached, &response) == nil {
+		span.SetAttributes(attribute.Bool("cache.hit", true))
+		t.metrics.IncTrendingCacheLookups(true)
+		return truncateTrending(response, limit), nil
+	}
+	span.SetAttributes(attribute.Bool("cache.hit", false))
+	t.metrics.IncTrendingCacheLookups(false)
+
+	// The computation outlives a request that gives up waiting, the others still wait for it
+	result := t.ranking.DoChan(trendingCacheKey, func() (interface{}, error) {
+		return t.refresh(context.WithoutCancel(ctx))
+	})
+	select {
+	case <-ctx.Done():
+		return models.GetTrendingResponse{}, ctx.Err()
+	case res := <-result:
+		if res.Err != nil {
+			return models.GetTrendingResponse{}, res.Err
+		}
+		span.SetAttributes(attribute.Bool("trending.shared", res.Shared))
+		return truncateTrending(res.Val.(models.GetTrendingResponse), limit), nil
+	}
+}
+
+// Refresh computes the ranking and caches it, refreshing it before the cached ranking expires keeps requests
+// from computing it
+func (t *trendingService) Refresh(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "TrendingService.Refresh")
+	defer func() { tracing.End(span, err) }()
+
+	result := t.ranking.DoChan(trendingCacheKey, func() (interface{}, error) {
+		return t.refresh(ctx)
+	})
+	return (<-result).Err
+}
+
+// refresh computes the ranking and caches it, cache failures are recorded and the ranking returned anyway
+func (t *trendingService) refresh(ctx context.Context) (models.GetTrendingResponse, error) {
+	response, err := t.rank(ctx)
+	if err != nil {
+		return models.GetTrendingResponse{}, err
+	}
+
+	value, err := json.Marshal(response)
+	if err != nil {
+		return models.GetTrendingResponse{}, err
+	}
+	if cacheErr := t.backend.Set(ctx, trendingCacheKey, value, t.cfg.CacheTTL); cacheErr != nil {
+		trace.SpanFromContext(ctx).RecordError(cacheErr)
+	}
+
+	return response, nil
+}
+
+// rank computes the ranking of all votable trending products from the votes of the trending and baseline windows
+func (t *trendingService) rank(ctx context.Context) (models.GetTrendingResponse, error) {
+	products, err := t.store.GetProducts(ctx)
+	if err != nil {
+		return models.GetTrendingResponse{}, err
+	}
+	votable := make(map[string]struct{}, len(products))
+	for _, product := range products {
+		if product.RetiredAt == nil {
+			votable[product.ProductID] = struct{}{}
+		}
+	}
+
+	now := time.Now()
+	ranker := trending.NewRanker(t.cfg, now)
+	err = t.store.StreamCountedVotesSince(ctx, ranker.BaselineStart(), func(vote models.Vote) error {
+		ranker.AddVote(vote)
+		return nil
+	})
+	if err != nil {
+		return models.GetTrendingResponse{}, err
+	}
+
+	return models.GetTrendingResponse{
+		Products:      ranker.Rank(votable),
+		WindowStart:   ranker.WindowStart(),
+		BaselineStart: ranker.BaselineStart(),
+		ComputedAt:    now,
+	}, nil
+}
+
+// truncateTrending keeps the limit most trending products of a ranking
+func truncateTrending(response models.GetTrendingResponse, limit int) models.GetTrendingResponse {
+	if len(response.Products) > limit {
+		response.Products = response.Products[:limit]
+	}
+	return response
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"foover/internal/cache"
+	"foover/internal/config"
+	"foover/internal/metrics"
+	"foover/internal/models"
+	"foover/internal/store/mongo"
+	"foover/internal/testutil"
+)
+
+// trendingStore serves the votes of the trending window, holding every ranking until release is closed
+type trendingStore struct {
+	mongo.Store
+	votes   []models.Vote
+	release chan struct{}
+	ranked  atomic.Int32
+}
+
+func (s *trendingStore) GetProducts(ctx context.Context) ([]models.Product, error) {
+	s.ranked.Add(1)
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return []models.Product{{ProductID: "a"}}, nil
+}
+
+func (s *trendingStore) StreamCountedVotesSince(_ context.Context, _ time.Time, fn func(models.Vote) error) error {
+	for _, vote := range s.votes {
+		if err := fn(vote); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestGetTrendingSharesRanking(t *testing.T) {
+	store := &trendingStore{
+		votes:   testutil.Votes("a", time.Now().Add(-time.Minute), 5, 4, 5),
+		release: make(chan struct{}),
+	}
+	cfg := config.Trending{Window: time.Hour, BaselineWindow: 24 * time.Hour, MinVotes: 1, ScoreWeight: 1, CacheTTL: time.Minute}
+	trendingService := NewTrendingService(store, cache.NewMemoryBackend(), cfg, metrics.New())
+
+	// Concurrent cache misses wait for one ranking, a request giving up doesn't cancel it for the others
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := trendingService.GetTrending(canceled, 10); err != context.Canceled {
+		t.Fatalf("GetTrending() with a canceled context error = %v, want %v", err, context.Canceled)
+	}
+
+	var wg sync.WaitGroup
+	responses := make([]models.GetTrendingResponse, 5)
+	errs := make([]error, len(responses))
+	for i := range responses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i], errs[i] = trendingService.GetTrending(context.Background(), 10)
+		}()
+	}
+	close(store.release)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil || len(responses[i].Products) != 1 || responses[i].Products[0].ProductID != "a" {
+			t.Errorf("GetTrending() = %+v, %v, want product a", responses[i], err)
+		}
+	}
+	if ranked := store.ranked.Load(); ranked != 1 {
+		t.Errorf("rankings computed = %d, want 1", ranked)
+	}
+
+	// Refresh replaces the cached ranking, which requests are served from
+	if err := trendingService.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if _, err := trendingService.GetTrending(context.Background(), 10); err != nil {
+		t.Fatalf("GetTrending() error = %v", err)
+	}
+	if ranked := store.ranked.Load(); ranked != 2 {
+		t.Errorf("rankings computed = %d, want 2", ranked)
+	}
+}
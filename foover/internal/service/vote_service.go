@@ -2,33 +2,517 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"foover/internal/config"
+	"foover/internal/fraud"
+	"foover/internal/importer"
+	"foover/internal/metrics"
 	"foover/internal/models"
 	"foover/internal/store/mongo"
+	"foover/internal/tracing"
+	"foover/internal/validation"
+	"foover/internal/votebuffer"
+	"io"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	// ErrInvalidImportFormat is returned when an import is requested in an unsupported format
+	ErrInvalidImportFormat = errors.New("invalid import format")
+	// ErrUnreadableImport wraps errors reading an import, rows before the error are imported
+	ErrUnreadableImport = errors.New("unreadable import")
+)
+
+const (
+	// importBatchSize is the number of votes written per bulk write
+	importBatchSize = 1000
+	// maxReportedImportErrors bounds the rejected rows listed in an import report
+	maxReportedImportErrors = 1000
+	// maxImportClockSkew tolerates imported timestamps slightly ahead of the server clock
+	maxImportClockSkew = time.Minute
 )
 
 // voteService implements the VoteService interface
 type voteService struct {
-	store mongo.Store
+	store    mongo.Store
+	detector fraud.Detector
+	buffer   votebuffer.Buffer // nil if votes aren't buffered
+	outbox   bool              // whether saved votes are written to the outbox
+	metrics  *metrics.Metrics
 }
 
 type VoteService interface {
 	SaveVote(ctx context.Context, vote models.Vote) error
 	GetVotesBySessionID(ctx context.Context, sessionID string) ([]models.Vote, error)
+	GetFlaggedVotes(ctx context.Context, limit int64) ([]models.Vote, error)
+	ApproveVote(ctx context.Context, id string) (models.Vote, error)
+	RejectVote(ctx context.Context, id string) (models.Vote, error)
+	ImportVotes(ctx context.Context, format string, r io.Reader, dryRun bool) (models.ImportVotesResponse, error)
+	SaveVotes(ctx context.Context, votes []models.Vote, atomic bool) (map[int]string, error)
+	ReplayBufferedVotes(ctx context.Context) (int, error)
+	BuffersVotes() bool
 }
 
-// NewVoteService creates a new VoteService
-func NewVoteService(store mongo.Store) VoteService {
+// NewVoteService creates a new VoteService, votes that can't be stored are written to buffer unless it is nil
+func NewVoteService(store mongo.Store, detector fraud.Detector, buffer votebuffer.Buffer, outboxCfg config.Outbox, m *metrics.Metrics) VoteService {
 	return &voteService{
-		store: store,
+		store:    store,
+		detector: detector,
+		buffer:   buffer,
+		outbox:   outboxCfg.Enabled,
+		metrics:  m,
+	}
+}
+
+// SaveVote assesses a vote and stores or updates it for a given session ID and product ID,
+// suspicious votes are stored as flagged and left out of the aggregated scores until approved.
+// With the outbox enabled, a vote.saved event is written in the transaction storing the vote.
+// If the store fails and a buffer is configured, the vote is buffered and replayed into the store later, along with its event.
+func (v *voteService) SaveVote(ctx context.Context, vote models.Vote) (err error) {
+	ctx, span := tracer.Start(ctx, "VoteService.SaveVote")
+	defer func() { tracing.End(span, err) }()
+
+	castAt := time.Now()
+
+	vote.Fingerprint = fraud.Fingerprint(vote.ClientIP, vote.UserAgent)
+	vote.Status = models.VoteStatusApproved
+
+	// Fail open, losing votes to a detector outage is worse than counting a few suspicious ones
+	assessment, assessErr := v.detector.Assess(ctx, vote)
+	if assessErr != nil {
+		span.RecordError(assessErr)
+	} else if assessment.Flagged {
+		vote.Status = models.VoteStatusFlagged
+	}
+	vote.FraudScore = assessment.Score
+	vote.FraudReasons = assessment.Reasons
+	span.SetAttributes(
+		attribute.String("vote.status", vote.Status),
+		attribute.Float64("vote.fraud_score", vote.FraudScore),
+	)
+
+	if err := v.saveVote(ctx, vote, castAt); err != nil {
+		if v.buffer == nil {
+			return err
+		}
+
+		// The buffered vote keeps the time it was cast, so votes stored after the outage win over it
+		vote.UpdatedAt = castAt
+		if bufferErr := v.buffer.Append(vote); bufferErr != nil {
+			return errors.Join(err, bufferErr)
+		}
+		span.RecordError(err)
+		span.SetAttributes(attribute.Bool("vote.buffered", true))
+		v.metrics.AddVoteBufferVotes(metrics.VoteBufferBuffered, 1)
+		v.metrics.SetVoteBufferDepth(v.buffer.Depth())
+		return nil
+	}
+
+	v.metrics.IncVotesSaved(vote.ProductID)
+	if vote.Status == models.VoteStatusFlagged {
+		v.metrics.IncVotesFlagged(vote.FraudReasons)
+	}
+	return nil
+}
+
+// saveVote stores the vote, along with its event if the outbox is enabled
+func (v *voteService) saveVote(ctx context.Context, vote models.Vote, castAt time.Time) error {
+	if !v.outbox {
+		return v.store.SaveVote(ctx, vote)
+	}
+
+	vote.UpdatedAt = castAt
+	event, err := newVoteEvent(models.EventTypeVoteSaved, vote)
+	if err != nil {
+		return err
+	}
+
+	return v.store.SaveVoteWithEvent(ctx, vote, event)
+}
+
+// newVoteEvent creates the outbox event of the given type describing a vote as of its UpdatedAt
+func newVoteEvent(eventType string, vote models.Vote) (models.OutboxEvent, error) {
+	payload, err := json.Marshal(models.VoteEvent{
+		SessionID: vote.SessionID,
+		ProductID: vote.ProductID,
+		Score:     vote.Score,
+		Status:    vote.Status,
+		UpdatedAt: vote.UpdatedAt,
+	})
+	if err != nil {
+		return models.OutboxEvent{}, err
+	}
+
+	return models.OutboxEvent{
+		Type:      eventType,
+		Key:       vote.ProductID,
+		SessionID: vote.SessionID,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// newVoteEvents creates the vote.saved events of a batch of votes, the event at index i describing votes[i]
+func newVoteEvents(votes []models.Vote) ([]models.OutboxEvent, error) {
+	events := make([]models.OutboxEvent, 0, len(votes))
+	for _, vote := range votes {
+		event, err := newVoteEvent(models.EventTypeVoteSaved, vote)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// BuffersVotes reports whether votes that can't be stored are buffered
+func (v *voteService) BuffersVotes() bool {
+	return v.buffer != nil
+}
+
+// ReplayBufferedVotes drains the vote buffer into the store and returns the number of replayed votes.
+// Votes may be buffered without validating their session and product while the store is down, so votes of
+// unknown sessions or votable products are dropped, as are votes the store rejects individually.
+// With the outbox enabled, each batch writes the vote.saved events of its votes in the transaction storing them.
+// A store error stops the replay until the next run.
+func (v *voteService) ReplayBufferedVotes(ctx context.Context) (replayed int, err error) {
+	if v.buffer == nil {
+		return 0, nil
+	}
+
+	ctx, span := tracer.Start(ctx, "VoteService.ReplayBufferedVotes")
+	defer func() { tracing.End(span, err) }()
+
+	replayed, err = v.buffer.Replay(ctx, func(ctx context.Context, votes []models.Vote) error {
+		votes, unknown, err := v.dropUnknownVotes(ctx, votes)
+		if err != nil {
+			return err
+		}
+		v.metrics.AddVoteBufferVotes(metrics.VoteBufferDropped, unknown)
+		if len(votes) == 0 {
+			return nil
+		}
+
+		failed, err := v.replayVotes(ctx, votes)
+		if err != nil {
+			return err
+		}
+
+		for i, vote := range votes {
+			if _, ok := failed[i]; ok {
+				continue
+			}
+			v.metrics.IncVotesSaved(vote.ProductID)
+			if vote.Status == models.VoteStatusFlagged {
+				v.metrics.IncVotesFlagged(vote.FraudReasons)
+			}
+		}
+		v.metrics.AddVoteBufferVotes(metrics.VoteBufferReplayed, len(votes)-len(failed))
+		v.metrics.AddVoteBufferVotes(metrics.VoteBufferDropped, len(failed))
+		return nil
+	})
+	span.SetAttributes(attribute.Int("votes.replayed", replayed))
+	v.metrics.SetVoteBufferDepth(v.buffer.Depth())
+
+	return replayed, err
+}
+
+// replayVotes stores buffered votes, along with their events if the outbox is enabled
+func (v *voteService) replayVotes(ctx context.Context, votes []models.Vote) (map[int]string, error) {
+	if !v.outbox {
+		return v.store.ReplayVotes(ctx, votes)
+	}
+
+	events, err := newVoteEvents(votes)
+	if err != nil {
+		return nil, err
+	}
+	return v.store.ReplayVotesWithEvents(ctx, votes, events)
+}
+
+// dropUnknownVotes removes votes of unknown sessions or votable products and returns the remaining votes and the number of removed ones
+func (v *voteService) dropUnknownVotes(ctx context.Context, votes []models.Vote) ([]models.Vote, int, error) {
+	sessionIDs := make([]string, 0, len(votes))
+	productIDs := make([]string, 0, len(votes))
+	for _, vote := range votes {
+		sessionIDs = append(sessionIDs, vote.SessionID)
+		productIDs = append(productIDs, vote.ProductID)
+	}
+
+	existing, err := v.store.GetExistingSessionIDs(ctx, sessionIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	valid, err := v.store.GetValidProductIDs(ctx, productIDs)
+	if err != nil {
+		return nil, 0, err
 	}
+
+	sessions := make(map[string]bool, len(existing))
+	for _, sessionID := range existing {
+		sessions[sessionID] = true
+	}
+	products := make(map[string]bool, len(valid))
+	for _, productID := range valid {
+		products[productID] = true
+	}
+
+	known := make([]models.Vote, 0, len(votes))
+	for _, vote := range votes {
+		if sessions[vote.SessionID] && products[vote.ProductID] {
+			known = append(known, vote)
+		}
+	}
+	return known, len(votes) - len(known), nil
 }
 
-// SaveVote stores or updates a vote for a given session ID and product ID
-func (v *voteService) SaveVote(ctx context.Context, vote models.Vote) error {
-	return v.store.SaveVote(ctx, vote)
+// SaveVotes assesses and stores votes cast together by one session with a single bulk write.
+// It returns the messages of the votes that failed, keyed by their index, an atomic batch stores all votes or returns an error.
+// If the store fails and a buffer is configured, the votes of a batch that isn't atomic are buffered like single votes,
+// atomic batches aren't since buffered votes are replayed individually.
+// With the outbox enabled, the batch writes the vote.saved events of its votes in the transaction storing them.
+func (v *voteService) SaveVotes(ctx context.Context, votes []models.Vote, atomic bool) (failed map[int]string, err error) {
+	ctx, span := tracer.Start(ctx, "VoteService.SaveVotes")
+	defer func() { tracing.End(span, err) }()
+	span.SetAttributes(attribute.Int("votes.count", len(votes)), attribute.Bool("votes.atomic", atomic))
+
+	castAt := time.Now()
+
+	for i := range votes {
+		votes[i].Fingerprint = fraud.Fingerprint(votes[i].ClientIP, votes[i].UserAgent)
+		votes[i].Status = models.VoteStatusApproved
+		votes[i].UpdatedAt = castAt
+	}
+
+	// Fail open like single votes
+	assessments, assessErr := v.detector.AssessBatch(ctx, votes)
+	if assessErr != nil {
+		span.RecordError(assessErr)
+	} else {
+		for i, assessment := range assessments {
+			if assessment.Flagged {
+				votes[i].Status = models.VoteStatusFlagged
+			}
+			votes[i].FraudScore = assessment.Score
+			votes[i].FraudReasons = assessment.Reasons
+		}
+	}
+
+	failed, err = v.saveVotes(ctx, votes, atomic)
+	if err != nil {
+		if v.buffer == nil || atomic {
+			return nil, err
+		}
+
+		// The buffered votes keep the time they were cast, so votes stored after the outage win over them
+		for i := range votes {
+			if bufferErr := v.buffer.Append(votes[i]); bufferErr != nil {
+				v.metrics.AddVoteBufferVotes(metrics.VoteBufferBuffered, i)
+				v.metrics.SetVoteBufferDepth(v.buffer.Depth())
+				return nil, errors.Join(err, bufferErr)
+			}
+		}
+		span.RecordError(err)
+		span.SetAttributes(attribute.Bool("vote.buffered", true))
+		v.metrics.AddVoteBufferVotes(metrics.VoteBufferBuffered, len(votes))
+		v.metrics.SetVoteBufferDepth(v.buffer.Depth())
+		return map[int]string{}, nil
+	}
+
+	for i, vote := range votes {
+		if _, ok := failed[i]; ok {
+			continue
+		}
+		v.metrics.IncVotesSaved(vote.ProductID)
+		if vote.Status == models.VoteStatusFlagged {
+			v.metrics.IncVotesFlagged(vote.FraudReasons)
+		}
+	}
+	return failed, nil
+}
+
+// saveVotes stores a batch of votes, along with their events if the outbox is enabled
+func (v *voteService) saveVotes(ctx context.Context, votes []models.Vote, atomic bool) (map[int]string, error) {
+	if !v.outbox {
+		return v.store.SaveVotes(ctx, votes, atomic)
+	}
+
+	events, err := newVoteEvents(votes)
+	if err != nil {
+		return nil, err
+	}
+	return v.store.SaveVotesWithEvents(ctx, votes, events, atomic)
 }
 
 // GetVotesBySessionID retrieves all votes associated with a session ID
 func (v *voteService) GetVotesBySessionID(ctx context.Context, sessionID string) ([]models.Vote, error) {
-	return v.store.GetVotesBySessionID(ctx, sessionID)
+	ctx, span := tracer.Start(ctx, "VoteService.GetVotesBySessionID")
+	votes, err := v.store.GetVotesBySessionID(ctx, sessionID)
+	tracing.End(span, err)
+	return votes, err
+}
+
+// GetFlaggedVotes retrieves up to limit votes waiting for a review, oldest first
+func (v *voteService) GetFlaggedVotes(ctx context.Context, limit int64) ([]models.Vote, error) {
+	ctx, span := tracer.Start(ctx, "VoteService.GetFlaggedVotes")
+	votes, err := v.store.GetVotesByStatus(ctx, models.VoteStatusFlagged, limit)
+	tracing.End(span, err)
+	return votes, err
+}
+
+// ApproveVote counts a flagged vote in the aggregated scores
+func (v *voteService) ApproveVote(ctx context.Context, id string) (models.Vote, error) {
+	ctx, span := tracer.Start(ctx, "VoteService.ApproveVote")
+	vote, err := v.reviewVote(ctx, id, models.VoteStatusApproved)
+	tracing.End(span, err)
+	return vote, err
+}
+
+// RejectVote keeps a flagged vote out of the aggregated scores for good
+func (v *voteService) RejectVote(ctx context.Context, id string) (models.Vote, error) {
+	ctx, span := tracer.Start(ctx, "VoteService.RejectVote")
+	vote, err := v.reviewVote(ctx, id, models.VoteStatusRejected)
+	tracing.End(span, err)
+	return vote, err
+}
+
+// reviewVote records the review of a flagged vote, along with a vote.reviewed event if the outbox is enabled
+func (v *voteService) reviewVote(ctx context.Context, id string, status string) (vote models.Vote, err error) {
+	if v.outbox {
+		vote, err = v.store.ReviewFlaggedVoteWithEvent(ctx, id, status, func(vote models.Vote) (models.OutboxEvent, error) {
+			return newVoteEvent(models.EventTypeVoteReviewed, vote)
+		})
+	} else {
+		vote, err = v.store.ReviewFlaggedVote(ctx, id, status)
+	}
+	if err == nil {
+		v.metrics.IncVotesReviewed(status)
+	}
+	return vote, err
+}
+
+// ImportVotes validates votes read from r in the given format with the rules of SaveVoteRequest and writes them in batches.
+// Invalid rows are reported and skipped, a dry run only validates them. Imported votes skip the fraud detection,
+// they come from trusted sources and their timestamps are in the past. With the outbox enabled, each batch writes
+// the vote.saved events of its votes in the transaction storing them.
+func (v *voteService) ImportVotes(ctx context.Context, format string, r io.Reader, dryRun bool) (response models.ImportVotesResponse, err error) {
+	ctx, span := tracer.Start(ctx, "VoteService.ImportVotes")
+	defer func() { tracing.End(span, err) }()
+
+	if !importer.IsValidFormat(format) {
+		return models.ImportVotesResponse{}, ErrInvalidImportFormat
+	}
+	reader, err := importer.NewVoteReader(format, r)
+	if err != nil {
+		return models.ImportVotesResponse{}, fmt.Errorf("%w, %w", ErrUnreadableImport, err)
+	}
+
+	response = models.ImportVotesResponse{DryRun: dryRun, Errors: []models.ImportRowError{}}
+	reject := func(row int, message string) {
+		response.Rejected++
+		if len(response.Errors) < maxReportedImportErrors {
+			response.Errors = append(response.Errors, models.ImportRowError{Row: row, Message: message})
+		}
+	}
+
+	var (
+		batch     []models.Vote
+		batchRows []int
+	)
+	flush := func() error {
+		defer func() { batch, batchRows = batch[:0], batchRows[:0] }()
+		if dryRun || len(batch) == 0 {
+			response.Imported += len(batch)
+			return nil
+		}
+		failed, err := v.importVotes(ctx, batch)
+		if err != nil {
+			return err
+		}
+		response.Imported += len(batch) - len(failed)
+		for i, message := range failed {
+			reject(batchRows[i], message)
+		}
+		return nil
+	}
+
+	validProducts := make(map[string]bool)
+	latest := time.Now().Add(maxImportClockSkew)
+	for {
+		rec, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return response, fmt.Errorf("%w, %w", ErrUnreadableImport, errors.Join(err, flush()))
+		}
+		if rec.Err != nil {
+			reject(rec.Row, rec.Err.Error())
+			continue
+		}
+		if err := validation.Struct(rec.Vote); err != nil {
+			reject(rec.Row, err.Error())
+			continue
+		}
+		if rec.Timestamp.After(latest) {
+			reject(rec.Row, "timestamp must not be in the future")
+			continue
+		}
+
+		valid, checked := validProducts[rec.Vote.ProductID]
+		if !checked {
+			if valid, err = v.store.IsValidProductID(ctx, rec.Vote.ProductID); err != nil {
+				return response, err
+			}
+			validProducts[rec.Vote.ProductID] = valid
+		}
+		if !valid {
+			reject(rec.Row, fmt.Sprintf("unknown product %s", rec.Vote.ProductID))
+			continue
+		}
+
+		batch = append(batch, models.Vote{
+			SessionID: rec.Vote.SessionID,
+			ProductID: rec.Vote.ProductID,
+			Score:     rec.Vote.Score,
+			UpdatedAt: rec.Timestamp,
+			Status:    models.VoteStatusApproved,
+		})
+		batchRows = append(batchRows, rec.Row)
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return response, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return response, err
+	}
+	// Rows failing to be written are reported after the rows failing validation
+	slices.SortFunc(response.Errors, func(a, b models.ImportRowError) int { return a.Row - b.Row })
+
+	span.SetAttributes(
+		attribute.Bool("import.dry_run", dryRun),
+		attribute.Int("import.imported", response.Imported),
+		attribute.Int("import.rejected", response.Rejected),
+	)
+	return response, nil
+}
+
+// importVotes writes a batch of imported votes, along with their events if the outbox is enabled
+func (v *voteService) importVotes(ctx context.Context, votes []models.Vote) (map[int]string, error) {
+	if !v.outbox {
+		return v.store.ImportVotes(ctx, votes)
+	}
+
+	events, err := newVoteEvents(votes)
+	if err != nil {
+		return nil, err
+	}
+	return v.store.ImportVotesWithEvents(ctx, votes, events)
 }
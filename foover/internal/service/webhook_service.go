@@ -0,0 +1,452 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"foover/internal/config"
+	"foover/internal/events"
+	"foover/internal/metrics"
+	"foover/internal/models"
+	"foover/internal/store/mongo"
+	"foover/internal/tracing"
+	"foover/internal/webhook"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrInvalidWebhookEvent is returned when a webhook subscribes to an unknown event type
+var ErrInvalidWebhookEvent = errors.New("invalid webhook event")
+
+type WebhookService interface {
+	CreateWebhook(ctx context.Context, req models.CreateWebhookRequest) (string, models.Webhook, error)
+	GetWebhooks(ctx context.Context) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, id string) (models.Webhook, error)
+	UpdateWebhook(ctx context.Context, id string, req models.UpdateWebhookRequest) (models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	GetDeliveries(ctx context.Context, id string, status string, limit int64) ([]models.WebhookDelivery, error)
+	GetDeadLetters(ctx context.Context, limit int64) ([]models.WebhookDelivery, error)
+	Redeliver(ctx context.Context, deliveryID string) (models.WebhookDelivery, error)
+	Emit(ctx context.Context, eventType string, productID string, data interface{}) error
+	EmitOutboxEvent(ctx context.Context, event models.OutboxEvent) error
+	CheckScores(ctx context.Context) error
+	Deliver(ctx context.Context) (int, error)
+}
+
+// webhookService implements the WebhookService interface
+type webhookService struct {
+	store   mongo.Store
+	client  webhook.Client
+	cfg     config.Webhook
+	metrics *metrics.Metrics
+}
+
+// NewWebhookService creates a new WebhookService sending deliveries with client
+func NewWebhookService(store mongo.Store, client webhook.Client, cfg config.Webhook, m *metrics.Metrics) WebhookService {
+	return &webhookService{
+		store:   store,
+		client:  client,
+		cfg:     cfg,
+		metrics: m,
+	}
+}
+
+// CreateWebhook subscribes a URL to events and returns the secret signing its payloads with the stored webhook.
+// The secret is stored to sign payloads, but only returned here.
+func (w *webhookService) CreateWebhook(ctx context.Context, req models.CreateWebhookRequest) (_ string, _ models.Webhook, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.CreateWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	if err := validateWebhookEvents(req.Events); err != nil {
+		return "", models.Webhook{}, err
+	}
+
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		return "", models.Webhook{}, err
+	}
+
+	now := time.Now()
+	created, err := w.store.CreateWebhook(ctx, models.Webhook{
+		URL:        req.URL,
+		Events:     slices.Compact(slices.Sorted(slices.Values(req.Events))),
+		ProductIDs: req.ProductIDs,
+		Secret:     secret,
+		Active:     true,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	})
+	if err != nil {
+		return "", models.Webhook{}, err
+	}
+
+	return secret, created, nil
+}
+
+// GetWebhooks retrieves all webhooks
+func (w *webhookService) GetWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetWebhooks")
+	webhooks, err := w.store.GetWebhooks(ctx)
+	tracing.End(span, err)
+	return webhooks, err
+}
+
+// GetWebhook retrieves the webhook with the given ID
+func (w *webhookService) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetWebhook")
+	hook, err := w.store.GetWebhook(ctx, id)
+	tracing.End(span, err)
+	return hook, err
+}
+
+// UpdateWebhook changes the URL, events, products or state of a webhook, omitted fields are left unchanged
+func (w *webhookService) UpdateWebhook(ctx context.Context, id string, req models.UpdateWebhookRequest) (_ models.Webhook, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.UpdateWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	hook, err := w.store.GetWebhook(ctx, id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	if req.URL != nil {
+		hook.URL = *req.URL
+	}
+	if req.Events != nil {
+		if err := validateWebhookEvents(*req.Events); err != nil {
+			return models.Webhook{}, err
+		}
+		hook.Events = slices.Compact(slices.Sorted(slices.Values(*req.Events)))
+	}
+	if req.ProductIDs != nil {
+		hook.ProductIDs = *req.ProductIDs
+	}
+	if req.Active != nil {
+		hook.Active = *req.Active
+	}
+
+	return w.store.UpdateWebhook(ctx, hook)
+}
+
+// DeleteWebhook deletes a webhook along with its pending deliveries and delivery logs
+func (w *webhookService) DeleteWebhook(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "WebhookService.DeleteWebhook")
+	err := w.store.DeleteWebhook(ctx, id)
+	tracing.End(span, err)
+	return err
+}
+
+// GetDeliveries retrieves up to limit deliveries of a webhook with their attempts, newest first,
+// optionally only those with the given status
+func (w *webhookService) GetDeliveries(ctx context.Context, id string, status string, limit int64) (_ []models.WebhookDelivery, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetDeliveries")
+	defer func() { tracing.End(span, err) }()
+
+	// Tell a webhook without deliveries from a missing webhook
+	if _, err := w.store.GetWebhook(ctx, id); err != nil {
+		return nil, err
+	}
+	return w.store.GetWebhookDeliveries(ctx, id, status, limit)
+}
+
+// GetDeadLetters retrieves up to limit deliveries of any webhook that ran out of attempts, newest first
+func (w *webhookService) GetDeadLetters(ctx context.Context, limit int64) ([]models.WebhookDelivery, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetDeadLetters")
+	deliveries, err := w.store.GetWebhookDeliveries(ctx, "", models.WebhookDeliveryDead, limit)
+	tracing.End(span, err)
+	return deliveries, err
+}
+
+// Redeliver schedules a dead letter for a new round of attempts, ErrNotFound is returned for other deliveries
+func (w *webhookService) Redeliver(ctx context.Context, deliveryID string) (models.WebhookDelivery, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.Redeliver")
+	delivery, err := w.store.RedeliverWebhookDelivery(ctx, deliveryID)
+	tracing.End(span, err)
+	return delivery, err
+}
+
+// Emit queues an event for delivery to the active webhooks subscribed to its type and product.
+// All deliveries of an event carry the same event ID, so receivers can deduplicate retried deliveries.
+func (w *webhookService) Emit(ctx context.Context, eventType string, productID string, data interface{}) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.Emit")
+	defer func() { tracing.End(span, err) }()
+
+	return w.emit(ctx, uuid.New().String(), eventType, productID, "", data)
+}
+
+// EmitOutboxEvent queues the webhook event of a vote event relayed from the outbox. Saved votes are sent as vote.saved
+// unless they are flagged or rejected, flagged votes are sent once they are approved. Other event types are ignored.
+// The outbox event ID is the webhook event ID, so an event relayed again doesn't queue its deliveries twice.
+func (w *webhookService) EmitOutboxEvent(ctx context.Context, event models.OutboxEvent) (err error) {
+	if event.Type != models.EventTypeVoteSaved && event.Type != models.EventTypeVoteReviewed {
+		return nil
+	}
+
+	ctx, span := tracer.Start(ctx, "WebhookService.EmitOutboxEvent")
+	defer func() { tracing.End(span, err) }()
+
+	var vote models.VoteEvent
+	if err := json.Unmarshal(event.Payload, &vote); err != nil {
+		return err
+	}
+	span.SetAttributes(attribute.String("vote.status", vote.Status))
+	if vote.Status == models.VoteStatusFlagged || vote.Status == models.VoteStatusRejected {
+		return nil
+	}
+
+	// The session is kept on the deliveries so erasing it removes them, but not sent to receivers
+	return w.emit(ctx, event.ID.Hex(), models.WebhookEventVoteSaved, vote.ProductID, vote.SessionID, models.WebhookVote{
+		ProductID: vote.ProductID,
+		Score:     vote.Score,
+		UpdatedAt: vote.UpdatedAt,
+	})
+}
+
+// emit queues the deliveries of an event with the given ID, linking them to sessionID unless it is empty
+func (w *webhookService) emit(ctx context.Context, eventID string, eventType string, productID string, sessionID string, data interface{}) error {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("webhook.event", eventType))
+
+	hooks, err := w.store.GetWebhooksByEvent(ctx, eventType)
+	if err != nil {
+		return err
+	}
+	hooks = slices.DeleteFunc(hooks, func(hook models.Webhook) bool {
+		return len(hook.ProductIDs) > 0 && !slices.Contains(hook.ProductIDs, productID)
+	})
+	span.SetAttributes(attribute.Int("webhook.subscribers", len(hooks)))
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	payload, err := json.Marshal(models.WebhookPayload{
+		ID:        eventID,
+		Type:      eventType,
+		CreatedAt: now,
+		Data:      encoded,
+	})
+	if err != nil {
+		return err
+	}
+
+	deliveries := make([]models.WebhookDelivery, 0, len(hooks))
+	for _, hook := range hooks {
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID:     hook.ID,
+			EventID:       eventID,
+			EventType:     eventType,
+			ProductID:     productID,
+			SessionID:     sessionID,
+			Payload:       payload,
+			Status:        models.WebhookDeliveryPending,
+			Attempts:      []models.WebhookAttempt{},
+			NextAttemptAt: &now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		})
+	}
+	if err := w.store.CreateWebhookDeliveries(ctx, deliveries); err != nil {
+		return err
+	}
+
+	w.metrics.IncWebhookEvents(eventType)
+	return nil
+}
+
+// CheckScores checks the aggregated scores of all products and raises or clears their score alerts
+func (w *webhookService) CheckScores(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.CheckScores")
+	defer func() { tracing.End(span, err) }()
+
+	scores, err := w.store.GetAggregatedProductScores(ctx)
+	if err != nil {
+		return err
+	}
+	span.SetAttributes(attribute.Int("webhook.products", len(scores)))
+
+	for _, score := range scores {
+		if err := w.checkScore(ctx, score); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkScore updates the alerts of a product from its aggregated score
+func (w *webhookService) checkScore(ctx context.Context, score models.ProductScore) error {
+	alerts := []struct {
+		event     string
+		raised    bool
+		threshold float64
+	}{
+		{
+			event:     models.WebhookEventScoreBelowThreshold,
+			raised:    score.VoteCount >= w.cfg.ScoreMinVotes && score.AvgScore < w.cfg.ScoreThreshold,
+			threshold: w.cfg.ScoreThreshold,
+		},
+		{
+			event:     models.WebhookEventVoteCountReached,
+			raised:    score.VoteCount >= w.cfg.VoteCountThreshold,
+			threshold: float64(w.cfg.VoteCountThreshold),
+		},
+	}
+
+	for _, alert := range alerts {
+		changed, err := w.store.SetProductAlert(ctx, score.ProductID, alert.event, alert.raised)
+		if err != nil {
+			return err
+		}
+		if !changed || !alert.raised {
+			continue
+		}
+
+		err = w.Emit(ctx, alert.event, score.ProductID, models.ScoreAlert{
+			ProductID: score.ProductID,
+			AvgScore:  score.AvgScore,
+			VoteCount: score.VoteCount,
+			Threshold: alert.threshold,
+		})
+		if err != nil {
+			// Clear the alert so the next check raises it again instead of losing the event
+			_, clearErr := w.store.SetProductAlert(ctx, score.ProductID, alert.event, false)
+			return errors.Join(err, clearErr)
+		}
+	}
+	return nil
+}
+
+// Deliver sends up to a batch of due deliveries and returns the number of successful ones.
+// Failed deliveries are retried with exponential backoff and become dead letters after the maximum attempts,
+// deliveries of deleted or inactive webhooks become dead letters right away.
+func (w *webhookService) Deliver(ctx context.Context) (delivered int, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.Deliver")
+	defer func() { tracing.End(span, err) }()
+
+	hooks := make(map[string]models.Webhook)
+	for range w.cfg.DeliveryBatchSize {
+		// A claim outlives the attempt, so the delivery is retried if this replica dies while sending it
+		delivery, err := w.store.ClaimWebhookDelivery(ctx, time.Now().Add(2*w.cfg.Timeout))
+		if errors.Is(err, mongo.ErrNotFound) {
+			break
+		}
+		if err != nil {
+			return delivered, err
+		}
+
+		hookID := delivery.WebhookID.Hex()
+		hook, ok := hooks[hookID]
+		if !ok {
+			hook, err = w.store.GetWebhook(ctx, hookID)
+			if err != nil && !errors.Is(err, mongo.ErrNotFound) {
+				return delivered, err
+			}
+			hooks[hookID] = hook
+		}
+
+		ok, err = w.attempt(ctx, hook, delivery)
+		if err != nil {
+			return delivered, err
+		}
+		if ok {
+			delivered++
+		}
+	}
+
+	span.SetAttributes(attribute.Int("webhook.delivered", delivered))
+	return delivered, nil
+}
+
+// attempt sends a delivery and records the attempt, it reports whether the delivery succeeded
+func (w *webhookService) attempt(ctx context.Context, hook models.Webhook, delivery models.WebhookDelivery) (bool, error) {
+	start := time.Now()
+	var (
+		statusCode int
+		sendErr    error
+	)
+	switch {
+	case hook.ID.IsZero():
+		sendErr = errors.New("webhook deleted")
+	case !hook.Active:
+		sendErr = errors.New("webhook inactive")
+	default:
+		statusCode, sendErr = w.client.Send(ctx, hook.URL, hook.Secret, delivery)
+	}
+
+	attempt := models.WebhookAttempt{
+		At:         start,
+		StatusCode: statusCode,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	now := time.Now()
+	expiresAt := now.Add(w.cfg.DeliveryRetention)
+	delivery.NextAttemptAt = nil
+	delivery.ExpiresAt = &expiresAt
+
+	// Attempts before a redelivery don't count towards the maximum
+	attempts := delivery.FailedAttempts + 1
+
+	switch {
+	case sendErr == nil:
+		delivery.Status = models.WebhookDeliverySucceeded
+		w.metrics.IncWebhookDeliveries(metrics.WebhookDeliverySucceeded)
+	case hook.ID.IsZero() || !hook.Active || attempts >= w.cfg.MaxAttempts:
+		attempt.Error = sendErr.Error()
+		delivery.FailedAttempts = attempts
+		delivery.Status = models.WebhookDeliveryDead
+		w.metrics.IncWebhookDeliveries(metrics.WebhookDeliveryDead)
+	default:
+		attempt.Error = sendErr.Error()
+		delivery.FailedAttempts = attempts
+		next := now.Add(webhook.Backoff(attempts, w.cfg.BackoffBase, w.cfg.BackoffMax))
+		delivery.Status = models.WebhookDeliveryPending
+		delivery.NextAttemptAt = &next
+		delivery.ExpiresAt = nil
+		w.metrics.IncWebhookDeliveries(metrics.WebhookDeliveryRetried)
+	}
+
+	if err := w.store.RecordWebhookAttempt(ctx, delivery, attempt); err != nil && !errors.Is(err, mongo.ErrNotFound) {
+		return false, fmt.Errorf("recording webhook attempt failed, %w", err)
+	}
+	return sendErr == nil, nil
+}
+
+// validateWebhookEvents checks that events only lists known event types
+func validateWebhookEvents(events []string) error {
+	for _, event := range events {
+		if !slices.Contains(models.WebhookEvents, event) {
+			return fmt.Errorf("%w: %s", ErrInvalidWebhookEvent, event)
+		}
+	}
+	return nil
+}
+
+// webhookEventPublisher implements the events.EventPublisher interface by queueing the webhook deliveries of outbox events
+type webhookEventPublisher struct {
+	webhooks WebhookService
+}
+
+// NewWebhookEventPublisher creates a new EventPublisher the outbox relay emits vote webhooks through,
+// so a vote.saved event is queued once the vote is committed and retried until it is
+func NewWebhookEventPublisher(webhooks WebhookService) events.EventPublisher {
+	return &webhookEventPublisher{webhooks: webhooks}
+}
+
+// Publish queues the webhook deliveries of an event
+func (p *webhookEventPublisher) Publish(ctx context.Context, event models.OutboxEvent) error {
+	return p.webhooks.EmitOutboxEvent(ctx, event)
+}
+
+// Close does nothing, deliveries are sent by the webhook delivery worker
+func (p *webhookEventPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,90 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"foover/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RecordAnomaly stores an anomaly unless one of the same product and direction was detected since cooldownStart,
+// it returns the anomaly with its generated ID and reports whether it was stored. The check isn't atomic, detection runs on a single replica.
+func (s *store) RecordAnomaly(ctx context.Context, anomaly models.Anomaly, cooldownStart time.Time) (models.Anomaly, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	collection := s.db.Collection("anomalies")
+	filter := bson.M{
+		"product_id":  anomaly.ProductID,
+		"direction":   anomaly.Direction,
+		"detected_at": bson.M{"$gte": cooldownStart},
+	}
+	err := collection.FindOne(ctx, filter, options.FindOne().SetProjection(bson.M{"_id": 1})).Err()
+	if err == nil {
+		return models.Anomaly{}, false, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Anomaly{}, false, err
+	}
+
+	result, err := collection.InsertOne(ctx, anomaly)
+	if err != nil {
+		return models.Anomaly{}, false, err
+	}
+
+	anomaly.ID = result.InsertedID.(primitive.ObjectID)
+	return anomaly, true, nil
+}
+
+// DeleteAnomaly deletes the anomaly with the given ID
+func (s *store) DeleteAnomaly(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	result, err := s.db.Collection("anomalies").DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetAnomalies retrieves up to limit anomalies detected since the given time, newest first,
+// of a product unless productID is empty and in a direction unless direction is empty
+func (s *store) GetAnomalies(ctx context.Context, productID string, direction string, since time.Time, limit int64) ([]models.Anomaly, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	filter := bson.M{"detected_at": bson.M{"$gte": since}}
+	if productID != "" {
+		filter["product_id"] = productID
+	}
+	if direction != "" {
+		filter["direction"] = direction
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "detected_at", Value: -1}}).
+		SetLimit(limit)
+
+	cursor, err := s.db.Collection("anomalies").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var anomalies []models.Anomaly
+	if err := cursor.All(ctx, &anomalies); err != nil {
+		return nil, err
+	}
+
+	return anomalies, nil
+}
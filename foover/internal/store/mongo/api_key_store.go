@@ -0,0 +1,97 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"foover/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateAPIKey stores a new API key and returns it with its generated ID
+func (s *store) CreateAPIKey(ctx context.Context, apiKey models.APIKey) (models.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	result, err := s.db.Collection("api_keys").InsertOne(ctx, apiKey)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	apiKey.ID = result.InsertedID.(primitive.ObjectID)
+	return apiKey, nil
+}
+
+// GetAPIKeyByHash retrieves the non-revoked API key with the given hash
+func (s *store) GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	filter := bson.M{
+		"hash":       hash,
+		"revoked_at": bson.M{"$exists": false},
+	}
+
+	var apiKey models.APIKey
+	err := s.db.Collection("api_keys").FindOne(ctx, filter).Decode(&apiKey)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.APIKey{}, ErrNotFound
+	}
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	return apiKey, nil
+}
+
+// GetAPIKeys retrieves all API keys, including revoked ones
+func (s *store) GetAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	cursor, err := s.db.Collection("api_keys").Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var apiKeys []models.APIKey
+	if err := cursor.All(ctx, &apiKeys); err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+// RevokeAPIKey marks the API key with the given ID as revoked
+func (s *store) RevokeAPIKey(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrNotFound
+	}
+
+	filter := bson.M{
+		"_id":        objectID,
+		"revoked_at": bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$set": bson.M{"revoked_at": time.Now()},
+	}
+
+	result, err := s.db.Collection("api_keys").UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
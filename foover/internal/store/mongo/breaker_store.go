@@ -0,0 +1,174 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"foover/internal/models"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// breakerStore decorates a Store with a circuit breaker on the operations of the vote path.
+// Once one of them found MongoDB unreachable, they fail fast with ErrUnavailable for the cooldown instead of each
+// waiting for its timeout, so a vote is buffered within the HTTP write timeout. Pings always reach MongoDB and
+// their outcome opens or closes the breaker, so readiness probes close it as soon as MongoDB answers again.
+type breakerStore struct {
+	Store
+	cooldown time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	openUntil time.Time
+}
+
+// NewBreakerStore wraps the given store with a circuit breaker failing the vote path fast for cooldown
+// after MongoDB was unreachable, a zero cooldown returns next as is
+func NewBreakerStore(next Store, cooldown time.Duration) Store {
+	if cooldown <= 0 {
+		return next
+	}
+	return &breakerStore{
+		Store:    next,
+		cooldown: cooldown,
+		now:      time.Now,
+	}
+}
+
+// allow returns ErrUnavailable while the breaker is open
+func (s *breakerStore) allow() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.now().Before(s.openUntil) {
+		return ErrUnavailable
+	}
+	return nil
+}
+
+// record opens the breaker if err shows MongoDB is unreachable and closes it if MongoDB answered,
+// other failures, e.g. a canceled request, leave it as it is
+func (s *breakerStore) record(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case mongo.IsTimeout(err) || mongo.IsNetworkError(err):
+		s.openUntil = s.now().Add(s.cooldown)
+	case err == nil || errors.Is(err, ErrNotFound) || errors.Is(err, ErrDuplicate):
+		s.openUntil = time.Time{}
+	}
+}
+
+func (s *breakerStore) Ping(ctx context.Context) error {
+	err := s.Store.Ping(ctx)
+	s.record(err)
+	return err
+}
+
+func (s *breakerStore) SessionExists(ctx context.Context, sessionID string) (bool, error) {
+	if err := s.allow(); err != nil {
+		return false, err
+	}
+	exists, err := s.Store.SessionExists(ctx, sessionID)
+	s.record(err)
+	return exists, err
+}
+
+func (s *breakerStore) GetExistingSessionIDs(ctx context.Context, sessionIDs []string) ([]string, error) {
+	if err := s.allow(); err != nil {
+		return nil, err
+	}
+	existing, err := s.Store.GetExistingSessionIDs(ctx, sessionIDs)
+	s.record(err)
+	return existing, err
+}
+
+func (s *breakerStore) IsValidProductID(ctx context.Context, productID string) (bool, error) {
+	if err := s.allow(); err != nil {
+		return false, err
+	}
+	valid, err := s.Store.IsValidProductID(ctx, productID)
+	s.record(err)
+	return valid, err
+}
+
+func (s *breakerStore) GetValidProductIDs(ctx context.Context, productIDs []string) ([]string, error) {
+	if err := s.allow(); err != nil {
+		return nil, err
+	}
+	valid, err := s.Store.GetValidProductIDs(ctx, productIDs)
+	s.record(err)
+	return valid, err
+}
+
+func (s *breakerStore) GetSession(ctx context.Context, sessionID string) (models.Session, error) {
+	if err := s.allow(); err != nil {
+		return models.Session{}, err
+	}
+	session, err := s.Store.GetSession(ctx, sessionID)
+	s.record(err)
+	return session, err
+}
+
+func (s *breakerStore) CountVotesBySessionSince(ctx context.Context, sessionID string, since time.Time) (int64, error) {
+	if err := s.allow(); err != nil {
+		return 0, err
+	}
+	count, err := s.Store.CountVotesBySessionSince(ctx, sessionID, since)
+	s.record(err)
+	return count, err
+}
+
+func (s *breakerStore) CountMatchingVotesSince(ctx context.Context, productID string, score int, since time.Time) (int64, error) {
+	if err := s.allow(); err != nil {
+		return 0, err
+	}
+	count, err := s.Store.CountMatchingVotesSince(ctx, productID, score, since)
+	s.record(err)
+	return count, err
+}
+
+func (s *breakerStore) CountSessionsByFingerprintSince(ctx context.Context, fingerprint string, since time.Time) (int64, error) {
+	if err := s.allow(); err != nil {
+		return 0, err
+	}
+	count, err := s.Store.CountSessionsByFingerprintSince(ctx, fingerprint, since)
+	s.record(err)
+	return count, err
+}
+
+func (s *breakerStore) SaveVote(ctx context.Context, vote models.Vote) error {
+	if err := s.allow(); err != nil {
+		return err
+	}
+	err := s.Store.SaveVote(ctx, vote)
+	s.record(err)
+	return err
+}
+
+func (s *breakerStore) SaveVoteWithEvent(ctx context.Context, vote models.Vote, event models.OutboxEvent) error {
+	if err := s.allow(); err != nil {
+		return err
+	}
+	err := s.Store.SaveVoteWithEvent(ctx, vote, event)
+	s.record(err)
+	return err
+}
+
+func (s *breakerStore) SaveVotes(ctx context.Context, votes []models.Vote, atomic bool) (map[int]string, error) {
+	if err := s.allow(); err != nil {
+		return nil, err
+	}
+	failed, err := s.Store.SaveVotes(ctx, votes, atomic)
+	s.record(err)
+	return failed, err
+}
+
+func (s *breakerStore) SaveVotesWithEvents(ctx context.Context, votes []models.Vote, events []models.OutboxEvent, atomic bool) (map[int]string, error) {
+	if err := s.allow(); err != nil {
+		return nil, err
+	}
+	failed, err := s.Store.SaveVotesWithEvents(ctx, votes, events, atomic)
+	s.record(err)
+	return failed, err
+}
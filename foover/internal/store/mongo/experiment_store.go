@@ -0,0 +1,144 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"foover/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateExperiment stores a new experiment and returns it with its generated ID.
+// It returns ErrDuplicate if an experiment with the same key exists.
+func (s *store) CreateExperiment(ctx context.Context, experiment models.Experiment) (models.Experiment, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	result, err := s.db.Collection("experiments").InsertOne(ctx, experiment)
+	if mongo.IsDuplicateKeyError(err) {
+		return models.Experiment{}, ErrDuplicate
+	}
+	if err != nil {
+		return models.Experiment{}, err
+	}
+
+	experiment.ID = result.InsertedID.(primitive.ObjectID)
+	return experiment, nil
+}
+
+// GetExperiments retrieves all experiments, newest first
+func (s *store) GetExperiments(ctx context.Context) ([]models.Experiment, error) {
+	return s.findExperiments(ctx, bson.M{})
+}
+
+// GetRunningExperiments retrieves the experiments assigning new sessions
+func (s *store) GetRunningExperiments(ctx context.Context) ([]models.Experiment, error) {
+	return s.findExperiments(ctx, bson.M{"status": models.ExperimentStatusRunning})
+}
+
+// findExperiments retrieves the experiments matching filter, newest first
+func (s *store) findExperiments(ctx context.Context, filter bson.M) ([]models.Experiment, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	cursor, err := s.db.Collection("experiments").Find(ctx, filter, options.Find().SetSort(bson.M{"started_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var experiments []models.Experiment
+	if err := cursor.All(ctx, &experiments); err != nil {
+		return nil, err
+	}
+
+	return experiments, nil
+}
+
+// GetExperiment retrieves the experiment with the given key
+func (s *store) GetExperiment(ctx context.Context, key string) (models.Experiment, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	var experiment models.Experiment
+	err := s.db.Collection("experiments").FindOne(ctx, bson.M{"key": key}).Decode(&experiment)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Experiment{}, ErrNotFound
+	}
+	if err != nil {
+		return models.Experiment{}, err
+	}
+
+	return experiment, nil
+}
+
+// StopExperiment stops a running experiment and returns it, an experiment already stopped is returned unchanged
+func (s *store) StopExperiment(ctx context.Context, key string, stoppedAt time.Time) (models.Experiment, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	filter := bson.M{"key": key, "status": models.ExperimentStatusRunning}
+	update := bson.M{"$set": bson.M{"status": models.ExperimentStatusStopped, "stopped_at": stoppedAt}}
+
+	var stopped models.Experiment
+	err := s.db.Collection("experiments").FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&stopped)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return s.GetExperiment(ctx, key)
+	}
+	if err != nil {
+		return models.Experiment{}, err
+	}
+
+	return stopped, nil
+}
+
+// StreamExperimentSessionScores calls fn with the counted votes on the products of the experiment every session
+// assigned to one of its variants cast while it ran, sessions without such votes included.
+// The stream is bounded by ctx only, like StreamVotes.
+func (s *store) StreamExperimentSessionScores(ctx context.Context, experiment models.Experiment, fn func(models.ExperimentSessionScore) error) error {
+	voteFilter := bson.M{
+		"$expr":      bson.M{"$eq": bson.A{"$session_id", "$$session_id"}},
+		"status":     bson.M{"$nin": bson.A{models.VoteStatusFlagged, models.VoteStatusRejected}},
+		"updated_at": bson.M{"$gte": experiment.StartedAt},
+	}
+	if experiment.StoppedAt != nil {
+		voteFilter["updated_at"] = bson.M{"$gte": experiment.StartedAt, "$lte": *experiment.StoppedAt}
+	}
+	if len(experiment.ProductIDs) > 0 {
+		voteFilter["product_id"] = bson.M{"$in": experiment.ProductIDs}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"variants.experiment": experiment.Key}}},
+		{{Key: "$unwind", Value: "$variants"}},
+		{{Key: "$match", Value: bson.M{"variants.experiment": experiment.Key}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": "votes",
+			"let":  bson.M{"session_id": "$session_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": voteFilter},
+				bson.M{"$group": bson.M{"_id": nil, "votes": bson.M{"$sum": 1}, "score_sum": bson.M{"$sum": "$score"}}},
+			},
+			"as": "scores",
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":       0,
+			"variant":   "$variants.variant",
+			"votes":     bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$scores.votes", 0}}, 0}},
+			"score_sum": bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$scores.score_sum", 0}}, 0}},
+		}}},
+	}
+
+	cursor, err := s.db.Collection("sessions").Aggregate(ctx, pipeline, options.Aggregate().SetBatchSize(streamBatchSize))
+	if err != nil {
+		return err
+	}
+
+	return stream(ctx, cursor, fn)
+}
@@ -0,0 +1,60 @@
+package mongo
+
+import (
+	"context"
+
+	"foover/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// streamBatchSize is the number of documents fetched per round trip while streaming
+const streamBatchSize = 1000
+
+// StreamVotes calls fn for every stored vote in insertion order, decoding one vote at a time,
+// so exports don't hold the collection in memory. Streaming stops at the first error returned by fn.
+// The stream is bounded by ctx only, exports of large collections outlast the read timeout.
+func (s *store) StreamVotes(ctx context.Context, fn func(models.Vote) error) error {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetBatchSize(streamBatchSize)
+
+	cursor, err := s.db.Collection("votes").Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return err
+	}
+
+	return stream(ctx, cursor, fn)
+}
+
+// StreamAggregatedProductScores calls fn for every aggregated product score, decoding one score at a time
+func (s *store) StreamAggregatedProductScores(ctx context.Context, fn func(models.ProductScore) error) error {
+	opts := options.Aggregate().
+		SetBatchSize(streamBatchSize).
+		SetAllowDiskUse(true)
+
+	cursor, err := s.db.Collection("votes").Aggregate(ctx, aggregatedScoresPipeline(), opts)
+	if err != nil {
+		return err
+	}
+
+	return stream(ctx, cursor, fn)
+}
+
+// stream decodes the documents of cursor one by one into fn and closes the cursor
+func stream[T any](ctx context.Context, cursor *mongo.Cursor, fn func(T) error) error {
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc T
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
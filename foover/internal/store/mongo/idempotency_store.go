@@ -0,0 +1,84 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"foover/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateIdempotencyRecord reserves the key of the record for a request in progress. It returns ErrDuplicate
+// if the key is held by a live record, expired records and requests in progress since before abandonedBefore are replaced.
+func (s *store) CreateIdempotencyRecord(ctx context.Context, record models.IdempotencyRecord, abandonedBefore time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	// A live record doesn't match the filter, so the upsert collides with its _id
+	filter := bson.M{
+		"_id": record.Key,
+		"$or": bson.A{
+			bson.M{"expires_at": bson.M{"$lte": record.CreatedAt}},
+			bson.M{"response": nil, "created_at": bson.M{"$lt": abandonedBefore}},
+		},
+	}
+
+	_, err := s.db.Collection("idempotency_keys").ReplaceOne(ctx, filter, record, options.Replace().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// GetIdempotencyRecord retrieves the live record of a key
+func (s *store) GetIdempotencyRecord(ctx context.Context, key string) (models.IdempotencyRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":        key,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+
+	var record models.IdempotencyRecord
+	err := s.db.Collection("idempotency_keys").FindOne(ctx, filter).Decode(&record)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.IdempotencyRecord{}, ErrNotFound
+	}
+	if err != nil {
+		return models.IdempotencyRecord{}, err
+	}
+
+	return record, nil
+}
+
+// CompleteIdempotencyRecord stores the response of the request holding the key
+func (s *store) CompleteIdempotencyRecord(ctx context.Context, key string, response models.IdempotentResponse) error {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	result, err := s.db.Collection("idempotency_keys").UpdateOne(ctx,
+		bson.M{"_id": key, "response": nil},
+		bson.M{"$set": bson.M{"response": response}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteIdempotencyRecord releases a key whose request in progress produced no response worth replaying
+func (s *store) DeleteIdempotencyRecord(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	_, err := s.db.Collection("idempotency_keys").DeleteOne(ctx, bson.M{"_id": key, "response": nil})
+	return err
+}
@@ -0,0 +1,611 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"foover/internal/metrics"
+	"foover/internal/models"
+	"foover/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("foover/internal/store/mongo")
+
+// instrumentedStore decorates a Store with a span and latency and error metrics per method
+type instrumentedStore struct {
+	next    Store
+	metrics *metrics.Metrics
+}
+
+// newInstrumentedStore wraps the given store with metrics instrumentation
+func newInstrumentedStore(next Store, m *metrics.Metrics) Store {
+	return &instrumentedStore{
+		next:    next,
+		metrics: m,
+	}
+}
+
+// observe starts a span and a timer for an operation and returns a function recording its outcome
+func (s *instrumentedStore) observe(ctx context.Context, operation string) (context.Context, func(err error)) {
+	ctx, span := tracer.Start(ctx, "Store."+operation, trace.WithSpanKind(trace.SpanKindClient))
+	start := time.Now()
+	return ctx, func(err error) {
+		// Missing and duplicate documents are expected outcomes, not failed operations
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrDuplicate) {
+			err = nil
+		}
+		s.metrics.ObserveStoreOperation(operation, time.Since(start), err)
+		tracing.End(span, err)
+	}
+}
+
+func (s *instrumentedStore) Close() error {
+	_, done := s.observe(context.Background(), "Close")
+	err := s.next.Close()
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) Ping(ctx context.Context) error {
+	ctx, done := s.observe(ctx, "Ping")
+	err := s.next.Ping(ctx)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) CreateSession(ctx context.Context, session models.Session) error {
+	ctx, done := s.observe(ctx, "CreateSession")
+	err := s.next.CreateSession(ctx, session)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) SaveVote(ctx context.Context, vote models.Vote) error {
+	ctx, done := s.observe(ctx, "SaveVote")
+	err := s.next.SaveVote(ctx, vote)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) GetVotesBySessionID(ctx context.Context, sessionID string) ([]models.Vote, error) {
+	ctx, done := s.observe(ctx, "GetVotesBySessionID")
+	votes, err := s.next.GetVotesBySessionID(ctx, sessionID)
+	done(err)
+	return votes, err
+}
+
+func (s *instrumentedStore) GetAggregatedProductScores(ctx context.Context) ([]models.ProductScore, error) {
+	ctx, done := s.observe(ctx, "GetAggregatedProductScores")
+	scores, err := s.next.GetAggregatedProductScores(ctx)
+	done(err)
+	return scores, err
+}
+
+func (s *instrumentedStore) SaveProducts(ctx context.Context, products []models.Product) error {
+	ctx, done := s.observe(ctx, "SaveProducts")
+	err := s.next.SaveProducts(ctx, products)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) IsValidProductID(ctx context.Context, productID string) (bool, error) {
+	ctx, done := s.observe(ctx, "IsValidProductID")
+	valid, err := s.next.IsValidProductID(ctx, productID)
+	done(err)
+	return valid, err
+}
+
+func (s *instrumentedStore) SessionExists(ctx context.Context, sessionID string) (bool, error) {
+	ctx, done := s.observe(ctx, "SessionExists")
+	exists, err := s.next.SessionExists(ctx, sessionID)
+	done(err)
+	return exists, err
+}
+
+func (s *instrumentedStore) GetExistingSessionIDs(ctx context.Context, sessionIDs []string) ([]string, error) {
+	ctx, done := s.observe(ctx, "GetExistingSessionIDs")
+	existing, err := s.next.GetExistingSessionIDs(ctx, sessionIDs)
+	done(err)
+	return existing, err
+}
+
+func (s *instrumentedStore) CreateAPIKey(ctx context.Context, apiKey models.APIKey) (models.APIKey, error) {
+	ctx, done := s.observe(ctx, "CreateAPIKey")
+	created, err := s.next.CreateAPIKey(ctx, apiKey)
+	done(err)
+	return created, err
+}
+
+func (s *instrumentedStore) GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	ctx, done := s.observe(ctx, "GetAPIKeyByHash")
+	apiKey, err := s.next.GetAPIKeyByHash(ctx, hash)
+	done(err)
+	return apiKey, err
+}
+
+func (s *instrumentedStore) GetAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	ctx, done := s.observe(ctx, "GetAPIKeys")
+	apiKeys, err := s.next.GetAPIKeys(ctx)
+	done(err)
+	return apiKeys, err
+}
+
+func (s *instrumentedStore) RevokeAPIKey(ctx context.Context, id string) error {
+	ctx, done := s.observe(ctx, "RevokeAPIKey")
+	err := s.next.RevokeAPIKey(ctx, id)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) GetProducts(ctx context.Context) ([]models.Product, error) {
+	ctx, done := s.observe(ctx, "GetProducts")
+	products, err := s.next.GetProducts(ctx)
+	done(err)
+	return products, err
+}
+
+func (s *instrumentedStore) GetProduct(ctx context.Context, productID string) (models.Product, error) {
+	ctx, done := s.observe(ctx, "GetProduct")
+	product, err := s.next.GetProduct(ctx, productID)
+	done(err)
+	return product, err
+}
+
+func (s *instrumentedStore) CreateProduct(ctx context.Context, product models.Product) (models.Product, error) {
+	ctx, done := s.observe(ctx, "CreateProduct")
+	created, err := s.next.CreateProduct(ctx, product)
+	done(err)
+	return created, err
+}
+
+func (s *instrumentedStore) UpdateProduct(ctx context.Context, product models.Product) (models.Product, error) {
+	ctx, done := s.observe(ctx, "UpdateProduct")
+	updated, err := s.next.UpdateProduct(ctx, product)
+	done(err)
+	return updated, err
+}
+
+func (s *instrumentedStore) SetProductRetired(ctx context.Context, productID string, retired bool) (models.Product, error) {
+	ctx, done := s.observe(ctx, "SetProductRetired")
+	product, err := s.next.SetProductRetired(ctx, productID, retired)
+	done(err)
+	return product, err
+}
+
+func (s *instrumentedStore) ImportProducts(ctx context.Context, products []models.Product) (int, error) {
+	ctx, done := s.observe(ctx, "ImportProducts")
+	imported, err := s.next.ImportProducts(ctx, products)
+	done(err)
+	return imported, err
+}
+
+func (s *instrumentedStore) GetSession(ctx context.Context, sessionID string) (models.Session, error) {
+	ctx, done := s.observe(ctx, "GetSession")
+	session, err := s.next.GetSession(ctx, sessionID)
+	done(err)
+	return session, err
+}
+
+func (s *instrumentedStore) CountVotesBySessionSince(ctx context.Context, sessionID string, since time.Time) (int64, error) {
+	ctx, done := s.observe(ctx, "CountVotesBySessionSince")
+	count, err := s.next.CountVotesBySessionSince(ctx, sessionID, since)
+	done(err)
+	return count, err
+}
+
+func (s *instrumentedStore) CountMatchingVotesSince(ctx context.Context, productID string, score int, since time.Time) (int64, error) {
+	ctx, done := s.observe(ctx, "CountMatchingVotesSince")
+	count, err := s.next.CountMatchingVotesSince(ctx, productID, score, since)
+	done(err)
+	return count, err
+}
+
+func (s *instrumentedStore) CountSessionsByFingerprintSince(ctx context.Context, fingerprint string, since time.Time) (int64, error) {
+	ctx, done := s.observe(ctx, "CountSessionsByFingerprintSince")
+	count, err := s.next.CountSessionsByFingerprintSince(ctx, fingerprint, since)
+	done(err)
+	return count, err
+}
+
+func (s *instrumentedStore) GetVotesByStatus(ctx context.Context, status string, limit int64) ([]models.Vote, error) {
+	ctx, done := s.observe(ctx, "GetVotesByStatus")
+	votes, err := s.next.GetVotesByStatus(ctx, status, limit)
+	done(err)
+	return votes, err
+}
+
+func (s *instrumentedStore) ReviewFlaggedVote(ctx context.Context, id string, status string) (models.Vote, error) {
+	ctx, done := s.observe(ctx, "ReviewFlaggedVote")
+	vote, err := s.next.ReviewFlaggedVote(ctx, id, status)
+	done(err)
+	return vote, err
+}
+
+func (s *instrumentedStore) EraseSession(ctx context.Context, sessionID string, entry models.ErasureAuditEntry) (models.ErasureAuditEntry, error) {
+	ctx, done := s.observe(ctx, "EraseSession")
+	entry, err := s.next.EraseSession(ctx, sessionID, entry)
+	done(err)
+	return entry, err
+}
+
+func (s *instrumentedStore) CountEmptySessions(ctx context.Context, createdBefore time.Time) (int64, error) {
+	ctx, done := s.observe(ctx, "CountEmptySessions")
+	count, err := s.next.CountEmptySessions(ctx, createdBefore)
+	done(err)
+	return count, err
+}
+
+func (s *instrumentedStore) DeleteEmptySessions(ctx context.Context, createdBefore time.Time, limit int64) (int64, error) {
+	ctx, done := s.observe(ctx, "DeleteEmptySessions")
+	count, err := s.next.DeleteEmptySessions(ctx, createdBefore, limit)
+	done(err)
+	return count, err
+}
+
+func (s *instrumentedStore) CountVotesToAnonymize(ctx context.Context, updatedBefore time.Time) (int64, error) {
+	ctx, done := s.observe(ctx, "CountVotesToAnonymize")
+	count, err := s.next.CountVotesToAnonymize(ctx, updatedBefore)
+	done(err)
+	return count, err
+}
+
+func (s *instrumentedStore) AnonymizeVotes(ctx context.Context, updatedBefore time.Time, limit int64) (int64, error) {
+	ctx, done := s.observe(ctx, "AnonymizeVotes")
+	count, err := s.next.AnonymizeVotes(ctx, updatedBefore, limit)
+	done(err)
+	return count, err
+}
+
+func (s *instrumentedStore) StreamVotes(ctx context.Context, fn func(models.Vote) error) error {
+	ctx, done := s.observe(ctx, "StreamVotes")
+	err := s.next.StreamVotes(ctx, fn)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) StreamAggregatedProductScores(ctx context.Context, fn func(models.ProductScore) error) error {
+	ctx, done := s.observe(ctx, "StreamAggregatedProductScores")
+	err := s.next.StreamAggregatedProductScores(ctx, fn)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) ImportVotes(ctx context.Context, votes []models.Vote) (map[int]string, error) {
+	ctx, done := s.observe(ctx, "ImportVotes")
+	failed, err := s.next.ImportVotes(ctx, votes)
+	done(err)
+	return failed, err
+}
+
+func (s *instrumentedStore) SaveVotes(ctx context.Context, votes []models.Vote, atomic bool) (map[int]string, error) {
+	ctx, done := s.observe(ctx, "SaveVotes")
+	failed, err := s.next.SaveVotes(ctx, votes, atomic)
+	done(err)
+	return failed, err
+}
+
+func (s *instrumentedStore) GetValidProductIDs(ctx context.Context, productIDs []string) ([]string, error) {
+	ctx, done := s.observe(ctx, "GetValidProductIDs")
+	valid, err := s.next.GetValidProductIDs(ctx, productIDs)
+	done(err)
+	return valid, err
+}
+
+func (s *instrumentedStore) CreateIdempotencyRecord(ctx context.Context, record models.IdempotencyRecord, abandonedBefore time.Time) error {
+	ctx, done := s.observe(ctx, "CreateIdempotencyRecord")
+	err := s.next.CreateIdempotencyRecord(ctx, record, abandonedBefore)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) GetIdempotencyRecord(ctx context.Context, key string) (models.IdempotencyRecord, error) {
+	ctx, done := s.observe(ctx, "GetIdempotencyRecord")
+	record, err := s.next.GetIdempotencyRecord(ctx, key)
+	done(err)
+	return record, err
+}
+
+func (s *instrumentedStore) CompleteIdempotencyRecord(ctx context.Context, key string, response models.IdempotentResponse) error {
+	ctx, done := s.observe(ctx, "CompleteIdempotencyRecord")
+	err := s.next.CompleteIdempotencyRecord(ctx, key, response)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) DeleteIdempotencyRecord(ctx context.Context, key string) error {
+	ctx, done := s.observe(ctx, "DeleteIdempotencyRecord")
+	err := s.next.DeleteIdempotencyRecord(ctx, key)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) ReplayVotes(ctx context.Context, votes []models.Vote) (map[int]string, error) {
+	ctx, done := s.observe(ctx, "ReplayVotes")
+	failed, err := s.next.ReplayVotes(ctx, votes)
+	done(err)
+	return failed, err
+}
+
+func (s *instrumentedStore) SaveVoteWithEvent(ctx context.Context, vote models.Vote, event models.OutboxEvent) error {
+	ctx, done := s.observe(ctx, "SaveVoteWithEvent")
+	err := s.next.SaveVoteWithEvent(ctx, vote, event)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) SaveVotesWithEvents(ctx context.Context, votes []models.Vote, events []models.OutboxEvent, atomic bool) (map[int]string, error) {
+	ctx, done := s.observe(ctx, "SaveVotesWithEvents")
+	failed, err := s.next.SaveVotesWithEvents(ctx, votes, events, atomic)
+	done(err)
+	return failed, err
+}
+
+func (s *instrumentedStore) ImportVotesWithEvents(ctx context.Context, votes []models.Vote, events []models.OutboxEvent) (map[int]string, error) {
+	ctx, done := s.observe(ctx, "ImportVotesWithEvents")
+	failed, err := s.next.ImportVotesWithEvents(ctx, votes, events)
+	done(err)
+	return failed, err
+}
+
+func (s *instrumentedStore) ReplayVotesWithEvents(ctx context.Context, votes []models.Vote, events []models.OutboxEvent) (map[int]string, error) {
+	ctx, done := s.observe(ctx, "ReplayVotesWithEvents")
+	failed, err := s.next.ReplayVotesWithEvents(ctx, votes, events)
+	done(err)
+	return failed, err
+}
+
+func (s *instrumentedStore) ReviewFlaggedVoteWithEvent(ctx context.Context, id string, status string, newEvent func(vote models.Vote) (models.OutboxEvent, error)) (models.Vote, error) {
+	ctx, done := s.observe(ctx, "ReviewFlaggedVoteWithEvent")
+	vote, err := s.next.ReviewFlaggedVoteWithEvent(ctx, id, status, newEvent)
+	done(err)
+	return vote, err
+}
+
+func (s *instrumentedStore) GetPendingOutboxEvents(ctx context.Context, excludedKeys []string, limit int64) ([]models.OutboxEvent, error) {
+	ctx, done := s.observe(ctx, "GetPendingOutboxEvents")
+	events, err := s.next.GetPendingOutboxEvents(ctx, excludedKeys, limit)
+	done(err)
+	return events, err
+}
+
+func (s *instrumentedStore) GetPublishedSequences(ctx context.Context, keys []string) (map[string]int64, error) {
+	ctx, done := s.observe(ctx, "GetPublishedSequences")
+	published, err := s.next.GetPublishedSequences(ctx, keys)
+	done(err)
+	return published, err
+}
+
+func (s *instrumentedStore) MarkOutboxEventPublished(ctx context.Context, event models.OutboxEvent, expiresAt time.Time) error {
+	ctx, done := s.observe(ctx, "MarkOutboxEventPublished")
+	err := s.next.MarkOutboxEventPublished(ctx, event, expiresAt)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) RecordOutboxEventFailure(ctx context.Context, id primitive.ObjectID, message string) error {
+	ctx, done := s.observe(ctx, "RecordOutboxEventFailure")
+	err := s.next.RecordOutboxEventFailure(ctx, id, message)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) AcquireLease(ctx context.Context, name string, owner string, ttl time.Duration) (bool, error) {
+	ctx, done := s.observe(ctx, "AcquireLease")
+	acquired, err := s.next.AcquireLease(ctx, name, owner, ttl)
+	done(err)
+	return acquired, err
+}
+
+func (s *instrumentedStore) CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	ctx, done := s.observe(ctx, "CreateWebhook")
+	webhook, err := s.next.CreateWebhook(ctx, webhook)
+	done(err)
+	return webhook, err
+}
+
+func (s *instrumentedStore) GetWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	ctx, done := s.observe(ctx, "GetWebhooks")
+	webhooks, err := s.next.GetWebhooks(ctx)
+	done(err)
+	return webhooks, err
+}
+
+func (s *instrumentedStore) GetWebhooksByEvent(ctx context.Context, eventType string) ([]models.Webhook, error) {
+	ctx, done := s.observe(ctx, "GetWebhooksByEvent")
+	webhooks, err := s.next.GetWebhooksByEvent(ctx, eventType)
+	done(err)
+	return webhooks, err
+}
+
+func (s *instrumentedStore) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	ctx, done := s.observe(ctx, "GetWebhook")
+	webhook, err := s.next.GetWebhook(ctx, id)
+	done(err)
+	return webhook, err
+}
+
+func (s *instrumentedStore) UpdateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	ctx, done := s.observe(ctx, "UpdateWebhook")
+	webhook, err := s.next.UpdateWebhook(ctx, webhook)
+	done(err)
+	return webhook, err
+}
+
+func (s *instrumentedStore) DeleteWebhook(ctx context.Context, id string) error {
+	ctx, done := s.observe(ctx, "DeleteWebhook")
+	err := s.next.DeleteWebhook(ctx, id)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) CreateWebhookDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	ctx, done := s.observe(ctx, "CreateWebhookDeliveries")
+	err := s.next.CreateWebhookDeliveries(ctx, deliveries)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) ClaimWebhookDelivery(ctx context.Context, lockedUntil time.Time) (models.WebhookDelivery, error) {
+	ctx, done := s.observe(ctx, "ClaimWebhookDelivery")
+	delivery, err := s.next.ClaimWebhookDelivery(ctx, lockedUntil)
+	done(err)
+	return delivery, err
+}
+
+func (s *instrumentedStore) RecordWebhookAttempt(ctx context.Context, delivery models.WebhookDelivery, attempt models.WebhookAttempt) error {
+	ctx, done := s.observe(ctx, "RecordWebhookAttempt")
+	err := s.next.RecordWebhookAttempt(ctx, delivery, attempt)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) GetWebhookDeliveries(ctx context.Context, webhookID string, status string, limit int64) ([]models.WebhookDelivery, error) {
+	ctx, done := s.observe(ctx, "GetWebhookDeliveries")
+	deliveries, err := s.next.GetWebhookDeliveries(ctx, webhookID, status, limit)
+	done(err)
+	return deliveries, err
+}
+
+func (s *instrumentedStore) RedeliverWebhookDelivery(ctx context.Context, id string) (models.WebhookDelivery, error) {
+	ctx, done := s.observe(ctx, "RedeliverWebhookDelivery")
+	delivery, err := s.next.RedeliverWebhookDelivery(ctx, id)
+	done(err)
+	return delivery, err
+}
+
+func (s *instrumentedStore) SetProductAlert(ctx context.Context, productID string, alert string, raised bool) (bool, error) {
+	ctx, done := s.observe(ctx, "SetProductAlert")
+	changed, err := s.next.SetProductAlert(ctx, productID, alert, raised)
+	done(err)
+	return changed, err
+}
+
+func (s *instrumentedStore) GetProductScore(ctx context.Context, productID string) (models.ProductScore, error) {
+	ctx, done := s.observe(ctx, "GetProductScore")
+	score, err := s.next.GetProductScore(ctx, productID)
+	done(err)
+	return score, err
+}
+
+func (s *instrumentedStore) GetChangedVoteProductIDs(ctx context.Context, since time.Time) ([]string, error) {
+	ctx, done := s.observe(ctx, "GetChangedVoteProductIDs")
+	productIDs, err := s.next.GetChangedVoteProductIDs(ctx, since)
+	done(err)
+	return productIDs, err
+}
+
+func (s *instrumentedStore) StreamCoRatingVotes(ctx context.Context, productIDs []string, fn func(models.Vote) error) error {
+	ctx, done := s.observe(ctx, "StreamCoRatingVotes")
+	err := s.next.StreamCoRatingVotes(ctx, productIDs, fn)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) SaveProductSimilarities(ctx context.Context, similarities []models.ProductSimilarity, builtAt time.Time) error {
+	ctx, done := s.observe(ctx, "SaveProductSimilarities")
+	err := s.next.SaveProductSimilarities(ctx, similarities, builtAt)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) DeleteStaleProductSimilarities(ctx context.Context, productIDs []string, builtBefore time.Time) (int64, error) {
+	ctx, done := s.observe(ctx, "DeleteStaleProductSimilarities")
+	deleted, err := s.next.DeleteStaleProductSimilarities(ctx, productIDs, builtBefore)
+	done(err)
+	return deleted, err
+}
+
+func (s *instrumentedStore) GetSimilarProducts(ctx context.Context, productID string, limit int64) ([]models.SimilarProduct, error) {
+	ctx, done := s.observe(ctx, "GetSimilarProducts")
+	similar, err := s.next.GetSimilarProducts(ctx, productID, limit)
+	done(err)
+	return similar, err
+}
+
+func (s *instrumentedStore) GetSimilarityIndexState(ctx context.Context) (models.SimilarityIndexState, error) {
+	ctx, done := s.observe(ctx, "GetSimilarityIndexState")
+	state, err := s.next.GetSimilarityIndexState(ctx)
+	done(err)
+	return state, err
+}
+
+func (s *instrumentedStore) SaveSimilarityIndexState(ctx context.Context, state models.SimilarityIndexState) error {
+	ctx, done := s.observe(ctx, "SaveSimilarityIndexState")
+	err := s.next.SaveSimilarityIndexState(ctx, state)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) StreamCountedVotesSince(ctx context.Context, since time.Time, fn func(models.Vote) error) error {
+	ctx, done := s.observe(ctx, "StreamCountedVotesSince")
+	err := s.next.StreamCountedVotesSince(ctx, since, fn)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) RecordAnomaly(ctx context.Context, anomaly models.Anomaly, cooldownStart time.Time) (models.Anomaly, bool, error) {
+	ctx, done := s.observe(ctx, "RecordAnomaly")
+	recorded, ok, err := s.next.RecordAnomaly(ctx, anomaly, cooldownStart)
+	done(err)
+	return recorded, ok, err
+}
+
+func (s *instrumentedStore) DeleteAnomaly(ctx context.Context, id primitive.ObjectID) error {
+	ctx, done := s.observe(ctx, "DeleteAnomaly")
+	err := s.next.DeleteAnomaly(ctx, id)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStore) GetAnomalies(ctx context.Context, productID string, direction string, since time.Time, limit int64) ([]models.Anomaly, error) {
+	ctx, done := s.observe(ctx, "GetAnomalies")
+	anomalies, err := s.next.GetAnomalies(ctx, productID, direction, since, limit)
+	done(err)
+	return anomalies, err
+}
+
+func (s *instrumentedStore) CreateExperiment(ctx context.Context, experiment models.Experiment) (models.Experiment, error) {
+	ctx, done := s.observe(ctx, "CreateExperiment")
+	created, err := s.next.CreateExperiment(ctx, experiment)
+	done(err)
+	return created, err
+}
+
+func (s *instrumentedStore) GetExperiments(ctx context.Context) ([]models.Experiment, error) {
+	ctx, done := s.observe(ctx, "GetExperiments")
+	experiments, err := s.next.GetExperiments(ctx)
+	done(err)
+	return experiments, err
+}
+
+func (s *instrumentedStore) GetRunningExperiments(ctx context.Context) ([]models.Experiment, error) {
+	ctx, done := s.observe(ctx, "GetRunningExperiments")
+	experiments, err := s.next.GetRunningExperiments(ctx)
+	done(err)
+	return experiments, err
+}
+
+func (s *instrumentedStore) GetExperiment(ctx context.Context, key string) (models.Experiment, error) {
+	ctx, done := s.observe(ctx, "GetExperiment")
+	experiment, err := s.next.GetExperiment(ctx, key)
+	done(err)
+	return experiment, err
+}
+
+func (s *instrumentedStore) StopExperiment(ctx context.Context, key string, stoppedAt time.Time) (models.Experiment, error) {
+	ctx, done := s.observe(ctx, "StopExperiment")
+	stopped, err := s.next.StopExperiment(ctx, key, stoppedAt)
+	done(err)
+	return stopped, err
+}
+
+func (s *instrumentedStore) StreamExperimentSessionScores(ctx context.Context, experiment models.Experiment, fn func(models.ExperimentSessionScore) error) error {
+	ctx, done := s.observe(ctx, "StreamExperimentSessionScores")
+	err := s.next.StreamExperimentSessionScores(ctx, experiment, fn)
+	done(err)
+	return err
+}
@@ -0,0 +1,264 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"foover/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// outboxSequence represents the event counter of an outbox key
+type outboxSequence struct {
+	Key       string `bson:"_id"`
+	Sequence  int64  `bson:"sequence"`  // sequence of the last event written
+	Published int64  `bson:"published"` // sequence of the last event published
+}
+
+// SaveVoteWithEvent stores or updates a vote and writes the event describing it to the outbox in one transaction,
+// which requires a replica set. The event is numbered with the next sequence of its key, events of a key are
+// numbered in the order their transactions commit as they all update the same counter.
+func (s *store) SaveVoteWithEvent(ctx context.Context, vote models.Vote, event models.OutboxEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	session, err := s.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(txCtx mongo.SessionContext) (interface{}, error) {
+		filter, update := voteUpsert(vote, time.Now())
+		if _, err := s.db.Collection("votes").UpdateOne(txCtx, filter, update, options.Update().SetUpsert(true)); err != nil {
+			return nil, err
+		}
+		return nil, s.insertOutboxEvents(txCtx, []models.OutboxEvent{event})
+	})
+	return err
+}
+
+// writeVotesWithEvents applies a batch of vote writes and writes the event of each vote to the outbox in one transaction,
+// events[i] describing the vote written by writes[i]. A write error aborts the transaction, so an atomic batch returns it
+// while otherwise the failed vote is left out and the others are written again in a new transaction.
+// It returns the messages of the votes that failed, keyed by their index in the batch.
+func (s *store) writeVotesWithEvents(ctx context.Context, writes []mongo.WriteModel, events []models.OutboxEvent, atomic bool) (map[int]string, error) {
+	session, err := s.client.StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	failed := make(map[int]string)
+	for len(failed) < len(writes) {
+		indexes := make([]int, 0, len(writes)-len(failed))
+		for i := range writes {
+			if _, ok := failed[i]; !ok {
+				indexes = append(indexes, i)
+			}
+		}
+
+		_, err := session.WithTransaction(ctx, func(txCtx mongo.SessionContext) (interface{}, error) {
+			batchWrites := make([]mongo.WriteModel, 0, len(indexes))
+			batchEvents := make([]models.OutboxEvent, 0, len(indexes))
+			for _, i := range indexes {
+				batchWrites = append(batchWrites, writes[i])
+				batchEvents = append(batchEvents, events[i])
+			}
+
+			if _, err := s.db.Collection("votes").BulkWrite(txCtx, batchWrites, options.BulkWrite().SetOrdered(false)); err != nil {
+				return nil, err
+			}
+			return nil, s.insertOutboxEvents(txCtx, batchEvents)
+		})
+		if err == nil {
+			return failed, nil
+		}
+
+		var bulkErr mongo.BulkWriteException
+		if atomic || !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil || len(bulkErr.WriteErrors) == 0 {
+			return nil, err
+		}
+		for _, writeErr := range bulkErr.WriteErrors {
+			failed[indexes[writeErr.Index]] = writeErr.Message
+		}
+	}
+
+	return failed, nil
+}
+
+// insertOutboxEvents numbers events with the next sequences of their keys, in the order they are given, and inserts them
+// within the transaction of txCtx. Retried transactions number and insert the events again.
+func (s *store) insertOutboxEvents(txCtx mongo.SessionContext, events []models.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	counts := make(map[string]int64)
+	for _, event := range events {
+		counts[event.Key]++
+	}
+	// Counters are updated in a fixed order, so concurrent transactions conflict early rather than midway
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	next := make(map[string]int64, len(keys))
+	for _, key := range keys {
+		var sequence outboxSequence
+		err := s.db.Collection("outbox_sequences").FindOneAndUpdate(txCtx,
+			bson.M{"_id": key},
+			bson.M{"$inc": bson.M{"sequence": counts[key]}, "$setOnInsert": bson.M{"published": int64(0)}},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		).Decode(&sequence)
+		if err != nil {
+			return err
+		}
+		next[key] = sequence.Sequence - counts[key] + 1
+	}
+
+	documents := make([]interface{}, 0, len(events))
+	for _, event := range events {
+		event.ID = primitive.NewObjectID()
+		event.Sequence = next[event.Key]
+		next[event.Key]++
+		documents = append(documents, event)
+	}
+	_, err := s.db.Collection("outbox_events").InsertMany(txCtx, documents)
+	return err
+}
+
+// GetPendingOutboxEvents retrieves up to limit unpublished events of keys other than excludedKeys, oldest first
+func (s *store) GetPendingOutboxEvents(ctx context.Context, excludedKeys []string, limit int64) ([]models.OutboxEvent, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	filter := bson.M{"published_at": bson.M{"$exists": false}}
+	if len(excludedKeys) > 0 {
+		filter["key"] = bson.M{"$nin": excludedKeys}
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(limit)
+
+	cursor, err := s.db.Collection("outbox_events").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	events := []models.OutboxEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// GetPublishedSequences retrieves the sequence of the last published event of each of the given keys,
+// keys without published events are missing from the result
+func (s *store) GetPublishedSequences(ctx context.Context, keys []string) (map[string]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	cursor, err := s.db.Collection("outbox_sequences").Find(ctx, bson.M{"_id": bson.M{"$in": keys}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var sequences []outboxSequence
+	if err := cursor.All(ctx, &sequences); err != nil {
+		return nil, err
+	}
+
+	published := make(map[string]int64, len(sequences))
+	for _, sequence := range sequences {
+		published[sequence.Key] = sequence.Published
+	}
+
+	return published, nil
+}
+
+// MarkOutboxEventPublished records an event as published and schedules its removal at expiresAt.
+// The published sequence of its key is advanced first, so an interruption leaves the event to be published again
+// rather than an event that looks published while the next one waits for it.
+func (s *store) MarkOutboxEventPublished(ctx context.Context, event models.OutboxEvent, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	_, err := s.db.Collection("outbox_sequences").UpdateOne(ctx,
+		bson.M{"_id": event.Key},
+		bson.M{"$max": bson.M{"published": event.Sequence}},
+	)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.Collection("outbox_events").UpdateOne(ctx,
+		bson.M{"_id": event.ID},
+		bson.M{
+			"$set":   bson.M{"published_at": time.Now(), "expires_at": expiresAt},
+			"$inc":   bson.M{"attempts": 1},
+			"$unset": bson.M{"last_error": ""},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// RecordOutboxEventFailure records a failed attempt to publish an event, the event stays pending
+func (s *store) RecordOutboxEventFailure(ctx context.Context, id primitive.ObjectID, message string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	_, err := s.db.Collection("outbox_events").UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$set": bson.M{"last_error": message},
+			"$inc": bson.M{"attempts": 1},
+		},
+	)
+	return err
+}
+
+// AcquireLease takes or renews the named lease for owner until ttl from now. It returns false
+// if another owner holds a lease that hasn't expired.
+func (s *store) AcquireLease(ctx context.Context, name string, owner string, ttl time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	now := time.Now()
+	// A lease held by another owner doesn't match the filter, so the upsert collides with its _id
+	filter := bson.M{
+		"_id": name,
+		"$or": bson.A{
+			bson.M{"owner": owner},
+			bson.M{"expires_at": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"owner": owner, "expires_at": now.Add(ttl)}}
+
+	_, err := s.db.Collection("leases").UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
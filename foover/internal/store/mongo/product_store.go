@@ -0,0 +1,175 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"foover/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetProducts retrieves all products, including retired ones
+func (s *store) GetProducts(ctx context.Context) ([]models.Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	cursor, err := s.db.Collection("products").Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"product_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var products []models.Product
+	if err := cursor.All(ctx, &products); err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+// GetProduct retrieves the product with the given product ID
+func (s *store) GetProduct(ctx context.Context, productID string) (models.Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	var product models.Product
+	err := s.db.Collection("products").FindOne(ctx, bson.M{"product_id": productID}).Decode(&product)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Product{}, ErrNotFound
+	}
+	if err != nil {
+		return models.Product{}, err
+	}
+
+	return product, nil
+}
+
+// CreateProduct stores a new product
+func (s *store) CreateProduct(ctx context.Context, product models.Product) (models.Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	now := time.Now()
+	product.CreatedAt = now
+	product.UpdatedAt = now
+
+	_, err := s.db.Collection("products").InsertOne(ctx, product)
+	if mongo.IsDuplicateKeyError(err) {
+		return models.Product{}, ErrDuplicate
+	}
+	if err != nil {
+		return models.Product{}, err
+	}
+
+	return product, nil
+}
+
+// UpdateProduct updates the name and pin of a product
+func (s *store) UpdateProduct(ctx context.Context, product models.Product) (models.Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"name":       product.Name,
+			"pinned":     product.Pinned,
+			"updated_at": time.Now(),
+		},
+	}
+
+	return s.findOneAndUpdateProduct(ctx, product.ProductID, update)
+}
+
+// SetProductRetired retires or restores a product
+func (s *store) SetProductRetired(ctx context.Context, productID string, retired bool) (models.Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	now := time.Now()
+	update := bson.M{
+		"$set":   bson.M{"updated_at": now},
+		"$unset": bson.M{"retired_at": ""},
+	}
+	if retired {
+		update = bson.M{
+			"$set": bson.M{"updated_at": now, "retired_at": now},
+		}
+	}
+
+	return s.findOneAndUpdateProduct(ctx, productID, update)
+}
+
+// ImportProducts upserts manually managed products and returns the number of written products
+func (s *store) ImportProducts(ctx context.Context, products []models.Product) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	if len(products) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	writes := make([]mongo.WriteModel, 0, len(products))
+	for _, product := range products {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"product_id": product.ProductID}).
+			SetUpdate(bson.M{
+				"$set": bson.M{
+					"name":       product.Name,
+					"pinned":     product.Pinned,
+					"source":     models.ProductSourceManual,
+					"updated_at": now,
+				},
+				"$setOnInsert": bson.M{"created_at": now},
+			}).
+			SetUpsert(true))
+	}
+
+	result, err := s.db.Collection("products").BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return 0, err
+	}
+
+	return int(result.MatchedCount + result.UpsertedCount), nil
+}
+
+// findOneAndUpdateProduct applies update to a product and returns the updated product
+func (s *store) findOneAndUpdateProduct(ctx context.Context, productID string, update bson.M) (models.Product, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var product models.Product
+	err := s.db.Collection("products").FindOneAndUpdate(ctx, bson.M{"product_id": productID}, update, opts).Decode(&product)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Product{}, ErrNotFound
+	}
+	if err != nil {
+		return models.Product{}, err
+	}
+
+	return product, nil
+}
+
+// GetValidProductIDs returns the given product IDs that exist and are not retired
+func (s *store) GetValidProductIDs(ctx context.Context, productIDs []string) ([]string, error) {
+	filter := bson.M{
+		"product_id": bson.M{"$in": productIDs},
+		"retired_at": bson.M{"$exists": false},
+	}
+
+	values, err := s.db.Collection("products").Distinct(ctx, "product_id", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	valid := make([]string, 0, len(values))
+	for _, value := range values {
+		if productID, ok := value.(string); ok {
+			valid = append(valid, productID)
+		}
+	}
+
+	return valid, nil
+}
@@ -0,0 +1,173 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// emptySessionsPipeline matches sessions created before the given time without any vote
+func emptySessionsPipeline(createdBefore time.Time) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "created_at", Value: bson.D{{Key: "$lt", Value: createdBefore}}}}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "votes"},
+			{Key: "let", Value: bson.D{{Key: "sessionID", Value: "$session_id"}}},
+			{Key: "pipeline", Value: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{{Key: "$expr", Value: bson.D{{Key: "$eq", Value: bson.A{"$session_id", "$$sessionID"}}}}}}},
+				bson.D{{Key: "$limit", Value: 1}},
+				bson.D{{Key: "$project", Value: bson.D{{Key: "_id", Value: 1}}}},
+			}},
+			{Key: "as", Value: "votes"},
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "votes", Value: bson.D{{Key: "$size", Value: 0}}}}}},
+	}
+}
+
+// CountEmptySessions counts the sessions created before the given time without any vote
+func (s *store) CountEmptySessions(ctx context.Context, createdBefore time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.aggregateTimeout)
+	defer cancel()
+
+	pipeline := append(emptySessionsPipeline(createdBefore), bson.D{{Key: "$count", Value: "count"}})
+	cursor, err := s.db.Collection("sessions").Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Count int64 `bson:"count"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return 0, err
+	}
+	if len(result) == 0 {
+		return 0, nil
+	}
+
+	return result[0].Count, nil
+}
+
+// DeleteEmptySessions deletes up to limit sessions created before the given time without any vote.
+// Each session is deleted in a transaction checking again that it has no vote, which requires a replica set,
+// so a vote saved since the sessions were found keeps its session.
+func (s *store) DeleteEmptySessions(ctx context.Context, createdBefore time.Time, limit int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.aggregateTimeout)
+	defer cancel()
+
+	pipeline := append(emptySessionsPipeline(createdBefore),
+		bson.D{{Key: "$limit", Value: limit}},
+		bson.D{{Key: "$project", Value: bson.D{{Key: "_id", Value: 1}, {Key: "session_id", Value: 1}}}},
+	)
+	cursor, err := s.db.Collection("sessions").Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []struct {
+		ID        primitive.ObjectID `bson:"_id"`
+		SessionID string             `bson:"session_id"`
+	}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return 0, err
+	}
+	if len(sessions) == 0 {
+		return 0, nil
+	}
+
+	txSession, err := s.client.StartSession()
+	if err != nil {
+		return 0, err
+	}
+	defer txSession.EndSession(ctx)
+
+	var deleted int64
+	for _, session := range sessions {
+		result, err := txSession.WithTransaction(ctx, func(txCtx mongo.SessionContext) (interface{}, error) {
+			votes, err := s.db.Collection("votes").CountDocuments(txCtx, bson.M{"session_id": session.SessionID}, options.Count().SetLimit(1))
+			if err != nil || votes > 0 {
+				return int64(0), err
+			}
+
+			result, err := s.db.Collection("sessions").DeleteOne(txCtx, bson.M{"_id": session.ID})
+			if err != nil {
+				return int64(0), err
+			}
+			return result.DeletedCount, nil
+		})
+		if err != nil {
+			return deleted, err
+		}
+		deleted += result.(int64)
+	}
+
+	return deleted, nil
+}
+
+// votesToAnonymizeFilter matches votes last updated before the given time that are still linked to their session
+func votesToAnonymizeFilter(updatedBefore time.Time) bson.M {
+	return bson.M{
+		"updated_at":    bson.M{"$lt": updatedBefore},
+		"anonymized_at": bson.M{"$exists": false},
+	}
+}
+
+// CountVotesToAnonymize counts the votes last updated before the given time that are still linked to their session
+func (s *store) CountVotesToAnonymize(ctx context.Context, updatedBefore time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.aggregateTimeout)
+	defer cancel()
+
+	return s.db.Collection("votes").CountDocuments(ctx, votesToAnonymizeFilter(updatedBefore))
+}
+
+// AnonymizeVotes unlinks up to limit votes last updated before the given time from their session.
+// The session ID is replaced by a value derived from the vote ID, which keeps the session and product index unique,
+// and the client fingerprint is dropped. Product, score and status are kept, so aggregated scores don't change.
+func (s *store) AnonymizeVotes(ctx context.Context, updatedBefore time.Time, limit int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.aggregateTimeout)
+	defer cancel()
+
+	opts := options.Find().
+		SetProjection(bson.M{"_id": 1}).
+		SetLimit(limit)
+	cursor, err := s.db.Collection("votes").Find(ctx, votesToAnonymizeFilter(updatedBefore), opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var votes []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &votes); err != nil {
+		return 0, err
+	}
+	if len(votes) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(votes))
+	for _, vote := range votes {
+		ids = append(ids, vote.ID)
+	}
+
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.D{
+			{Key: "session_id", Value: bson.D{{Key: "$concat", Value: bson.A{"anonymized-", bson.D{{Key: "$toString", Value: "$_id"}}}}}},
+			{Key: "anonymized_at", Value: "$$NOW"},
+		}}},
+		{{Key: "$unset", Value: bson.A{"fingerprint"}}},
+	}
+	result, err := s.db.Collection("votes").UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, update)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
@@ -0,0 +1,116 @@
+package mongo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"foover/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// EraseSession deletes a session, its votes and the webhook deliveries of its votes and records the erasure in the audit
+// collection, in one transaction, which requires a replica set. Published outbox events of the session are deleted,
+// pending ones are kept so their products' sequences have no gap, but lose the session ID from their payload.
+// The audit entry keeps the products of the erased votes, so changes derived from votes can catch up on the erasure.
+// Aggregated scores are computed from the stored votes, so they no longer include the erased votes.
+func (s *store) EraseSession(ctx context.Context, sessionID string, entry models.ErasureAuditEntry) (models.ErasureAuditEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	session, err := s.client.StartSession()
+	if err != nil {
+		return models.ErasureAuditEntry{}, err
+	}
+	defer session.EndSession(ctx)
+
+	erased, err := session.WithTransaction(ctx, func(txCtx mongo.SessionContext) (interface{}, error) {
+		filter := bson.M{"session_id": sessionID}
+
+		productIDs, err := s.db.Collection("votes").Distinct(txCtx, "product_id", filter)
+		if err != nil {
+			return nil, err
+		}
+		votes, err := s.db.Collection("votes").DeleteMany(txCtx, filter)
+		if err != nil {
+			return nil, err
+		}
+		sessions, err := s.db.Collection("sessions").DeleteOne(txCtx, filter)
+		if err != nil {
+			return nil, err
+		}
+		if sessions.DeletedCount == 0 && votes.DeletedCount == 0 {
+			return nil, ErrNotFound
+		}
+
+		if _, err := s.db.Collection("webhook_deliveries").DeleteMany(txCtx, filter); err != nil {
+			return nil, err
+		}
+		if err := s.eraseOutboxEvents(txCtx, sessionID); err != nil {
+			return nil, err
+		}
+
+		e := entry
+		e.VotesDeleted = votes.DeletedCount
+		e.ErasedAt = time.Now()
+		for _, value := range productIDs {
+			if productID, ok := value.(string); ok {
+				e.ProductIDs = append(e.ProductIDs, productID)
+			}
+		}
+		result, err := s.db.Collection("erasure_audit").InsertOne(txCtx, e)
+		if err != nil {
+			return nil, err
+		}
+		e.ID = result.InsertedID.(primitive.ObjectID)
+		return e, nil
+	})
+	if err != nil {
+		return models.ErasureAuditEntry{}, err
+	}
+
+	return erased.(models.ErasureAuditEntry), nil
+}
+
+// eraseOutboxEvents deletes the published outbox events of a session and removes the session ID from the payloads
+// of its pending events, which stay to be published in their sequence
+func (s *store) eraseOutboxEvents(txCtx mongo.SessionContext, sessionID string) error {
+	collection := s.db.Collection("outbox_events")
+
+	_, err := collection.DeleteMany(txCtx, bson.M{"session_id": sessionID, "published_at": bson.M{"$exists": true}})
+	if err != nil {
+		return err
+	}
+
+	cursor, err := collection.Find(txCtx, bson.M{"session_id": sessionID})
+	if err != nil {
+		return err
+	}
+	var pending []models.OutboxEvent
+	if err := cursor.All(txCtx, &pending); err != nil {
+		return err
+	}
+
+	for _, event := range pending {
+		var payload map[string]json.RawMessage
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return err
+		}
+		delete(payload, "session_id")
+		scrubbed, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+
+		_, err = collection.UpdateOne(txCtx,
+			bson.M{"_id": event.ID},
+			bson.M{"$set": bson.M{"payload": json.RawMessage(scrubbed)}, "$unset": bson.M{"session_id": ""}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
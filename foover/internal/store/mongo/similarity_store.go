@@ -0,0 +1,199 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"foover/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// similarityIndexStateID is the ID of the state document of the product similarity index
+const similarityIndexStateID = "product_similarities"
+
+// coRatingVotesFilter matches the votes counted in the aggregated scores that are still linked to their session
+func coRatingVotesFilter() bson.M {
+	return bson.M{
+		"status":        bson.M{"$nin": bson.A{models.VoteStatusFlagged, models.VoteStatusRejected}},
+		"anonymized_at": bson.M{"$exists": false},
+	}
+}
+
+// GetChangedVoteProductIDs retrieves the IDs of the products with votes saved, moderated, anonymized or erased since the given time
+func (s *store) GetChangedVoteProductIDs(ctx context.Context, since time.Time) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.aggregateTimeout)
+	defer cancel()
+
+	filter := bson.M{"$or": bson.A{
+		bson.M{"updated_at": bson.M{"$gte": since}},
+		bson.M{"reviewed_at": bson.M{"$gte": since}},
+		bson.M{"anonymized_at": bson.M{"$gte": since}},
+	}}
+	changed, err := s.db.Collection("votes").Distinct(ctx, "product_id", filter)
+	if err != nil {
+		return nil, err
+	}
+	// Erased votes are gone, their products are recorded with the erasure
+	erased, err := s.db.Collection("erasure_audit").Distinct(ctx, "product_ids", bson.M{"erased_at": bson.M{"$gte": since}})
+	if err != nil {
+		return nil, err
+	}
+
+	productIDs := make([]string, 0, len(changed)+len(erased))
+	for _, value := range append(changed, erased...) {
+		if productID, ok := value.(string); ok && !slices.Contains(productIDs, productID) {
+			productIDs = append(productIDs, productID)
+		}
+	}
+
+	return productIDs, nil
+}
+
+// StreamCoRatingVotes calls fn for every counted vote, still linked to its session, of the sessions
+// that voted on one of productIDs, or of all sessions if productIDs is nil.
+// The stream is bounded by ctx only, like StreamVotes.
+func (s *store) StreamCoRatingVotes(ctx context.Context, productIDs []string, fn func(models.Vote) error) error {
+	collection := s.db.Collection("votes")
+
+	if productIDs == nil {
+		cursor, err := collection.Find(ctx, coRatingVotesFilter(), options.Find().SetBatchSize(streamBatchSize))
+		if err != nil {
+			return err
+		}
+		return stream(ctx, cursor, fn)
+	}
+
+	match := coRatingVotesFilter()
+	match["product_id"] = bson.M{"$in": productIDs}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$session_id"}}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "votes"},
+			{Key: "localField", Value: "_id"},
+			{Key: "foreignField", Value: "session_id"},
+			{Key: "pipeline", Value: bson.A{bson.D{{Key: "$match", Value: coRatingVotesFilter()}}}},
+			{Key: "as", Value: "votes"},
+		}}},
+		{{Key: "$unwind", Value: "$votes"}},
+		{{Key: "$replaceRoot", Value: bson.D{{Key: "newRoot", Value: "$votes"}}}},
+	}
+	opts := options.Aggregate().
+		SetBatchSize(streamBatchSize).
+		SetAllowDiskUse(true)
+
+	cursor, err := collection.Aggregate(ctx, pipeline, opts)
+	if err != nil {
+		return err
+	}
+
+	return stream(ctx, cursor, fn)
+}
+
+// SaveProductSimilarities upserts similarities, stamped with builtAt
+func (s *store) SaveProductSimilarities(ctx context.Context, similarities []models.ProductSimilarity, builtAt time.Time) error {
+	if len(similarities) == 0 {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(similarities))
+	for _, similarity := range similarities {
+		similarity.BuiltAt = builtAt
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"product_id": similarity.ProductID, "similar_product_id": similarity.SimilarProductID}).
+			SetReplacement(similarity).
+			SetUpsert(true))
+	}
+
+	_, err := s.db.Collection("product_similarities").BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// DeleteStaleProductSimilarities deletes the similarities built before builtBefore that involve one of productIDs,
+// or all of them if productIDs is nil, and returns the number of deleted similarities
+func (s *store) DeleteStaleProductSimilarities(ctx context.Context, productIDs []string, builtBefore time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.aggregateTimeout)
+	defer cancel()
+
+	filter := bson.M{"built_at": bson.M{"$lt": builtBefore}}
+	if productIDs != nil {
+		filter["$or"] = bson.A{
+			bson.M{"product_id": bson.M{"$in": productIDs}},
+			bson.M{"similar_product_id": bson.M{"$in": productIDs}},
+		}
+	}
+
+	result, err := s.db.Collection("product_similarities").DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+// GetSimilarProducts retrieves up to limit products similar to a product, most similar first, retired products are left out
+func (s *store) GetSimilarProducts(ctx context.Context, productID string, limit int64) ([]models.SimilarProduct, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "product_id", Value: productID}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "similarity", Value: -1}, {Key: "support", Value: -1}, {Key: "similar_product_id", Value: 1}}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "products"},
+			{Key: "localField", Value: "similar_product_id"},
+			{Key: "foreignField", Value: "product_id"},
+			{Key: "pipeline", Value: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{{Key: "retired_at", Value: bson.D{{Key: "$exists", Value: false}}}}}},
+				bson.D{{Key: "$project", Value: bson.D{{Key: "_id", Value: 1}}}},
+			}},
+			{Key: "as", Value: "product"},
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "product", Value: bson.D{{Key: "$ne", Value: bson.A{}}}}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	cursor, err := s.db.Collection("product_similarities").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var similar []models.SimilarProduct
+	if err := cursor.All(ctx, &similar); err != nil {
+		return nil, err
+	}
+
+	return similar, nil
+}
+
+// GetSimilarityIndexState retrieves the state of the product similarity index, ErrNotFound is returned if it was never built
+func (s *store) GetSimilarityIndexState(ctx context.Context) (models.SimilarityIndexState, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	var state models.SimilarityIndexState
+	err := s.db.Collection("similarity_index").FindOne(ctx, bson.M{"_id": similarityIndexStateID}).Decode(&state)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.SimilarityIndexState{}, ErrNotFound
+	}
+	if err != nil {
+		return models.SimilarityIndexState{}, err
+	}
+
+	return state, nil
+}
+
+// SaveSimilarityIndexState stores the state of the product similarity index
+func (s *store) SaveSimilarityIndexState(ctx context.Context, state models.SimilarityIndexState) error {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	state.ID = similarityIndexStateID
+	_, err := s.db.Collection("similarity_index").ReplaceOne(ctx, bson.M{"_id": similarityIndexStateID}, state, options.Replace().SetUpsert(true))
+	return err
+}
@@ -2,41 +2,128 @@ package mongo
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"foover/internal/config"
+	"foover/internal/metrics"
 	"foover/internal/models"
-	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+)
+
+var (
+	// ErrNotFound is returned when a document to read or modify does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when a document to create already exists
+	ErrDuplicate = errors.New("duplicate")
+	// ErrUnavailable is returned without calling MongoDB while it is considered unreachable
+	ErrUnavailable = errors.New("store unavailable")
 )
 
 // Store defines behaviors of the MongoDB store
 type Store interface {
 	Close() error
-	CreateSession(ctx context.Context) (string, error)
+	Ping(ctx context.Context) error
+	CreateSession(ctx context.Context, session models.Session) error
 	SaveVote(ctx context.Context, vote models.Vote) error
 	GetVotesBySessionID(ctx context.Context, sessionID string) ([]models.Vote, error)
 	GetAggregatedProductScores(ctx context.Context) ([]models.ProductScore, error)
 	SaveProducts(ctx context.Context, products []models.Product) error
 	IsValidProductID(ctx context.Context, productID string) (bool, error)
 	SessionExists(ctx context.Context, sessionID string) (bool, error)
+	GetExistingSessionIDs(ctx context.Context, sessionIDs []string) ([]string, error)
+	CreateAPIKey(ctx context.Context, apiKey models.APIKey) (models.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error)
+	GetAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+	GetProducts(ctx context.Context) ([]models.Product, error)
+	GetProduct(ctx context.Context, productID string) (models.Product, error)
+	CreateProduct(ctx context.Context, product models.Product) (models.Product, error)
+	UpdateProduct(ctx context.Context, product models.Product) (models.Product, error)
+	SetProductRetired(ctx context.Context, productID string, retired bool) (models.Product, error)
+	ImportProducts(ctx context.Context, products []models.Product) (int, error)
+	GetSession(ctx context.Context, sessionID string) (models.Session, error)
+	CountVotesBySessionSince(ctx context.Context, sessionID string, since time.Time) (int64, error)
+	CountMatchingVotesSince(ctx context.Context, productID string, score int, since time.Time) (int64, error)
+	CountSessionsByFingerprintSince(ctx context.Context, fingerprint string, since time.Time) (int64, error)
+	GetVotesByStatus(ctx context.Context, status string, limit int64) ([]models.Vote, error)
+	ReviewFlaggedVote(ctx context.Context, id string, status string) (models.Vote, error)
+	EraseSession(ctx context.Context, sessionID string, entry models.ErasureAuditEntry) (models.ErasureAuditEntry, error)
+	CountEmptySessions(ctx context.Context, createdBefore time.Time) (int64, error)
+	DeleteEmptySessions(ctx context.Context, createdBefore time.Time, limit int64) (int64, error)
+	CountVotesToAnonymize(ctx context.Context, updatedBefore time.Time) (int64, error)
+	AnonymizeVotes(ctx context.Context, updatedBefore time.Time, limit int64) (int64, error)
+	StreamVotes(ctx context.Context, fn func(models.Vote) error) error
+	StreamAggregatedProductScores(ctx context.Context, fn func(models.ProductScore) error) error
+	ImportVotes(ctx context.Context, votes []models.Vote) (map[int]string, error)
+	SaveVotes(ctx context.Context, votes []models.Vote, atomic bool) (map[int]string, error)
+	GetValidProductIDs(ctx context.Context, productIDs []string) ([]string, error)
+	CreateIdempotencyRecord(ctx context.Context, record models.IdempotencyRecord, abandonedBefore time.Time) error
+	GetIdempotencyRecord(ctx context.Context, key string) (models.IdempotencyRecord, error)
+	CompleteIdempotencyRecord(ctx context.Context, key string, response models.IdempotentResponse) error
+	DeleteIdempotencyRecord(ctx context.Context, key string) error
+	ReplayVotes(ctx context.Context, votes []models.Vote) (map[int]string, error)
+	SaveVoteWithEvent(ctx context.Context, vote models.Vote, event models.OutboxEvent) error
+	SaveVotesWithEvents(ctx context.Context, votes []models.Vote, events []models.OutboxEvent, atomic bool) (map[int]string, error)
+	ImportVotesWithEvents(ctx context.Context, votes []models.Vote, events []models.OutboxEvent) (map[int]string, error)
+	ReplayVotesWithEvents(ctx context.Context, votes []models.Vote, events []models.OutboxEvent) (map[int]string, error)
+	ReviewFlaggedVoteWithEvent(ctx context.Context, id string, status string, newEvent func(vote models.Vote) (models.OutboxEvent, error)) (models.Vote, error)
+	GetPendingOutboxEvents(ctx context.Context, excludedKeys []string, limit int64) ([]models.OutboxEvent, error)
+	GetPublishedSequences(ctx context.Context, keys []string) (map[string]int64, error)
+	MarkOutboxEventPublished(ctx context.Context, event models.OutboxEvent, expiresAt time.Time) error
+	RecordOutboxEventFailure(ctx context.Context, id primitive.ObjectID, message string) error
+	AcquireLease(ctx context.Context, name string, owner string, ttl time.Duration) (bool, error)
+	CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error)
+	GetWebhooks(ctx context.Context) ([]models.Webhook, error)
+	GetWebhooksByEvent(ctx context.Context, eventType string) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, id string) (models.Webhook, error)
+	UpdateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	CreateWebhookDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error
+	ClaimWebhookDelivery(ctx context.Context, lockedUntil time.Time) (models.WebhookDelivery, error)
+	RecordWebhookAttempt(ctx context.Context, delivery models.WebhookDelivery, attempt models.WebhookAttempt) error
+	GetWebhookDeliveries(ctx context.Context, webhookID string, status string, limit int64) ([]models.WebhookDelivery, error)
+	RedeliverWebhookDelivery(ctx context.Context, id string) (models.WebhookDelivery, error)
+	SetProductAlert(ctx context.Context, productID string, alert string, raised bool) (bool, error)
+	GetProductScore(ctx context.Context, productID string) (models.ProductScore, error)
+	GetChangedVoteProductIDs(ctx context.Context, since time.Time) ([]string, error)
+	StreamCoRatingVotes(ctx context.Context, productIDs []string, fn func(models.Vote) error) error
+	SaveProductSimilarities(ctx context.Context, similarities []models.ProductSimilarity, builtAt time.Time) error
+	DeleteStaleProductSimilarities(ctx context.Context, productIDs []string, builtBefore time.Time) (int64, error)
+	GetSimilarProducts(ctx context.Context, productID string, limit int64) ([]models.SimilarProduct, error)
+	GetSimilarityIndexState(ctx context.Context) (models.SimilarityIndexState, error)
+	SaveSimilarityIndexState(ctx context.Context, state models.SimilarityIndexState) error
+	StreamCountedVotesSince(ctx context.Context, since time.Time, fn func(models.Vote) error) error
+	RecordAnomaly(ctx context.Context, anomaly models.Anomaly, cooldownStart time.Time) (models.Anomaly, bool, error)
+	DeleteAnomaly(ctx context.Context, id primitive.ObjectID) error
+	GetAnomalies(ctx context.Context, productID string, direction string, since time.Time, limit int64) ([]models.Anomaly, error)
+	CreateExperiment(ctx context.Context, experiment models.Experiment) (models.Experiment, error)
+	GetExperiments(ctx context.Context) ([]models.Experiment, error)
+	GetRunningExperiments(ctx context.Context) ([]models.Experiment, error)
+	GetExperiment(ctx context.Context, key string) (models.Experiment, error)
+	StopExperiment(ctx context.Context, key string, stoppedAt time.Time) (models.Experiment, error)
+	StreamExperimentSessionScores(ctx context.Context, experiment models.Experiment, fn func(models.ExperimentSessionScore) error) error
 }
 
 // store represents the MongoDB store
 type store struct {
-	client           *mongo.Client
-	db               *mongo.Database
-	sessionTimeout   time.Duration
-	voteTimeout      time.Duration
-	aggregateTimeout time.Duration
+	client            *mongo.Client
+	db                *mongo.Database
+	pingTimeout       time.Duration
+	disconnectTimeout time.Duration
+	sessionTimeout    time.Duration
+	voteTimeout       time.Duration
+	aggregateTimeout  time.Duration
 }
 
-// NewStore creates and returns a new MongoDB store
-func NewStore(cfg config.Mongo) (Store, error) {
+// NewStore creates and returns a new MongoDB store instrumented with the given metrics
+func NewStore(cfg config.Mongo, m *metrics.Metrics) (Store, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
 	defer cancel()
 
@@ -44,6 +131,9 @@ func NewStore(cfg config.Mongo) (Store, error) {
 	clientOptions.SetMinPoolSize(cfg.MinPoolSize)
 	clientOptions.SetMaxPoolSize(cfg.MaxPoolSize)
 	clientOptions.SetConnectTimeout(cfg.ConnectTimeout)
+	clientOptions.SetServerSelectionTimeout(cfg.ServerSelectionTimeout)
+	clientOptions.SetPoolMonitor(m.PoolMonitor())
+	clientOptions.SetMonitor(otelmongo.NewMonitor())
 
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
@@ -61,48 +151,50 @@ func NewStore(cfg config.Mongo) (Store, error) {
 	db := client.Database(cfg.Database)
 
 	s := &store{
-		client:           client,
-		db:               db,
-		sessionTimeout:   cfg.ReadTimeout,
-		voteTimeout:      cfg.WriteTimeout,
-		aggregateTimeout: cfg.ReadTimeout,
+		client:            client,
+		db:                db,
+		pingTimeout:       cfg.PingTimeout,
+		disconnectTimeout: cfg.DisconnectTimeout,
+		sessionTimeout:    cfg.ReadTimeout,
+		voteTimeout:       cfg.WriteTimeout,
+		aggregateTimeout:  cfg.ReadTimeout,
 	}
 
 	if err := s.ensureIndexes(); err != nil {
 		return nil, err
 	}
 
-	return s, nil
+	return newInstrumentedStore(s, m), nil
 }
 
 // Close disconnects the MongoDB client
 func (s *store) Close() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.disconnectTimeout)
 	defer cancel()
 	return s.client.Disconnect(ctx)
 }
 
-// CreateSession generates and stores a new unique session ID
-func (s *store) CreateSession(ctx context.Context) (string, error) {
-	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+// Ping checks the connection to the primary MongoDB node
+func (s *store) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.pingTimeout)
 	defer cancel()
+	return s.client.Ping(ctx, readpref.Primary())
+}
 
-	sessionID := uuid.New().String()
-	session := models.Session{
-		SessionID: sessionID,
-		CreatedAt: time.Now(),
-	}
+// CreateSession stores a new session, its session ID is unique
+func (s *store) CreateSession(ctx context.Context, session models.Session) error {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
 
 	_, err := s.db.Collection("sessions").InsertOne(ctx, session)
-	if err != nil {
-		return "", err
-	}
-
-	return sessionID, nil
+	return err
 }
 
 // SessionExists checks if a session ID exists
 func (s *store) SessionExists(ctx context.Context, sessionID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
 	collection := s.db.Collection("sessions")
 
 	count, err := collection.CountDocuments(ctx, bson.M{"session_id": sessionID})
@@ -113,11 +205,57 @@ func (s *store) SessionExists(ctx context.Context, sessionID string) (bool, erro
 	return count > 0, nil
 }
 
+// GetExistingSessionIDs returns the given session IDs that exist with a single query
+func (s *store) GetExistingSessionIDs(ctx context.Context, sessionIDs []string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	values, err := s.db.Collection("sessions").Distinct(ctx, "session_id", bson.M{"session_id": bson.M{"$in": sessionIDs}})
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make([]string, 0, len(values))
+	for _, value := range values {
+		if sessionID, ok := value.(string); ok {
+			existing = append(existing, sessionID)
+		}
+	}
+	return existing, nil
+}
+
+// GetSession retrieves the session with the given session ID
+func (s *store) GetSession(ctx context.Context, sessionID string) (models.Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	var session models.Session
+	err := s.db.Collection("sessions").FindOne(ctx, bson.M{"session_id": sessionID}).Decode(&session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Session{}, ErrNotFound
+	}
+	if err != nil {
+		return models.Session{}, err
+	}
+
+	return session, nil
+}
+
 // SaveVote stores or updates a vote for a given session ID and product ID
 func (s *store) SaveVote(ctx context.Context, vote models.Vote) error {
 	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
 	defer cancel()
 
+	filter, update := voteUpsert(vote, time.Now())
+
+	options := options.Update().SetUpsert(true)
+
+	_, err := s.db.Collection("votes").UpdateOne(ctx, filter, update, options)
+	return err
+}
+
+// voteUpsert returns the filter and update storing a vote cast at the given time
+func voteUpsert(vote models.Vote, now time.Time) (bson.M, bson.M) {
 	filter := bson.M{
 		"session_id": vote.SessionID,
 		"product_id": vote.ProductID,
@@ -125,15 +263,18 @@ func (s *store) SaveVote(ctx context.Context, vote models.Vote) error {
 
 	update := bson.M{
 		"$set": bson.M{
-			"score":      vote.Score,
-			"updated_at": time.Now(),
+			"score":         vote.Score,
+			"updated_at":    now,
+			"status":        vote.Status,
+			"fraud_score":   vote.FraudScore,
+			"fraud_reasons": vote.FraudReasons,
+			"fingerprint":   vote.Fingerprint,
 		},
+		// A changed vote is assessed again, so an earlier review no longer applies
+		"$unset": bson.M{"reviewed_at": ""},
 	}
 
-	options := options.Update().SetUpsert(true)
-
-	_, err := s.db.Collection("votes").UpdateOne(ctx, filter, update, options)
-	return err
+	return filter, update
 }
 
 // GetVotesBySessionID retrieves all votes associated with a session ID
@@ -164,17 +305,7 @@ func (s *store) GetAggregatedProductScores(ctx context.Context) ([]models.Produc
 	ctx, cancel := context.WithTimeout(ctx, s.aggregateTimeout)
 	defer cancel()
 
-	pipeline := mongo.Pipeline{
-		{
-			{"$group", bson.D{
-				{"_id", "$product_id"},
-				{"avg_score", bson.D{{"$avg", "$score"}}},
-				{"vote_count", bson.D{{"$sum", 1}}},
-			}},
-		},
-	}
-
-	cursor, err := s.db.Collection("votes").Aggregate(ctx, pipeline)
+	cursor, err := s.db.Collection("votes").Aggregate(ctx, aggregatedScoresPipeline())
 	if err != nil {
 		return nil, err
 	}
@@ -188,35 +319,82 @@ func (s *store) GetAggregatedProductScores(ctx context.Context) ([]models.Produc
 	return results, nil
 }
 
-// SaveProducts stores a list of products
+// aggregatedScoresPipeline groups the counted votes by product into average scores
+func aggregatedScoresPipeline() mongo.Pipeline {
+	return mongo.Pipeline{
+		// Flagged and rejected votes don't count, votes stored before moderation have no status
+		{
+			{Key: "$match", Value: bson.D{
+				{Key: "status", Value: bson.D{{Key: "$nin", Value: bson.A{models.VoteStatusFlagged, models.VoteStatusRejected}}}},
+			}},
+		},
+		{
+			{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: "$product_id"},
+				{Key: "avg_score", Value: bson.D{{Key: "$avg", Value: "$score"}}},
+				{Key: "vote_count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			}},
+		},
+		// $group outputs in no defined order, a stable order keeps identical scores serialized identically
+		{
+			{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}},
+		},
+	}
+}
+
+// SaveProducts replaces the catalog products with the given list,
+// pinned and manually created products are kept even if they are missing from the list
 func (s *store) SaveProducts(ctx context.Context, products []models.Product) error {
 	collection := s.db.Collection("products")
+	now := time.Now()
 
-	var docs []interface{}
+	var writes []mongo.WriteModel
+	productIDs := make([]string, 0, len(products))
 	for _, product := range products {
-		docs = append(docs, product)
-	}
-
-	// Clear existing products
-	if _, err := collection.DeleteMany(ctx, bson.M{}); err != nil {
-		return err
+		productIDs = append(productIDs, product.ProductID)
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"product_id": product.ProductID}).
+			SetUpdate(bson.M{
+				"$set": bson.M{"updated_at": now},
+				"$setOnInsert": bson.M{
+					"source":     models.ProductSourceCatalog,
+					"pinned":     false,
+					"created_at": now,
+				},
+			}).
+			SetUpsert(true))
 	}
 
-	// Insert new products
-	if len(docs) > 0 {
-		if _, err := collection.InsertMany(ctx, docs); err != nil {
+	// Upsert catalog products, keeping the state set through the admin API
+	if len(writes) > 0 {
+		if _, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
 			return err
 		}
 	}
 
+	// Clear catalog products that are no longer listed
+	filter := bson.M{
+		"product_id": bson.M{"$nin": productIDs},
+		"source":     bson.M{"$ne": models.ProductSourceManual},
+		"pinned":     bson.M{"$ne": true},
+	}
+	if _, err := collection.DeleteMany(ctx, filter); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-// IsValidProductID checks if a product ID exists
+// IsValidProductID checks if a product ID exists and is not retired
 func (s *store) IsValidProductID(ctx context.Context, productID string) (bool, error) {
 	collection := s.db.Collection("products")
 
-	count, err := collection.CountDocuments(ctx, bson.M{"product_id": productID})
+	filter := bson.M{
+		"product_id": productID,
+		"retired_at": bson.M{"$exists": false},
+	}
+
+	count, err := collection.CountDocuments(ctx, filter)
 	if err != nil {
 		return false, err
 	}
@@ -253,6 +431,118 @@ func (s *store) ensureIndexes() error {
 		return fmt.Errorf("failed to create index on votes collection: %v", err)
 	}
 
+	// Support the fraud detection heuristics and the moderation queue
+	_, err = votesCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "session_id", Value: 1}, {Key: "updated_at", Value: 1}}},
+		{Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "score", Value: 1}, {Key: "updated_at", Value: 1}}},
+		{Keys: bson.D{{Key: "fingerprint", Value: 1}, {Key: "updated_at", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "updated_at", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create fraud detection indexes on votes collection: %v", err)
+	}
+
+	// Support the retention rules
+	_, err = s.db.Collection("sessions").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "created_at", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create retention index on sessions collection: %v", err)
+	}
+
+	_, err = votesCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "updated_at", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create retention index on votes collection: %v", err)
+	}
+
+	// Ensure indexes on the erasure audit collection, erasures since the last similarity refresh are looked up by time
+	_, err = s.db.Collection("erasure_audit").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "session_id_hash", Value: 1}}},
+		{Keys: bson.D{{Key: "erased_at", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create index on erasure_audit collection: %v", err)
+	}
+
+	// Expire idempotency keys after their replay window
+	_, err = s.db.Collection("idempotency_keys").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create ttl index on idempotency_keys collection: %v", err)
+	}
+
+	// Support the outbox relay and expire published events after their retention
+	_, err = s.db.Collection("outbox_events").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "published_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "session_id", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create indexes on outbox_events collection: %v", err)
+	}
+
+	// Support the webhook dispatcher, delivery logs and session erasure, queue an event once per webhook
+	// and expire finished deliveries after their retention
+	_, err = s.db.Collection("webhook_deliveries").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "event_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "session_id", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create indexes on webhook_deliveries collection: %v", err)
+	}
+
+	// Support the lookup of votes changed since the last similarity index refresh
+	_, err = votesCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "reviewed_at", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "anonymized_at", Value: 1}}, Options: options.Index().SetSparse(true)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create change indexes on votes collection: %v", err)
+	}
+
+	// Support similar product lookups and the removal of stale similarities
+	_, err = s.db.Collection("product_similarities").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "similar_product_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "similarity", Value: -1}}},
+		{Keys: bson.D{{Key: "similar_product_id", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create indexes on product_similarities collection: %v", err)
+	}
+
+	// Support anomaly cooldowns and listings and expire anomalies after their retention
+	_, err = s.db.Collection("anomalies").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "direction", Value: 1}, {Key: "detected_at", Value: -1}}},
+		{Keys: bson.D{{Key: "detected_at", Value: -1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create indexes on anomalies collection: %v", err)
+	}
+
+	// Ensure unique experiment keys, find running experiments and the sessions assigned to their variants
+	_, err = s.db.Collection("experiments").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "status", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create indexes on experiments collection: %v", err)
+	}
+	_, err = s.db.Collection("sessions").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "variants.experiment", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create experiment index on sessions collection: %v", err)
+	}
+
 	// Ensure indexes on the products collection
 	productsCollection := s.db.Collection("products")
 	_, err = productsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
@@ -265,5 +555,17 @@ func (s *store) ensureIndexes() error {
 		return fmt.Errorf("failed to create index on products collection: %v", err)
 	}
 
+	// Ensure indexes on the api_keys collection
+	apiKeysCollection := s.db.Collection("api_keys")
+	_, err = apiKeysCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.M{
+			"hash": 1,
+		},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create index on api_keys collection: %v", err)
+	}
+
 	return nil
 }
@@ -0,0 +1,26 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"foover/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// StreamCountedVotesSince calls fn for every vote counted in the aggregated scores last updated at or after since.
+// The stream is bounded by ctx only, like StreamVotes.
+func (s *store) StreamCountedVotesSince(ctx context.Context, since time.Time, fn func(models.Vote) error) error {
+	filter := bson.M{
+		"updated_at": bson.M{"$gte": since},
+		"status":     bson.M{"$nin": bson.A{models.VoteStatusFlagged, models.VoteStatusRejected}},
+	}
+
+	cursor, err := s.db.Collection("votes").Find(ctx, filter, options.Find().SetBatchSize(streamBatchSize))
+	if err != nil {
+		return err
+	}
+
+	return stream(ctx, cursor, fn)
+}
@@ -0,0 +1,75 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"foover/internal/models"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SaveVotes stores or updates a batch of votes with a single bulk write and returns the messages
+// of the votes that failed, keyed by their index in the batch. An atomic batch is written in a transaction,
+// which requires a replica set, and either all votes are stored or an error is returned.
+func (s *store) SaveVotes(ctx context.Context, votes []models.Vote, atomic bool) (map[int]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	writes := saveVoteWrites(votes, time.Now())
+	collection := s.db.Collection("votes")
+
+	if atomic {
+		session, err := s.client.StartSession()
+		if err != nil {
+			return nil, err
+		}
+		defer session.EndSession(ctx)
+
+		_, err = session.WithTransaction(ctx, func(txCtx mongo.SessionContext) (interface{}, error) {
+			return collection.BulkWrite(txCtx, writes)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return map[int]string{}, nil
+	}
+
+	failed := make(map[int]string)
+	_, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		for _, writeErr := range bulkErr.WriteErrors {
+			failed[writeErr.Index] = writeErr.Message
+		}
+		return failed, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return failed, nil
+}
+
+// SaveVotesWithEvents stores or updates a batch of votes like SaveVotes and writes the event of each vote to the outbox
+// in the same transaction, events[i] describing votes[i]. Failed votes don't write their event.
+func (s *store) SaveVotesWithEvents(ctx context.Context, votes []models.Vote, events []models.OutboxEvent, atomic bool) (map[int]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	return s.writeVotesWithEvents(ctx, saveVoteWrites(votes, time.Now()), events, atomic)
+}
+
+// saveVoteWrites returns the upserts storing votes saved at now
+func saveVoteWrites(votes []models.Vote, now time.Time) []mongo.WriteModel {
+	writes := make([]mongo.WriteModel, 0, len(votes))
+	for _, vote := range votes {
+		filter, update := voteUpsert(vote, now)
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(filter).
+			SetUpdate(update).
+			SetUpsert(true))
+	}
+	return writes
+}
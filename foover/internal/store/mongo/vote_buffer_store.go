@@ -0,0 +1,60 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+
+	"foover/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReplayVotes upserts votes that were buffered while the store was unavailable with an unordered bulk write.
+// Buffered votes carry the time they were cast in UpdatedAt and only replace a stored vote of the same session
+// and product if they are newer, so a vote cast after the outage wins over the buffered one and replaying
+// a batch again changes nothing. It returns the messages of the votes that failed, keyed by their index in the batch.
+func (s *store) ReplayVotes(ctx context.Context, votes []models.Vote) (map[int]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	failed := make(map[int]string)
+	_, err := s.db.Collection("votes").BulkWrite(ctx, replayVoteWrites(votes), options.BulkWrite().SetOrdered(false))
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		for _, writeErr := range bulkErr.WriteErrors {
+			failed[writeErr.Index] = writeErr.Message
+		}
+		return failed, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return failed, nil
+}
+
+// ReplayVotesWithEvents replays buffered votes like ReplayVotes and writes the event of each vote to the outbox
+// in one transaction, events[i] describing votes[i]. Votes superseded by a newer stored vote change nothing
+// but still write their event, which carries the time the vote was cast.
+func (s *store) ReplayVotesWithEvents(ctx context.Context, votes []models.Vote, events []models.OutboxEvent) (map[int]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	return s.writeVotesWithEvents(ctx, replayVoteWrites(votes), events, false)
+}
+
+// replayVoteWrites returns the upserts storing buffered votes unless the stored votes are newer
+func replayVoteWrites(votes []models.Vote) []mongo.WriteModel {
+	writes := make([]mongo.WriteModel, 0, len(votes))
+	for _, vote := range votes {
+		writes = append(writes, upsertIfNewer(vote, bson.D{
+			{Key: "score", Value: vote.Score},
+			{Key: "status", Value: vote.Status},
+			{Key: "fraud_score", Value: vote.FraudScore},
+			{Key: "fraud_reasons", Value: vote.FraudReasons},
+			{Key: "fingerprint", Value: vote.Fingerprint},
+		}, "reviewed_at"))
+	}
+	return writes
+}
@@ -0,0 +1,128 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"foover/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ImportVotes upserts a batch of imported votes and their sessions with unordered bulk writes.
+// Imported votes carry their original timestamp in UpdatedAt and only replace a stored vote of the same session
+// and product if they are newer, so re-running an import or importing older data never overrides newer votes.
+// Sessions unknown to the store are created with the timestamp of their oldest vote.
+// It returns the messages of the votes that failed, keyed by their index in the batch.
+func (s *store) ImportVotes(ctx context.Context, votes []models.Vote) (map[int]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	if err := s.importSessions(ctx, votes); err != nil {
+		return nil, err
+	}
+
+	failed := make(map[int]string)
+	_, err := s.db.Collection("votes").BulkWrite(ctx, importVoteWrites(votes), options.BulkWrite().SetOrdered(false))
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		for _, writeErr := range bulkErr.WriteErrors {
+			failed[writeErr.Index] = writeErr.Message
+		}
+		return failed, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return failed, nil
+}
+
+// ImportVotesWithEvents imports a batch of votes like ImportVotes and writes the event of each vote to the outbox
+// in one transaction, events[i] describing votes[i]. Sessions are created before the transaction, as creating them
+// again is harmless. Votes older than the stored vote change nothing but still write their event, which carries
+// the original timestamp so consumers can tell it apart from the stored vote.
+func (s *store) ImportVotesWithEvents(ctx context.Context, votes []models.Vote, events []models.OutboxEvent) (map[int]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	if err := s.importSessions(ctx, votes); err != nil {
+		return nil, err
+	}
+
+	return s.writeVotesWithEvents(ctx, importVoteWrites(votes), events, false)
+}
+
+// importSessions creates the sessions of imported votes unknown to the store with the timestamp of their oldest vote
+func (s *store) importSessions(ctx context.Context, votes []models.Vote) error {
+	firstVotes := make(map[string]time.Time)
+	for _, vote := range votes {
+		if first, ok := firstVotes[vote.SessionID]; !ok || vote.UpdatedAt.Before(first) {
+			firstVotes[vote.SessionID] = vote.UpdatedAt
+		}
+	}
+
+	sessionWrites := make([]mongo.WriteModel, 0, len(firstVotes))
+	for sessionID, first := range firstVotes {
+		sessionWrites = append(sessionWrites, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"session_id": sessionID}).
+			SetUpdate(bson.M{"$min": bson.M{"created_at": first}}).
+			SetUpsert(true))
+	}
+	_, err := s.db.Collection("sessions").BulkWrite(ctx, sessionWrites, options.BulkWrite().SetOrdered(false))
+	if err != nil && !isDuplicateKeyOnly(err) {
+		return err
+	}
+	return nil
+}
+
+// importVoteWrites returns the upserts storing imported votes unless the stored votes are newer
+func importVoteWrites(votes []models.Vote) []mongo.WriteModel {
+	writes := make([]mongo.WriteModel, 0, len(votes))
+	for _, vote := range votes {
+		writes = append(writes, upsertIfNewer(vote, bson.D{
+			{Key: "score", Value: vote.Score},
+			{Key: "status", Value: vote.Status},
+		}))
+	}
+	return writes
+}
+
+// upsertIfNewer returns a write setting the fields of the vote of its session and product to the given values
+// and unsetting the fields listed in unset, unless the stored vote was updated after vote.UpdatedAt
+func upsertIfNewer(vote models.Vote, fields bson.D, unset ...string) mongo.WriteModel {
+	isNewer := bson.D{{Key: "$lt", Value: bson.A{bson.D{{Key: "$ifNull", Value: bson.A{"$updated_at", time.Time{}}}}, vote.UpdatedAt}}}
+	keepOrSet := func(field string, value interface{}) bson.E {
+		return bson.E{Key: field, Value: bson.D{{Key: "$cond", Value: bson.A{isNewer, value, "$" + field}}}}
+	}
+
+	set := bson.D{keepOrSet("updated_at", vote.UpdatedAt)}
+	for _, field := range fields {
+		set = append(set, keepOrSet(field.Key, bson.D{{Key: "$literal", Value: field.Value}}))
+	}
+	for _, field := range unset {
+		set = append(set, keepOrSet(field, "$$REMOVE"))
+	}
+
+	return mongo.NewUpdateOneModel().
+		SetFilter(bson.M{"session_id": vote.SessionID, "product_id": vote.ProductID}).
+		SetUpdate(mongo.Pipeline{{{Key: "$set", Value: set}}}).
+		SetUpsert(true)
+}
+
+// isDuplicateKeyOnly reports whether err is a bulk write error caused by duplicate keys only,
+// which concurrent upserts of the same document run into
+func isDuplicateKeyOnly(err error) bool {
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return false
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		if !mongo.IsDuplicateKeyError(writeErr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"foover/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CountVotesBySessionSince counts the votes a session cast or updated since the given time
+func (s *store) CountVotesBySessionSince(ctx context.Context, sessionID string, since time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	filter := bson.M{
+		"session_id": sessionID,
+		"updated_at": bson.M{"$gte": since},
+	}
+
+	return s.db.Collection("votes").CountDocuments(ctx, filter)
+}
+
+// CountMatchingVotesSince counts the votes giving a product the same score since the given time
+func (s *store) CountMatchingVotesSince(ctx context.Context, productID string, score int, since time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	filter := bson.M{
+		"product_id": productID,
+		"score":      score,
+		"updated_at": bson.M{"$gte": since},
+	}
+
+	return s.db.Collection("votes").CountDocuments(ctx, filter)
+}
+
+// CountSessionsByFingerprintSince counts the distinct sessions that voted from a client fingerprint since the given time
+func (s *store) CountSessionsByFingerprintSince(ctx context.Context, fingerprint string, since time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	filter := bson.M{
+		"fingerprint": fingerprint,
+		"updated_at":  bson.M{"$gte": since},
+	}
+
+	sessionIDs, err := s.db.Collection("votes").Distinct(ctx, "session_id", filter)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(sessionIDs)), nil
+}
+
+// GetVotesByStatus retrieves up to limit votes with the given moderation status, oldest first
+func (s *store) GetVotesByStatus(ctx context.Context, status string, limit int64) ([]models.Vote, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: 1}}).
+		SetLimit(limit)
+
+	cursor, err := s.db.Collection("votes").Find(ctx, bson.M{"status": status}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var votes []models.Vote
+	if err := cursor.All(ctx, &votes); err != nil {
+		return nil, err
+	}
+
+	return votes, nil
+}
+
+// ReviewFlaggedVote records the review of a flagged vote and returns the updated vote,
+// votes that aren't flagged are reported as not found so a review can't override a newer vote
+func (s *store) ReviewFlaggedVote(ctx context.Context, id string, status string) (models.Vote, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	return s.reviewFlaggedVote(ctx, id, status)
+}
+
+// ReviewFlaggedVoteWithEvent records the review of a flagged vote like ReviewFlaggedVote and writes the event
+// newEvent creates from the updated vote to the outbox in the same transaction, which requires a replica set
+func (s *store) ReviewFlaggedVoteWithEvent(ctx context.Context, id string, status string, newEvent func(vote models.Vote) (models.OutboxEvent, error)) (models.Vote, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	session, err := s.client.StartSession()
+	if err != nil {
+		return models.Vote{}, err
+	}
+	defer session.EndSession(ctx)
+
+	reviewed, err := session.WithTransaction(ctx, func(txCtx mongo.SessionContext) (interface{}, error) {
+		vote, err := s.reviewFlaggedVote(txCtx, id, status)
+		if err != nil {
+			return nil, err
+		}
+		event, err := newEvent(vote)
+		if err != nil {
+			return nil, err
+		}
+		return vote, s.insertOutboxEvents(txCtx, []models.OutboxEvent{event})
+	})
+	if err != nil {
+		return models.Vote{}, err
+	}
+
+	return reviewed.(models.Vote), nil
+}
+
+// reviewFlaggedVote sets the review status of a flagged vote and returns the updated vote
+func (s *store) reviewFlaggedVote(ctx context.Context, id string, status string) (models.Vote, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.Vote{}, ErrNotFound
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":      status,
+			"reviewed_at": time.Now(),
+		},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var vote models.Vote
+	err = s.db.Collection("votes").FindOneAndUpdate(ctx, bson.M{"_id": objectID, "status": models.VoteStatusFlagged}, update, opts).Decode(&vote)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Vote{}, ErrNotFound
+	}
+	if err != nil {
+		return models.Vote{}, err
+	}
+
+	return vote, nil
+}
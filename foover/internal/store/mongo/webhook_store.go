@@ -0,0 +1,350 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"foover/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxLoggedWebhookAttempts bounds the attempts kept in the log of a delivery redelivered many times
+const maxLoggedWebhookAttempts = 50
+
+// CreateWebhook stores a new webhook and returns it with its generated ID
+func (s *store) CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	result, err := s.db.Collection("webhooks").InsertOne(ctx, webhook)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	webhook.ID = result.InsertedID.(primitive.ObjectID)
+	return webhook, nil
+}
+
+// GetWebhooks retrieves all webhooks
+func (s *store) GetWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	cursor, err := s.db.Collection("webhooks").Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var webhooks []models.Webhook
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// GetWebhooksByEvent retrieves the active webhooks subscribed to an event type
+func (s *store) GetWebhooksByEvent(ctx context.Context, eventType string) ([]models.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	cursor, err := s.db.Collection("webhooks").Find(ctx, bson.M{"events": eventType, "active": true})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var webhooks []models.Webhook
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// GetWebhook retrieves the webhook with the given ID
+func (s *store) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.Webhook{}, ErrNotFound
+	}
+
+	var webhook models.Webhook
+	err = s.db.Collection("webhooks").FindOne(ctx, bson.M{"_id": objectID}).Decode(&webhook)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Webhook{}, ErrNotFound
+	}
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+// UpdateWebhook replaces the subscription of a webhook and returns the updated webhook, its secret is kept
+func (s *store) UpdateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"url":         webhook.URL,
+			"events":      webhook.Events,
+			"product_ids": webhook.ProductIDs,
+			"active":      webhook.Active,
+			"updated_at":  time.Now(),
+		},
+	}
+
+	var updated models.Webhook
+	err := s.db.Collection("webhooks").FindOneAndUpdate(ctx, bson.M{"_id": webhook.ID}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Webhook{}, ErrNotFound
+	}
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	return updated, nil
+}
+
+// DeleteWebhook deletes the webhook with the given ID along with its deliveries
+func (s *store) DeleteWebhook(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrNotFound
+	}
+
+	result, err := s.db.Collection("webhooks").DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	_, err = s.db.Collection("webhook_deliveries").DeleteMany(ctx, bson.M{"webhook_id": objectID})
+	return err
+}
+
+// CreateWebhookDeliveries stores deliveries to attempt, deliveries of an event already queued for their webhook are skipped
+func (s *store) CreateWebhookDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	documents := make([]interface{}, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		documents = append(documents, delivery)
+	}
+
+	_, err := s.db.Collection("webhook_deliveries").InsertMany(ctx, documents, options.InsertMany().SetOrdered(false))
+	if err != nil && !isDuplicateKeyOnly(err) {
+		return err
+	}
+	return nil
+}
+
+// ClaimWebhookDelivery takes the pending delivery due the longest and pushes its next attempt to lockedUntil,
+// so other replicas skip it while it is sent and pick it up again if this one dies. It returns ErrNotFound if none is due.
+func (s *store) ClaimWebhookDelivery(ctx context.Context, lockedUntil time.Time) (models.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	filter := bson.M{
+		"status":          models.WebhookDeliveryPending,
+		"next_attempt_at": bson.M{"$lte": time.Now()},
+	}
+	update := bson.M{"$set": bson.M{"next_attempt_at": lockedUntil}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var delivery models.WebhookDelivery
+	err := s.db.Collection("webhook_deliveries").FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.WebhookDelivery{}, ErrNotFound
+	}
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	return delivery, nil
+}
+
+// RecordWebhookAttempt appends an attempt to the log of a delivery and stores its resulting status,
+// next attempt and expiry
+func (s *store) RecordWebhookAttempt(ctx context.Context, delivery models.WebhookDelivery, attempt models.WebhookAttempt) error {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	set := bson.M{
+		"status":          delivery.Status,
+		"failed_attempts": delivery.FailedAttempts,
+		"updated_at":      time.Now(),
+	}
+	unset := bson.M{}
+	if delivery.NextAttemptAt != nil {
+		set["next_attempt_at"] = *delivery.NextAttemptAt
+	} else {
+		unset["next_attempt_at"] = ""
+	}
+	if delivery.ExpiresAt != nil {
+		set["expires_at"] = *delivery.ExpiresAt
+	} else {
+		unset["expires_at"] = ""
+	}
+
+	update := bson.M{
+		"$set":  set,
+		"$push": bson.M{"attempts": bson.M{"$each": bson.A{attempt}, "$slice": -maxLoggedWebhookAttempts}},
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	result, err := s.db.Collection("webhook_deliveries").UpdateOne(ctx, bson.M{"_id": delivery.ID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveries retrieves up to limit deliveries, newest first, of a webhook unless webhookID is empty
+// and with a status unless status is empty
+func (s *store) GetWebhookDeliveries(ctx context.Context, webhookID string, status string, limit int64) ([]models.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.sessionTimeout)
+	defer cancel()
+
+	filter := bson.M{}
+	if webhookID != "" {
+		objectID, err := primitive.ObjectIDFromHex(webhookID)
+		if err != nil {
+			return nil, ErrNotFound
+		}
+		filter["webhook_id"] = objectID
+	}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetLimit(limit)
+
+	cursor, err := s.db.Collection("webhook_deliveries").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var deliveries []models.WebhookDelivery
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// RedeliverWebhookDelivery schedules a dead letter for another round of attempts right away
+func (s *store) RedeliverWebhookDelivery(ctx context.Context, id string) (models.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.WebhookDelivery{}, ErrNotFound
+	}
+
+	now := time.Now()
+	filter := bson.M{"_id": objectID, "status": models.WebhookDeliveryDead}
+	update := bson.M{
+		"$set":   bson.M{"status": models.WebhookDeliveryPending, "failed_attempts": 0, "next_attempt_at": now, "updated_at": now},
+		"$unset": bson.M{"expires_at": ""},
+	}
+
+	var delivery models.WebhookDelivery
+	err = s.db.Collection("webhook_deliveries").FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&delivery)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.WebhookDelivery{}, ErrNotFound
+	}
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	return delivery, nil
+}
+
+// SetProductAlert records whether an alert of a product is raised and reports whether that changed.
+// Concurrent calls raising the same alert report the change once.
+func (s *store) SetProductAlert(ctx context.Context, productID string, alert string, raised bool) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.voteTimeout)
+	defer cancel()
+
+	collection := s.db.Collection("product_alerts")
+	id := productID + ":" + alert
+	update := bson.M{"$set": bson.M{"raised": raised, "updated_at": time.Now()}}
+
+	if !raised {
+		result, err := collection.UpdateOne(ctx, bson.M{"_id": id, "raised": true}, update)
+		if err != nil {
+			return false, err
+		}
+		return result.ModifiedCount > 0, nil
+	}
+
+	// A raised alert doesn't match the filter, so the upsert collides with its _id
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id, "raised": bson.M{"$ne": true}}, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetProductScore retrieves the aggregated score of a product, ErrNotFound is returned if no vote counts
+func (s *store) GetProductScore(ctx context.Context, productID string) (models.ProductScore, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.aggregateTimeout)
+	defer cancel()
+
+	pipeline := append(mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "product_id", Value: productID}}}},
+	}, aggregatedScoresPipeline()...)
+
+	cursor, err := s.db.Collection("votes").Aggregate(ctx, pipeline)
+	if err != nil {
+		return models.ProductScore{}, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []models.ProductScore
+	if err := cursor.All(ctx, &results); err != nil {
+		return models.ProductScore{}, err
+	}
+	if len(results) == 0 {
+		return models.ProductScore{}, ErrNotFound
+	}
+
+	return results[0], nil
+}
@@ -0,0 +1,23 @@
+// Package testutil provides fixtures shared by tests
+package testutil
+
+import (
+	"fmt"
+	"time"
+
+	"foover/internal/models"
+)
+
+// Votes returns votes of a product with the given scores, cast by the sessions s0, s1... in order and last updated at updatedAt
+func Votes(productID string, updatedAt time.Time, scores ...int) []models.Vote {
+	votes := make([]models.Vote, 0, len(scores))
+	for i, score := range scores {
+		votes = append(votes, models.Vote{
+			SessionID: fmt.Sprintf("s%d", i),
+			ProductID: productID,
+			Score:     score,
+			UpdatedAt: updatedAt,
+		})
+	}
+	return votes
+}
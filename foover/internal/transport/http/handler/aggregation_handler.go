@@ -1,19 +1,24 @@
 package handler
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"foover/internal/models"
 	"foover/internal/service"
 	"log/slog"
 	"net/http"
+	"strings"
 )
 
 // GetAggregatedScoresHandler retrieves aggregated product scores
 // @Summary Get aggregated product scores
-// @Description Retrieves aggregated average scores for products across all session IDs.
+// @Description Retrieves aggregated average scores for products across all session IDs. Responses are cached briefly and carry an ETag for conditional requests.
 // @Tags aggregation
 // @Produce json
+// @Param If-None-Match header string false "ETag of a previous response, answered with 304 if the scores are unchanged"
 // @Success 200 {object} models.GetAggregatedScoresResponse
+// @Success 304 "Scores unchanged since the response with the given ETag"
 // @Failure 500 {object} models.ErrorResponse
 // @Router /aggregated-scores [get]
 func GetAggregatedScoresHandler(aggregationService service.AggregationService, logger *slog.Logger) http.HandlerFunc {
@@ -21,7 +26,7 @@ func GetAggregatedScoresHandler(aggregationService service.AggregationService, l
 		ctx := r.Context()
 		scores, err := aggregationService.GetAggregatedProductScores(ctx)
 		if err != nil {
-			logger.Error("Failed to get aggregated scores", "error", err)
+			logger.ErrorContext(ctx, "Failed to get aggregated scores", "error", err)
 			writeErrorResponse(w, http.StatusInternalServerError, "Failed to get aggregated scores")
 			return
 		}
@@ -35,8 +40,43 @@ func GetAggregatedScoresHandler(aggregationService service.AggregationService, l
 			Scores: scores,
 		}
 
+		body, err := json.Marshal(response)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to encode aggregated scores", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to get aggregated scores")
+			return
+		}
+
+		// Clients revalidate with If-None-Match and skip the download while the scores are unchanged
+		etag := entityTag(body)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache")
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			logger.InfoContext(ctx, "Aggregated scores not modified")
+			return
+		}
+
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
-		logger.Info("Successfully retrieved and sent aggregated scores")
+		w.Write(append(body, '\n'))
+		logger.InfoContext(ctx, "Successfully retrieved and sent aggregated scores")
+	}
+}
+
+// entityTag returns a strong ETag identifying the representation in body
+func entityTag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header lists etag or is a wildcard,
+// weak tags match as the comparison for GET requests is weak
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
 	}
+	return false
 }
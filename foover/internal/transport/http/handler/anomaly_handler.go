@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/json"
+	"foover/internal/models"
+	"foover/internal/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultAnomaliesLimit = 100
+	maxAnomaliesLimit     = 1000
+)
+
+// GetAnomaliesHandler retrieves detected score anomalies
+// @Summary List score anomalies
+// @Description Retrieves the anomalies found by the detection job, newest first. An anomaly is recorded when the average score of a product over the last ANOMALY_WINDOW lies at least ANOMALY_Z_THRESHOLD standard errors away from its average over the ANOMALY_BASELINE_WINDOW before it. Anomalies are also sent to webhooks subscribed to score.anomaly.
+// @Tags votes
+// @Produce json
+// @Security ApiKeyAuth
+// @Param product_id query string false "Only anomalies of this product"
+// @Param direction query string false "Only anomalies in this direction (drop, spike)"
+// @Param since query string false "Only anomalies detected at or after this RFC 3339 time"
+// @Param limit query int false "Maximum number of anomalies to return (default 100, max 1000)"
+// @Success 200 {object} models.GetAnomaliesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /anomalies [get]
+func GetAnomaliesHandler(anomalyService service.AnomalyService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		query := r.URL.Query()
+
+		direction := query.Get("direction")
+		switch direction {
+		case "", models.AnomalyDrop, models.AnomalySpike:
+		default:
+			logger.WarnContext(ctx, "Invalid anomaly direction", "direction", direction)
+			writeErrorResponse(w, http.StatusBadRequest, "direction must be one of drop, spike")
+			return
+		}
+
+		var since time.Time
+		if raw := query.Get("since"); raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				logger.WarnContext(ctx, "Invalid since", "since", raw)
+				writeErrorResponse(w, http.StatusBadRequest, "since must be an RFC 3339 time")
+				return
+			}
+			since = parsed
+		}
+
+		limit := int64(defaultAnomaliesLimit)
+		if raw := query.Get("limit"); raw != "" {
+			parsed, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || parsed < 1 || parsed > maxAnomaliesLimit {
+				logger.WarnContext(ctx, "Invalid limit", "limit", raw)
+				writeErrorResponse(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+				return
+			}
+			limit = parsed
+		}
+
+		anomalies, err := anomalyService.GetAnomalies(ctx, query.Get("product_id"), direction, since, limit)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to get anomalies", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to get anomalies")
+			return
+		}
+		if anomalies == nil {
+			anomalies = []models.Anomaly{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.GetAnomaliesResponse{Anomalies: anomalies})
+		logger.InfoContext(ctx, "Successfully retrieved anomalies", "anomalies", len(anomalies))
+	}
+}
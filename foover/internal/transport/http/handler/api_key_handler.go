@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"foover/internal/models"
+	"foover/internal/service"
+	"foover/internal/store/mongo"
+	"foover/internal/validation"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"net/http"
+)
+
+// CreateAPIKeyHandler handles API key creation
+// @Summary Create an API key
+// @Description Issues a new API key with the given scopes. The key is only returned in this response.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param apiKey body models.CreateAPIKeyRequest true "API key creation request"
+// @Success 201 {object} models.CreateAPIKeyResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/api-keys [post]
+func CreateAPIKeyHandler(authService service.AuthService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var req models.CreateAPIKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.ErrorContext(ctx, "Invalid request payload", "error", err)
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		if err := validation.Struct(req); err != nil {
+			logger.ErrorContext(ctx, "Validation failed", "error", err)
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		key, apiKey, err := authService.CreateAPIKey(ctx, req.Name, req.Scopes)
+		if errors.Is(err, service.ErrInvalidScope) {
+			logger.WarnContext(ctx, "Invalid API key scope", "error", err)
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to create API key", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to create API key")
+			return
+		}
+
+		response := models.CreateAPIKeyResponse{
+			Key:    key,
+			APIKey: apiKey,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+		logger.InfoContext(ctx, "Successfully created API key", "apiKeyID", apiKey.ID.Hex(), "scopes", apiKey.Scopes)
+	}
+}
+
+// GetAPIKeysHandler retrieves all API keys
+// @Summary List API keys
+// @Description Retrieves the metadata of all API keys, including revoked ones.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.GetAPIKeysResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/api-keys [get]
+func GetAPIKeysHandler(authService service.AuthService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		apiKeys, err := authService.GetAPIKeys(ctx)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to get API keys", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to get API keys")
+			return
+		}
+
+		// Returning empty array if no API keys found
+		if apiKeys == nil {
+			apiKeys = []models.APIKey{}
+		}
+
+		response := models.GetAPIKeysResponse{
+			APIKeys: apiKeys,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// RevokeAPIKeyHandler revokes an API key
+// @Summary Revoke an API key
+// @Description Revokes the API key with the given ID, it is rejected from then on.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "The API key ID"
+// @Success 204
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/api-keys/{id} [delete]
+func RevokeAPIKeyHandler(authService service.AuthService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id := mux.Vars(r)["id"]
+
+		err := authService.RevokeAPIKey(ctx, id)
+		if errors.Is(err, mongo.ErrNotFound) {
+			logger.WarnContext(ctx, "API key not found", "apiKeyID", id)
+			writeErrorResponse(w, http.StatusNotFound, "API key not found")
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to revoke API key", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to revoke API key")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		logger.InfoContext(ctx, "Successfully revoked API key", "apiKeyID", id)
+	}
+}
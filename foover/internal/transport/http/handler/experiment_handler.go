@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"foover/internal/models"
+	"foover/internal/service"
+	"foover/internal/store/mongo"
+	"foover/internal/validation"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"net/http"
+)
+
+// CreateExperimentHandler handles experiment creation
+// @Summary Create an experiment
+// @Description Starts an A/B experiment on the presentation of products. Every session created while it runs is assigned to one of its variants in proportion to their weights, deterministically from the session ID, and gets the variant in the session creation response. The first variant is the control.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param experiment body models.CreateExperimentRequest true "Experiment creation request"
+// @Success 201 {object} models.Experiment
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/experiments [post]
+func CreateExperimentHandler(experimentService service.ExperimentService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var req models.CreateExperimentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.ErrorContext(ctx, "Invalid request payload", "error", err)
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		if err := validation.Struct(req); err != nil {
+			logger.ErrorContext(ctx, "Validation failed", "error", err)
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		experiment, err := experimentService.CreateExperiment(ctx, req)
+		if errors.Is(err, service.ErrInvalidExperiment) {
+			logger.WarnContext(ctx, "Invalid experiment", "error", err)
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, mongo.ErrDuplicate) {
+			logger.WarnContext(ctx, "Experiment already exists", "experiment", req.Key)
+			writeErrorResponse(w, http.StatusConflict, "Experiment already exists")
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to create experiment", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to create experiment")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(experiment)
+		logger.InfoContext(ctx, "Successfully created experiment", "experiment", experiment.Key)
+	}
+}
+
+// GetExperimentsHandler retrieves all experiments
+// @Summary List experiments
+// @Description Retrieves all experiments, running or stopped, newest first.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.GetExperimentsResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/experiments [get]
+func GetExperimentsHandler(experimentService service.ExperimentService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		experiments, err := experimentService.GetExperiments(ctx)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to get experiments", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to get experiments")
+			return
+		}
+
+		// Returning empty array if no experiments found
+		if experiments == nil {
+			experiments = []models.Experiment{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.GetExperimentsResponse{Experiments: experiments})
+	}
+}
+
+// GetExperimentHandler retrieves an experiment
+// @Summary Get an experiment
+// @Description Retrieves the experiment with the given key.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param key path string true "The experiment key"
+// @Success 200 {object} models.Experiment
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/experiments/{key} [get]
+func GetExperimentHandler(experimentService service.ExperimentService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		experiment, err := experimentService.GetExperiment(r.Context(), mux.Vars(r)["key"])
+		writeExperimentResult(w, r, experiment, err, "get", logger)
+	}
+}
+
+// StopExperimentHandler stops an experiment
+// @Summary Stop an experiment
+// @Description Stops assigning new sessions to the variants of an experiment. Sessions keep their variants and the analysis only counts votes cast until the experiment stopped. Stopping a stopped experiment has no effect.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param key path string true "The experiment key"
+// @Success 200 {object} models.Experiment
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/experiments/{key}/stop [post]
+func StopExperimentHandler(experimentService service.ExperimentService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		experiment, err := experimentService.StopExperiment(r.Context(), mux.Vars(r)["key"])
+		writeExperimentResult(w, r, experiment, err, "stop", logger)
+	}
+}
+
+// GetExperimentAnalysisHandler compares the variants of an experiment
+// @Summary Analyze an experiment
+// @Description Compares the counted votes cast on the products of an experiment while it ran by the sessions assigned to each variant. Each other variant is compared to the control with Welch's t-test on the average scores of the voting sessions, at EXPERIMENT_SIGNIFICANCE_LEVEL split across the comparisons. Variants with fewer than EXPERIMENT_MIN_SESSIONS voting sessions aren't tested.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param key path string true "The experiment key"
+// @Success 200 {object} models.GetExperimentAnalysisResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/experiments/{key}/analysis [get]
+func GetExperimentAnalysisHandler(experimentService service.ExperimentService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		key := mux.Vars(r)["key"]
+
+		analysis, err := experimentService.AnalyzeExperiment(ctx, key)
+		if errors.Is(err, mongo.ErrNotFound) {
+			logger.WarnContext(ctx, "Experiment not found", "experiment", key)
+			writeErrorResponse(w, http.StatusNotFound, "Experiment not found")
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to analyze experiment", "experiment", key, "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to analyze experiment")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(analysis)
+		logger.InfoContext(ctx, "Successfully analyzed experiment", "experiment", key)
+	}
+}
+
+// writeExperimentResult writes an experiment or the matching error response
+func writeExperimentResult(w http.ResponseWriter, r *http.Request, experiment models.Experiment, err error, operation string, logger *slog.Logger) {
+	ctx := r.Context()
+	if errors.Is(err, mongo.ErrNotFound) {
+		logger.WarnContext(ctx, "Experiment not found", "experiment", mux.Vars(r)["key"])
+		writeErrorResponse(w, http.StatusNotFound, "Experiment not found")
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to "+operation+" experiment", "error", err)
+		writeErrorResponse(w, http.StatusInternalServerError, "Failed to "+operation+" experiment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(experiment)
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"foover/internal/export"
+	"foover/internal/service"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// ExportVotesHandler streams all raw votes
+// @Summary Export votes
+// @Description Streams all raw votes as CSV, NDJSON or Parquet. Client fingerprints are not exported.
+// @Tags export
+// @Produce text/csv,application/x-ndjson,application/vnd.apache.parquet
+// @Security ApiKeyAuth
+// @Param format query string false "Export format" Enums(csv, ndjson, parquet) default(csv)
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /export/votes [get]
+func ExportVotesHandler(exportService service.ExportService, logger *slog.Logger) http.HandlerFunc {
+	return exportHandler("votes", exportService.ExportVotes, logger)
+}
+
+// ExportScoresHandler streams the aggregated product scores
+// @Summary Export aggregated scores
+// @Description Streams the aggregated product scores as CSV, NDJSON or Parquet.
+// @Tags export
+// @Produce text/csv,application/x-ndjson,application/vnd.apache.parquet
+// @Param format query string false "Export format" Enums(csv, ndjson, parquet) default(csv)
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /export/scores [get]
+func ExportScoresHandler(exportService service.ExportService, logger *slog.Logger) http.HandlerFunc {
+	return exportHandler("scores", exportService.ExportScores, logger)
+}
+
+// exportHandler streams the export produced by exportFn in the format requested by the format query parameter
+func exportHandler(name string, exportFn func(ctx context.Context, format string, w io.Writer) (int64, error), logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = export.FormatCSV
+		}
+		if !export.IsValidFormat(format) {
+			logger.WarnContext(ctx, "Invalid export format", "format", format)
+			writeErrorResponse(w, http.StatusBadRequest, "format must be one of csv, ndjson, parquet")
+			return
+		}
+
+		// Exports of large collections outlast the server write timeout, the client disconnecting cancels them
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			logger.WarnContext(ctx, "Failed to lift write deadline for export", "error", err)
+		}
+
+		w.Header().Set("Content-Type", export.ContentType(format))
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.`+format+`"`)
+
+		tw := &trackingWriter{ResponseWriter: w}
+		count, err := exportFn(ctx, format, tw)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to export "+name, "format", format, "rows", count, "error", err)
+			if !tw.written {
+				w.Header().Del("Content-Disposition")
+				writeErrorResponse(w, http.StatusInternalServerError, "Failed to export "+name)
+				return
+			}
+			// The status line is already sent, aborting the response tells the client the export is incomplete
+			panic(http.ErrAbortHandler)
+		}
+
+		logger.InfoContext(ctx, "Successfully exported "+name, "format", format, "rows", count)
+	}
+}
+
+// trackingWriter records whether anything was written to the response, after which the status can't change
+type trackingWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+// WriteHeader records that the status line is sent
+func (tw *trackingWriter) WriteHeader(code int) {
+	tw.written = true
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+// Write records that the response is started
+func (tw *trackingWriter) Write(b []byte) (int, error) {
+	tw.written = true
+	return tw.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the underlying ResponseWriter to http.ResponseController
+func (tw *trackingWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
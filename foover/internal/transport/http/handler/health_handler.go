@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"encoding/json"
+	"foover/internal/health"
+	"foover/internal/models"
+	"log/slog"
+	"net/http"
+)
+
+// LivenessHandler reports whether the process is alive
+// @Summary Liveness probe
+// @Description Reports that the process is alive and able to serve HTTP requests.
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.HealthResponse
+// @Router /healthz [get]
+func LivenessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.HealthResponse{Status: health.StatusUp})
+	}
+}
+
+// ReadinessHandler reports whether the service is ready to receive traffic
+// @Summary Readiness probe
+// @Description Reports whether all registered dependency checks pass and the service is not shutting down. A service with a failed dependency covered by a fallback is degraded but ready.
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.HealthResponse
+// @Failure 503 {object} models.HealthResponse
+// @Router /readyz [get]
+func ReadinessHandler(healthRegistry health.Registry, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		report := healthRegistry.Check(ctx)
+		switch report.Status {
+		case health.StatusDown:
+			logger.WarnContext(ctx, "Service is not ready", "shuttingDown", report.ShuttingDown)
+		case health.StatusDegraded:
+			logger.WarnContext(ctx, "Service is degraded")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(healthStatusCode(report))
+		json.NewEncoder(w).Encode(models.HealthResponse{Status: report.Status})
+	}
+}
+
+// StatusHandler reports the detailed status of the service dependencies
+// @Summary Detailed service status
+// @Description Runs all registered dependency checks and reports their status and latencies.
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.GetStatusResponse
+// @Failure 503 {object} models.GetStatusResponse
+// @Router /status [get]
+func StatusHandler(healthRegistry health.Registry, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		report := healthRegistry.Check(ctx)
+
+		checks := make([]models.HealthCheckResponse, 0, len(report.Checks))
+		for _, result := range report.Checks {
+			if result.Status != health.StatusUp {
+				logger.WarnContext(ctx, "Health check failed", "check", result.Name, "error", result.Error)
+			}
+			checks = append(checks, models.HealthCheckResponse{
+				Name:      result.Name,
+				Status:    result.Status,
+				LatencyMs: float64(result.Latency.Microseconds()) / 1000,
+				Error:     result.Error,
+				Details:   result.Details,
+			})
+		}
+
+		response := models.GetStatusResponse{
+			Status:       report.Status,
+			ShuttingDown: report.ShuttingDown,
+			Checks:       checks,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(healthStatusCode(report))
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// healthStatusCode fails the probes only if the service is down, a degraded service keeps receiving traffic
+func healthStatusCode(report health.Report) int {
+	if report.Status == health.StatusDown {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
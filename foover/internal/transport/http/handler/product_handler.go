@@ -0,0 +1,314 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"foover/internal/models"
+	"foover/internal/service"
+	"foover/internal/store/mongo"
+	"foover/internal/validation"
+	"github.com/gorilla/mux"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxImportBodyBytes bounds the size of bulk import payloads
+const maxImportBodyBytes = 10 << 20
+
+// GetProductsHandler retrieves all products
+// @Summary List products
+// @Description Retrieves all products, including retired ones.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.GetProductsResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/products [get]
+func GetProductsHandler(productService service.ProductService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		products, err := productService.GetProducts(ctx)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to get products", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to get products")
+			return
+		}
+
+		// Returning empty array if no products found
+		if products == nil {
+			products = []models.Product{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.GetProductsResponse{Products: products})
+	}
+}
+
+// CreateProductHandler handles manual product creation
+// @Summary Create a product
+// @Description Creates a manually managed product, which is left untouched by catalog syncs.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param product body models.CreateProductRequest true "Product creation request"
+// @Success 201 {object} models.Product
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/products [post]
+func CreateProductHandler(productService service.ProductService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var req models.CreateProductRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.ErrorContext(ctx, "Invalid request payload", "error", err)
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		if err := validation.Struct(req); err != nil {
+			logger.ErrorContext(ctx, "Validation failed", "error", err)
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		product, err := productService.CreateProduct(ctx, req)
+		if errors.Is(err, mongo.ErrDuplicate) {
+			logger.WarnContext(ctx, "Product already exists", "productID", req.ProductID)
+			writeErrorResponse(w, http.StatusConflict, "Product already exists")
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to create product", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to create product")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(product)
+		logger.InfoContext(ctx, "Successfully created product", "productID", product.ProductID)
+	}
+}
+
+// UpdateProductHandler handles product updates
+// @Summary Update a product
+// @Description Updates the name and pin of a product, omitted fields are left unchanged.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param product_id path string true "The product ID"
+// @Param product body models.UpdateProductRequest true "Product update request"
+// @Success 200 {object} models.Product
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/products/{product_id} [patch]
+func UpdateProductHandler(productService service.ProductService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		productID := mux.Vars(r)["product_id"]
+
+		var req models.UpdateProductRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.ErrorContext(ctx, "Invalid request payload", "error", err)
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		if err := validation.Struct(req); err != nil {
+			logger.ErrorContext(ctx, "Validation failed", "error", err)
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		product, err := productService.UpdateProduct(ctx, productID, req)
+		writeProductResult(w, r, product, err, "update", logger)
+	}
+}
+
+// RetireProductHandler handles product retirement
+// @Summary Retire a product
+// @Description Retires a product so it no longer accepts votes. Existing votes are kept.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param product_id path string true "The product ID"
+// @Success 200 {object} models.Product
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/products/{product_id}/retire [post]
+func RetireProductHandler(productService service.ProductService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		product, err := productService.RetireProduct(r.Context(), mux.Vars(r)["product_id"])
+		writeProductResult(w, r, product, err, "retire", logger)
+	}
+}
+
+// RestoreProductHandler handles restoring retired products
+// @Summary Restore a product
+// @Description Restores a retired product so it accepts votes again.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param product_id path string true "The product ID"
+// @Success 200 {object} models.Product
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/products/{product_id}/restore [post]
+func RestoreProductHandler(productService service.ProductService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		product, err := productService.RestoreProduct(r.Context(), mux.Vars(r)["product_id"])
+		writeProductResult(w, r, product, err, "restore", logger)
+	}
+}
+
+// ImportProductsHandler handles bulk product imports
+// @Summary Import products
+// @Description Creates or updates manually managed products from a JSON array or a CSV file with a product_id,name,pinned header. Invalid rows are reported and skipped.
+// @Tags admin
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Security ApiKeyAuth
+// @Param products body []models.CreateProductRequest true "Products to import"
+// @Success 200 {object} models.ImportProductsResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/products/import [post]
+func ImportProductsHandler(productService service.ProductService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		body := http.MaxBytesReader(w, r.Body, maxImportBodyBytes)
+
+		var (
+			reqs []models.CreateProductRequest
+			err  error
+		)
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "application/json":
+			err = json.NewDecoder(body).Decode(&reqs)
+		case "text/csv":
+			reqs, err = parseProductsCSV(body)
+		default:
+			writeErrorResponse(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json or text/csv")
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "Invalid import payload", "error", err)
+			writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid import payload: %s", err.Error()))
+			return
+		}
+
+		response := models.ImportProductsResponse{Errors: []models.ImportRowError{}}
+		valid := make([]models.CreateProductRequest, 0, len(reqs))
+		for i, req := range reqs {
+			if err := validation.Struct(req); err != nil {
+				response.Errors = append(response.Errors, models.ImportRowError{Row: i + 1, Message: err.Error()})
+				continue
+			}
+			valid = append(valid, req)
+		}
+
+		response.Imported, err = productService.ImportProducts(ctx, valid)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to import products", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to import products")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		logger.InfoContext(ctx, "Successfully imported products", "imported", response.Imported, "rejected", len(response.Errors))
+	}
+}
+
+// writeProductResult writes the product returned by an admin product operation or the matching error
+func writeProductResult(w http.ResponseWriter, r *http.Request, product models.Product, err error, operation string, logger *slog.Logger) {
+	ctx := r.Context()
+	if errors.Is(err, mongo.ErrNotFound) {
+		logger.WarnContext(ctx, "Product not found", "productID", mux.Vars(r)["product_id"])
+		writeErrorResponse(w, http.StatusNotFound, "Product not found")
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to "+operation+" product", "error", err)
+		writeErrorResponse(w, http.StatusInternalServerError, "Failed to "+operation+" product")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+	logger.InfoContext(ctx, "Successfully applied "+operation+" to product", "productID", product.ProductID)
+}
+
+// parseProductsCSV reads products from a CSV file whose header names the product_id, name and pinned columns
+func parseProductsCSV(r io.Reader) ([]models.CreateProductRequest, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading csv header failed, %s", err.Error())
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["product_id"]; !ok {
+		return nil, errors.New("csv header must contain a product_id column")
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var reqs []models.CreateProductRequest
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		req := models.CreateProductRequest{
+			ProductID: field(record, "product_id"),
+			Name:      field(record, "name"),
+		}
+		if pinned := field(record, "pinned"); pinned != "" {
+			// Unparseable values leave the product unpinned
+			req.Pinned, _ = strconv.ParseBool(pinned)
+		}
+		reqs = append(reqs, req)
+	}
+
+	return reqs, nil
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"foover/internal/log"
+	"foover/internal/service"
+	"foover/internal/store/mongo"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultRecommendationsLimit = 10
+	maxRecommendationsLimit     = 50
+)
+
+// GetRecommendationsHandler retrieves product recommendations for a session
+// @Summary Get recommendations for a session
+// @Description Returns products the session hasn't voted on, ranked by the score predicted from how sessions that rated the same products scored them. When there is too little to predict from, the list is filled in with the top-ranked products. Similarities are rebuilt periodically, the latest votes of the session are always taken into account.
+// @Tags sessions
+// @Produce json
+// @Param session_id path string true "The session ID"
+// @Param limit query int false "Maximum number of recommendations (1-50, default 10)"
+// @Success 200 {object} models.GetRecommendationsResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /sessions/{session_id}/recommendations [get]
+func GetRecommendationsHandler(recommendationService service.RecommendationService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		sessionID := mux.Vars(r)["session_id"]
+		ctx = log.WithAttrs(ctx, slog.String("sessionID", sessionID))
+
+		limit := defaultRecommendationsLimit
+		if raw := r.URL.Query().Get("limit"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed < 1 || parsed > maxRecommendationsLimit {
+				logger.WarnContext(ctx, "Invalid limit", "limit", raw)
+				writeErrorResponse(w, http.StatusBadRequest, "limit must be between 1 and 50")
+				return
+			}
+			limit = parsed
+		}
+
+		response, err := recommendationService.GetRecommendations(ctx, sessionID, limit)
+		if errors.Is(err, mongo.ErrNotFound) {
+			logger.WarnContext(ctx, "Session not found")
+			writeErrorResponse(w, http.StatusNotFound, "Session not found")
+			return
+		}
+		if errors.Is(err, service.ErrRecommendationsUnavailable) {
+			logger.WarnContext(ctx, "Recommendation model not built yet")
+			writeErrorResponse(w, http.StatusServiceUnavailable, "Recommendations are not available yet")
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to get recommendations", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to get recommendations")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		logger.InfoContext(ctx, "Successfully retrieved recommendations", "recommendations", len(response.Recommendations))
+	}
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"encoding/json"
+	"foover/internal/service"
+	"log/slog"
+	"net/http"
+)
+
+// RetentionReportHandler reports what the retention rules would remove
+// @Summary Preview data retention
+// @Description Runs the retention rules as a dry run and reports how many sessions without votes would be deleted and how many votes would be anonymized.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.RetentionReport
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/retention/report [get]
+func RetentionReportHandler(retentionService service.RetentionService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		report, err := retentionService.Run(ctx, true)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to run retention dry run", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to run retention dry run")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+		logger.InfoContext(ctx, "Successfully ran retention dry run", "emptySessions", report.EmptySessions, "anonymizedVotes", report.AnonymizedVotes)
+	}
+}
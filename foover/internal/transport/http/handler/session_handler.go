@@ -2,8 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"foover/internal/log"
+	"foover/internal/middleware"
 	"foover/internal/models"
 	"foover/internal/service"
+	"foover/internal/store/mongo"
+	"github.com/gorilla/mux"
 	"io"
 	"log/slog"
 	"net/http"
@@ -11,10 +16,11 @@ import (
 
 // CreateSessionHandler handles session creation
 // @Summary Create a new session
-// @Description Generates a unique session ID.
+// @Description Generates a unique session ID and assigns the session to a variant of every running experiment.
 // @Tags sessions
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Client chosen key making retries safe, the response of the first request is replayed"
 // @Param session body models.CreateSessionRequest false "Session creation request"
 // @Success 200 {object} models.CreateSessionResponse
 // @Failure 400 {object} models.ErrorResponse
@@ -22,25 +28,102 @@ import (
 // @Router /sessions [post]
 func CreateSessionHandler(sessionService service.SessionService, logger *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
 		var req models.CreateSessionRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
-			logger.Error("Invalid request payload", "error", err)
+			logger.ErrorContext(ctx, "Invalid request payload", "error", err)
 			writeErrorResponse(w, http.StatusBadRequest, "Invalid request payload")
 			return
 		}
 
-		logger.Info("Creating session")
-		ctx := r.Context()
-		sessionID, err := sessionService.CreateSession(ctx)
+		logger.InfoContext(ctx, "Creating session")
+		session, err := sessionService.CreateSession(ctx)
 		if err != nil {
-			logger.Error("Failed to create session", "error", err)
+			logger.ErrorContext(ctx, "Failed to create session", "error", err)
 			writeErrorResponse(w, http.StatusInternalServerError, "Failed to create session")
 			return
 		}
 
-		response := models.CreateSessionResponse{SessionID: sessionID}
+		ctx = log.WithAttrs(ctx, slog.String("sessionID", session.SessionID))
+
+		response := models.CreateSessionResponse{SessionID: session.SessionID, Variants: session.Variants}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(response)
-		logger.Info("Successfully created session", "sessionID", sessionID)
+		logger.InfoContext(ctx, "Successfully created session")
+	}
+}
+
+// ExportSessionHandler exports everything stored for a session
+// @Summary Export session data
+// @Description Returns everything stored for a session (the session record, its votes with their moderation state and the stored client fingerprints) to honor data subject access requests.
+// @Tags sessions
+// @Produce json
+// @Security ApiKeyAuth
+// @Param session_id path string true "The session ID"
+// @Success 200 {object} models.SessionExport
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /sessions/{session_id}/export [get]
+func ExportSessionHandler(sessionService service.SessionService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		sessionID := mux.Vars(r)["session_id"]
+		ctx = log.WithAttrs(ctx, slog.String("sessionID", sessionID))
+
+		export, err := sessionService.ExportSession(ctx, sessionID)
+		if errors.Is(err, mongo.ErrNotFound) {
+			logger.WarnContext(ctx, "Session not found")
+			writeErrorResponse(w, http.StatusNotFound, "Session not found")
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to export session", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to export session")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="session-export.json"`)
+		json.NewEncoder(w).Encode(export)
+		logger.InfoContext(ctx, "Successfully exported session", "votes", len(export.Votes))
+	}
+}
+
+// EraseSessionHandler erases a session and its votes
+// @Summary Erase session data
+// @Description Deletes a session, its votes and the webhook deliveries and published outbox events of its votes in one transaction to honor data subject erasure requests. Vote events not published yet are published without the session ID. Aggregated scores no longer include the erased votes. The erasure is audited with a hash of the session ID, which is returned as a receipt.
+// @Tags sessions
+// @Produce json
+// @Security ApiKeyAuth
+// @Param session_id path string true "The session ID"
+// @Success 200 {object} models.ErasureAuditEntry
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /sessions/{session_id} [delete]
+func EraseSessionHandler(sessionService service.SessionService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		sessionID := mux.Vars(r)["session_id"]
+		ctx = log.WithAttrs(ctx, slog.String("sessionID", sessionID))
+
+		entry, err := sessionService.EraseSession(ctx, sessionID, middleware.RequestIDFromContext(ctx))
+		if errors.Is(err, mongo.ErrNotFound) {
+			logger.WarnContext(ctx, "Session not found")
+			writeErrorResponse(w, http.StatusNotFound, "Session not found")
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to erase session", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to erase session")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entry)
+		logger.InfoContext(ctx, "Successfully erased session", "votesDeleted", entry.VotesDeleted, "auditID", entry.ID.Hex())
 	}
 }
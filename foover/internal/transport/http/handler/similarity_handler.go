@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"foover/internal/models"
+	"foover/internal/service"
+	"foover/internal/store/mongo"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultSimilarProductsLimit = 10
+	maxSimilarProductsLimit     = 50
+)
+
+// GetSimilarProductsHandler retrieves the products similar to a product
+// @Summary Get similar products
+// @Description Returns the products most similar to a product, measured by how similarly the sessions that rated both scored them ("people who liked this also liked"). Only pairs rated by enough sessions are considered and retired products are left out. The similarity index is refreshed periodically.
+// @Tags products
+// @Produce json
+// @Param product_id path string true "The product ID"
+// @Param limit query int false "Maximum number of similar products (1-50, default 10)"
+// @Success 200 {object} models.GetSimilarProductsResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /products/{product_id}/similar [get]
+func GetSimilarProductsHandler(similarityService service.SimilarityService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		productID := mux.Vars(r)["product_id"]
+
+		limit := defaultSimilarProductsLimit
+		if raw := r.URL.Query().Get("limit"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed < 1 || parsed > maxSimilarProductsLimit {
+				logger.WarnContext(ctx, "Invalid limit", "limit", raw)
+				writeErrorResponse(w, http.StatusBadRequest, "limit must be between 1 and 50")
+				return
+			}
+			limit = parsed
+		}
+
+		similar, err := similarityService.GetSimilarProducts(ctx, productID, limit)
+		if errors.Is(err, mongo.ErrNotFound) {
+			logger.WarnContext(ctx, "Product not found", "productID", productID)
+			writeErrorResponse(w, http.StatusNotFound, "Product not found")
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to get similar products", "productID", productID, "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to get similar products")
+			return
+		}
+		if similar == nil {
+			similar = []models.SimilarProduct{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.GetSimilarProductsResponse{ProductID: productID, Similar: similar})
+		logger.InfoContext(ctx, "Successfully retrieved similar products", "productID", productID, "similar", len(similar))
+	}
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"encoding/json"
+	"foover/internal/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultTrendingLimit = 10
+	maxTrendingLimit     = 50
+)
+
+// GetTrendingHandler retrieves the trending products
+// @Summary Get trending products
+// @Description Ranks products by the change of their vote volume and average score in a recent window (TRENDING_WINDOW) compared to the baseline window before it (TRENDING_BASELINE_WINDOW). Recent votes weigh less as they age, halving every TRENDING_HALF_LIFE of Vote.UpdatedAt. Flagged and rejected votes don't count and retired products are left out. The ranking is cached for TRENDING_CACHE_TTL.
+// @Tags products
+// @Produce json
+// @Param limit query int false "Maximum number of products (1-50, default 10)"
+// @Success 200 {object} models.GetTrendingResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /trending [get]
+func GetTrendingHandler(trendingService service.TrendingService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		limit := defaultTrendingLimit
+		if raw := r.URL.Query().Get("limit"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed < 1 || parsed > maxTrendingLimit {
+				logger.WarnContext(ctx, "Invalid limit", "limit", raw)
+				writeErrorResponse(w, http.StatusBadRequest, "limit must be between 1 and 50")
+				return
+			}
+			limit = parsed
+		}
+
+		response, err := trendingService.GetTrending(ctx, limit)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to get trending products", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to get trending products")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		logger.InfoContext(ctx, "Successfully retrieved trending products", "products", len(response.Products))
+	}
+}
@@ -2,10 +2,12 @@ package handler
 
 import (
 	"encoding/json"
+	"foover/internal/log"
+	"foover/internal/middleware"
 	"foover/internal/models"
 	"foover/internal/service"
+	"foover/internal/validation"
 	"github.com/gorilla/mux"
-	"log"
 	"log/slog"
 	"net/http"
 )
@@ -16,6 +18,7 @@ import (
 // @Tags votes
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Client chosen key making retries safe, the response of the first request is replayed"
 // @Param vote body models.SaveVoteRequest true "Vote object that needs to be added or updated"
 // @Success 201 {object} models.EmptyResponse
 // @Failure 400 {object} models.ErrorResponse
@@ -23,43 +26,53 @@ import (
 // @Router /votes [post]
 func SaveVoteHandler(voteService service.VoteService, productService service.ProductService, sessionService service.SessionService, logger *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
 		var voteReq models.SaveVoteRequest
 		if err := json.NewDecoder(r.Body).Decode(&voteReq); err != nil {
-			logger.Error("Invalid request payload", "error", err)
+			logger.ErrorContext(ctx, "Invalid request payload", "error", err)
 			writeErrorResponse(w, http.StatusBadRequest, "Invalid request payload")
 			return
 		}
 
 		// Validate the request
-		if err := ValidateStruct(voteReq); err != nil {
-			logger.Error("Validation failed", "error", err)
+		if err := validation.Struct(voteReq); err != nil {
+			logger.ErrorContext(ctx, "Validation failed", "error", err)
 			writeErrorResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		ctx := r.Context()
-		// Validate session ID
+		ctx = log.WithAttrs(ctx, slog.String("sessionID", voteReq.SessionID))
+
+		// Validate session ID, with a vote buffer a store outage skips the check and replaying drops unknown sessions
 		sessionExists, err := sessionService.SessionExists(ctx, voteReq.SessionID)
-		if err != nil {
-			logger.Error("Error validating session ID", "error", err)
+		switch {
+		case err != nil && voteService.BuffersVotes():
+			logger.WarnContext(ctx, "Skipping session ID validation", "error", err)
+			sessionExists = true
+		case err != nil:
+			logger.ErrorContext(ctx, "Error validating session ID", "error", err)
 			writeErrorResponse(w, http.StatusInternalServerError, "Failed to validate session ID")
 			return
 		}
 		if !sessionExists {
-			logger.Warn("Invalid session ID", "sessionID", voteReq.SessionID)
+			logger.WarnContext(ctx, "Invalid session ID")
 			writeErrorResponse(w, http.StatusBadRequest, "Invalid session ID")
 			return
 		}
 
-		// Validate product ID
+		// Validate product ID, skipped on a store outage like the session ID
 		isValidProduct, err := productService.IsValidProductID(ctx, voteReq.ProductID)
-		if err != nil {
-			logger.Error("Error validating product ID", "error", err)
+		switch {
+		case err != nil && voteService.BuffersVotes():
+			logger.WarnContext(ctx, "Skipping product ID validation", "error", err)
+			isValidProduct = true
+		case err != nil:
+			logger.ErrorContext(ctx, "Error validating product ID", "error", err)
 			writeErrorResponse(w, http.StatusInternalServerError, "Failed to validate product ID")
 			return
 		}
 		if !isValidProduct {
-			logger.Warn("Invalid product ID", "productID", voteReq.ProductID)
+			logger.WarnContext(ctx, "Invalid product ID", "productID", voteReq.ProductID)
 			writeErrorResponse(w, http.StatusBadRequest, "Invalid product ID")
 			return
 		}
@@ -68,16 +81,147 @@ func SaveVoteHandler(voteService service.VoteService, productService service.Pro
 			SessionID: voteReq.SessionID,
 			ProductID: voteReq.ProductID,
 			Score:     voteReq.Score,
+			ClientIP:  middleware.ClientIPFromContext(ctx),
+			UserAgent: r.UserAgent(),
 		}
 
 		if err := voteService.SaveVote(ctx, vote); err != nil {
-			logger.Error("Failed to save vote", "error", err)
+			logger.ErrorContext(ctx, "Failed to save vote", "error", err)
 			writeErrorResponse(w, http.StatusInternalServerError, "Failed to save vote")
 			return
 		}
 
 		w.WriteHeader(http.StatusCreated)
-		logger.Info("Successfully saved vote", "sessionID", voteReq.SessionID, "productID", voteReq.ProductID)
+		logger.InfoContext(ctx, "Successfully saved vote", "productID", voteReq.ProductID)
+	}
+}
+
+// SaveVotesBatchHandler handles saving several votes of a session at once
+// @Summary Save or update several votes
+// @Description Stores or updates up to 50 product votes of a session with a single write and reports the outcome of each vote. Invalid votes are rejected individually, unless the batch is atomic, in which case nothing is stored.
+// @Tags votes
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string false "Client chosen key making retries safe, the response of the first request is replayed"
+// @Param votes body models.SaveVotesBatchRequest true "Votes that need to be added or updated"
+// @Success 201 {object} models.SaveVotesBatchResponse "All votes were saved"
+// @Success 207 {object} models.SaveVotesBatchResponse "Some votes were not saved"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /votes/batch [post]
+func SaveVotesBatchHandler(voteService service.VoteService, productService service.ProductService, sessionService service.SessionService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var batchReq models.SaveVotesBatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&batchReq); err != nil {
+			logger.ErrorContext(ctx, "Invalid request payload", "error", err)
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		if err := validation.Struct(batchReq); err != nil {
+			logger.ErrorContext(ctx, "Validation failed", "error", err)
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		ctx = log.WithAttrs(ctx, slog.String("sessionID", batchReq.SessionID))
+
+		// Non-atomic batches are buffered on a store outage, so the outage skips the checks of the session
+		// and products, replaying drops unknown ones
+		buffered := voteService.BuffersVotes() && !batchReq.Atomic
+		sessionExists, err := sessionService.SessionExists(ctx, batchReq.SessionID)
+		switch {
+		case err != nil && buffered:
+			logger.WarnContext(ctx, "Skipping session ID validation", "error", err)
+			sessionExists = true
+		case err != nil:
+			logger.ErrorContext(ctx, "Error validating session ID", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to validate session ID")
+			return
+		}
+		if !sessionExists {
+			logger.WarnContext(ctx, "Invalid session ID")
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+
+		productIDs := make([]string, 0, len(batchReq.Votes))
+		for _, item := range batchReq.Votes {
+			productIDs = append(productIDs, item.ProductID)
+		}
+		validProducts, err := productService.GetValidProductIDs(ctx, productIDs)
+		switch {
+		case err != nil && buffered:
+			logger.WarnContext(ctx, "Skipping product ID validation", "error", err)
+			validProducts = make(map[string]bool, len(productIDs))
+			for _, productID := range productIDs {
+				validProducts[productID] = true
+			}
+		case err != nil:
+			logger.ErrorContext(ctx, "Error validating product IDs", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to validate product IDs")
+			return
+		}
+
+		// Validate the votes individually, so every invalid vote is reported
+		results := make([]models.BatchVoteResult, len(batchReq.Votes))
+		votes := make([]models.Vote, 0, len(batchReq.Votes))
+		indexes := make([]int, 0, len(batchReq.Votes))
+		seen := make(map[string]bool, len(batchReq.Votes))
+		for i, item := range batchReq.Votes {
+			results[i] = models.BatchVoteResult{ProductID: item.ProductID, Status: models.BatchVoteRejected}
+			itemErr := validation.Struct(item)
+			switch {
+			case itemErr != nil:
+				results[i].Error = itemErr.Error()
+			case seen[item.ProductID]:
+				results[i].Error = "Duplicate product ID"
+			case !validProducts[item.ProductID]:
+				results[i].Error = "Invalid product ID"
+			default:
+				results[i].Status = models.BatchVoteSaved
+				votes = append(votes, models.Vote{
+					SessionID: batchReq.SessionID,
+					ProductID: item.ProductID,
+					Score:     item.Score,
+					ClientIP:  middleware.ClientIPFromContext(ctx),
+					UserAgent: r.UserAgent(),
+				})
+				indexes = append(indexes, i)
+			}
+			seen[item.ProductID] = true
+		}
+
+		saved := len(votes)
+		switch {
+		case batchReq.Atomic && saved < len(batchReq.Votes):
+			for _, i := range indexes {
+				results[i].Status = models.BatchVoteSkipped
+			}
+			saved = 0
+		case saved > 0:
+			failed, err := voteService.SaveVotes(ctx, votes, batchReq.Atomic)
+			if err != nil {
+				logger.ErrorContext(ctx, "Failed to save votes", "error", err)
+				writeErrorResponse(w, http.StatusInternalServerError, "Failed to save votes")
+				return
+			}
+			for j, message := range failed {
+				results[indexes[j]].Status = models.BatchVoteFailed
+				results[indexes[j]].Error = message
+				saved--
+			}
+		}
+
+		status := http.StatusCreated
+		if saved < len(batchReq.Votes) {
+			status = http.StatusMultiStatus
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(models.SaveVotesBatchResponse{Results: results})
+		logger.InfoContext(ctx, "Successfully processed vote batch", "votes", len(batchReq.Votes), "saved", saved)
 	}
 }
 
@@ -86,21 +230,25 @@ func SaveVoteHandler(voteService service.VoteService, productService service.Pro
 // @Description Retrieves existing votes for products for a given session ID.
 // @Tags votes
 // @Produce json
+// @Security ApiKeyAuth
 // @Param session_id path string true "The session ID"
 // @Success 200 {object} models.GetVotesResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /votes/{session_id} [get]
 func GetVotesHandler(voteService service.VoteService, logger *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
 		vars := mux.Vars(r)
 		sessionID := vars["session_id"]
+		ctx = log.WithAttrs(ctx, slog.String("sessionID", sessionID))
 
-		logger.Info("Received request to get votes", "sessionID", sessionID)
-		ctx := r.Context()
+		logger.InfoContext(ctx, "Received request to get votes")
 		votes, err := voteService.GetVotesBySessionID(ctx, sessionID)
 		if err != nil {
-			log.Printf("Error getting votes: %v", err)
+			logger.ErrorContext(ctx, "Failed to get votes", "error", err)
 			writeErrorResponse(w, http.StatusInternalServerError, "Failed to get votes")
 			return
 		}
@@ -116,6 +264,6 @@ func GetVotesHandler(voteService service.VoteService, logger *slog.Logger) http.
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(response)
-		logger.Info("Successfully retrieved and sent votes", "sessionID", sessionID)
+		logger.InfoContext(ctx, "Successfully retrieved and sent votes")
 	}
 }
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"foover/internal/config"
+	"foover/internal/fraud"
+	"foover/internal/metrics"
+	"foover/internal/models"
+	"foover/internal/service"
+	"foover/internal/store/mongo"
+	"foover/internal/votebuffer"
+)
+
+// unreachableStore fails every vote path operation like a MongoDB without a reachable node, after latency
+type unreachableStore struct {
+	mongo.Store
+	latency time.Duration
+	calls   atomic.Int32
+}
+
+func (s *unreachableStore) unreachable() error {
+	s.calls.Add(1)
+	time.Sleep(s.latency)
+	return context.DeadlineExceeded
+}
+
+func (s *unreachableStore) SessionExists(context.Context, string) (bool, error) {
+	return false, s.unreachable()
+}
+
+func (s *unreachableStore) IsValidProductID(context.Context, string) (bool, error) {
+	return false, s.unreachable()
+}
+
+func (s *unreachableStore) GetSession(context.Context, string) (models.Session, error) {
+	return models.Session{}, s.unreachable()
+}
+
+func (s *unreachableStore) CountVotesBySessionSince(context.Context, string, time.Time) (int64, error) {
+	return 0, s.unreachable()
+}
+
+func (s *unreachableStore) CountMatchingVotesSince(context.Context, string, int, time.Time) (int64, error) {
+	return 0, s.unreachable()
+}
+
+func (s *unreachableStore) CountSessionsByFingerprintSince(context.Context, string, time.Time) (int64, error) {
+	return 0, s.unreachable()
+}
+
+func (s *unreachableStore) SaveVote(context.Context, models.Vote) error {
+	return s.unreachable()
+}
+
+func (s *unreachableStore) Ping(context.Context) error {
+	return nil
+}
+
+func TestSaveVoteHandlerStoreDown(t *testing.T) {
+	const latency = 100 * time.Millisecond
+	next := &unreachableStore{latency: latency}
+	store := mongo.NewBreakerStore(next, time.Minute)
+
+	buffer, err := votebuffer.NewFileBuffer(config.VoteBuffer{Dir: t.TempDir(), MaxDepth: 10, ReplayBatchSize: 10})
+	if err != nil {
+		t.Fatalf("NewFileBuffer() error = %v", err)
+	}
+	defer buffer.Close()
+
+	m := metrics.New()
+	fraudCfg := config.Fraud{Enabled: true, FlagThreshold: 1, MaxVotesPerMinute: 20, BurstThreshold: 20, MaxSessionsByFingerprint: 30}
+	voteService := service.NewVoteService(store, fraud.NewDetector(store, fraudCfg), buffer, config.Outbox{}, m)
+	handler := SaveVoteHandler(voteService, service.NewProductService(store, config.ProductCache{}, m),
+		service.NewSessionService(store, config.Experiment{}, m), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// Every lookup of the vote path would wait for the store, only the first one may
+	body := `{"session_id":"2f0c2a4e-8a4b-4b7e-9c1d-3e5f6a7b8c9d","product_id":"7d9e1b2c-4f3a-4c5b-8d6e-1a2b3c4d5e6f","score":5}`
+	req := httptest.NewRequest(http.MethodPost, "/votes", strings.NewReader(body))
+	req.Header.Set("User-Agent", "test")
+	rec := httptest.NewRecorder()
+
+	start := time.Now()
+	handler(rec, req)
+	elapsed := time.Since(start)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d, body %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if elapsed >= 3*latency {
+		t.Errorf("answered after %v, want less than %v", elapsed, 3*latency)
+	}
+	if calls := next.calls.Load(); calls != 1 {
+		t.Errorf("store called %d times, want 1", calls)
+	}
+	if depth := buffer.Depth(); depth != 1 {
+		t.Errorf("buffer depth = %d, want 1", depth)
+	}
+
+	// A successful readiness ping closes the breaker
+	if err := store.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	if _, err := store.SessionExists(context.Background(), "s1"); err == mongo.ErrUnavailable {
+		t.Errorf("SessionExists() after a successful ping error = %v, want the store to be called", err)
+	}
+	if calls := next.calls.Load(); calls != 2 {
+		t.Errorf("store called %d times, want 2", calls)
+	}
+}
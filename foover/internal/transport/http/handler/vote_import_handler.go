@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"foover/internal/importer"
+	"foover/internal/service"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxVoteImportBodyBytes bounds the size of bulk vote imports, larger imports go through the CLI
+const maxVoteImportBodyBytes = 100 << 20
+
+// ImportVotesHandler handles bulk vote imports
+// @Summary Import votes
+// @Description Imports votes from a CSV file with a session_id,product_id,score,timestamp header or from NDJSON objects with the same fields. Rows are validated like single votes, timestamps are RFC 3339 or YYYY-MM-DD. A stored vote is only replaced by an imported vote with a newer timestamp. Invalid rows are reported and skipped.
+// @Tags admin
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Produce json
+// @Security ApiKeyAuth
+// @Param dry_run query bool false "Only validate the rows without writing them"
+// @Success 200 {object} models.ImportVotesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/votes/import [post]
+func ImportVotesHandler(voteService service.VoteService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var format string
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "text/csv":
+			format = importer.FormatCSV
+		case "application/x-ndjson", "application/ndjson":
+			format = importer.FormatNDJSON
+		default:
+			writeErrorResponse(w, http.StatusUnsupportedMediaType, "Content-Type must be text/csv or application/x-ndjson")
+			return
+		}
+
+		dryRun := false
+		if raw := r.URL.Query().Get("dry_run"); raw != "" {
+			var err error
+			if dryRun, err = strconv.ParseBool(raw); err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "dry_run must be a boolean")
+				return
+			}
+		}
+
+		// Large imports outlast the server read timeout
+		if err := http.NewResponseController(w).SetReadDeadline(time.Time{}); err != nil {
+			logger.WarnContext(ctx, "Failed to lift read deadline for import", "error", err)
+		}
+		body := http.MaxBytesReader(w, r.Body, maxVoteImportBodyBytes)
+
+		response, err := voteService.ImportVotes(ctx, format, body, dryRun)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			logger.WarnContext(ctx, "Import payload too large", "imported", response.Imported)
+			writeErrorResponse(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Import payload exceeds %d bytes, %d votes were imported before the limit", maxBytesErr.Limit, response.Imported))
+			return
+		}
+		if errors.Is(err, service.ErrUnreadableImport) {
+			logger.WarnContext(ctx, "Invalid import payload", "imported", response.Imported, "error", err)
+			writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid import payload, %d votes were imported before the error: %s", response.Imported, err.Error()))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to import votes", "imported", response.Imported, "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Failed to import votes, %d votes were imported before the failure", response.Imported))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		logger.InfoContext(ctx, "Successfully imported votes", "dryRun", dryRun, "imported", response.Imported, "rejected", response.Rejected)
+	}
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"foover/internal/models"
+	"foover/internal/service"
+	"foover/internal/store/mongo"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultFlaggedVotesLimit = 100
+	maxFlaggedVotesLimit     = 1000
+)
+
+// GetFlaggedVotesHandler retrieves the votes waiting for a review
+// @Summary List flagged votes
+// @Description Retrieves votes flagged by the fraud detection, oldest first. Flagged votes are left out of the aggregated scores until approved.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param limit query int false "Maximum number of votes to return (default 100, max 1000)"
+// @Success 200 {object} models.GetFlaggedVotesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/votes/flagged [get]
+func GetFlaggedVotesHandler(voteService service.VoteService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		limit := int64(defaultFlaggedVotesLimit)
+		if raw := r.URL.Query().Get("limit"); raw != "" {
+			parsed, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || parsed < 1 || parsed > maxFlaggedVotesLimit {
+				logger.WarnContext(ctx, "Invalid limit", "limit", raw)
+				writeErrorResponse(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+				return
+			}
+			limit = parsed
+		}
+
+		votes, err := voteService.GetFlaggedVotes(ctx, limit)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to get flagged votes", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to get flagged votes")
+			return
+		}
+
+		// Returning empty array if no votes are flagged
+		if votes == nil {
+			votes = []models.Vote{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.GetFlaggedVotesResponse{Votes: votes})
+	}
+}
+
+// ApproveVoteHandler approves a flagged vote
+// @Summary Approve a flagged vote
+// @Description Approves a flagged vote, so it counts in the aggregated scores.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "The vote ID"
+// @Success 200 {object} models.Vote
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/votes/{id}/approve [post]
+func ApproveVoteHandler(voteService service.VoteService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vote, err := voteService.ApproveVote(r.Context(), mux.Vars(r)["id"])
+		writeReviewResult(w, r, vote, err, "approve", logger)
+	}
+}
+
+// RejectVoteHandler rejects a flagged vote
+// @Summary Reject a flagged vote
+// @Description Rejects a flagged vote, so it stays out of the aggregated scores. A new vote by the session on the product is assessed again.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "The vote ID"
+// @Success 200 {object} models.Vote
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/votes/{id}/reject [post]
+func RejectVoteHandler(voteService service.VoteService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vote, err := voteService.RejectVote(r.Context(), mux.Vars(r)["id"])
+		writeReviewResult(w, r, vote, err, "reject", logger)
+	}
+}
+
+// writeReviewResult writes the vote returned by a review or the matching error
+func writeReviewResult(w http.ResponseWriter, r *http.Request, vote models.Vote, err error, operation string, logger *slog.Logger) {
+	ctx := r.Context()
+	if errors.Is(err, mongo.ErrNotFound) {
+		logger.WarnContext(ctx, "Flagged vote not found", "voteID", mux.Vars(r)["id"])
+		writeErrorResponse(w, http.StatusNotFound, "Flagged vote not found")
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to "+operation+" vote", "error", err)
+		writeErrorResponse(w, http.StatusInternalServerError, "Failed to "+operation+" vote")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(vote)
+	logger.InfoContext(ctx, "Successfully applied "+operation+" to vote", "voteID", vote.ID.Hex(), "sessionID", vote.SessionID, "productID", vote.ProductID)
+}
@@ -0,0 +1,359 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"foover/internal/models"
+	"foover/internal/service"
+	"foover/internal/store/mongo"
+	"foover/internal/validation"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultWebhookDeliveriesLimit = 100
+	maxWebhookDeliveriesLimit     = 1000
+)
+
+// CreateWebhookHandler handles webhook creation
+// @Summary Create a webhook
+// @Description Subscribes a URL to events, optionally only for some products. Payloads are signed with HMAC-SHA256 in the X-Foover-Signature header, the secret is only returned in this response.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param webhook body models.CreateWebhookRequest true "Webhook creation request"
+// @Success 201 {object} models.CreateWebhookResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/webhooks [post]
+func CreateWebhookHandler(webhookService service.WebhookService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var req models.CreateWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.ErrorContext(ctx, "Invalid request payload", "error", err)
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		if err := validation.Struct(req); err != nil {
+			logger.ErrorContext(ctx, "Validation failed", "error", err)
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		secret, webhook, err := webhookService.CreateWebhook(ctx, req)
+		if errors.Is(err, service.ErrInvalidWebhookEvent) {
+			logger.WarnContext(ctx, "Invalid webhook event", "error", err)
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to create webhook", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to create webhook")
+			return
+		}
+
+		response := models.CreateWebhookResponse{
+			Secret:  secret,
+			Webhook: webhook,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+		logger.InfoContext(ctx, "Successfully created webhook", "webhookID", webhook.ID.Hex(), "events", webhook.Events)
+	}
+}
+
+// GetWebhooksHandler retrieves all webhooks
+// @Summary List webhooks
+// @Description Retrieves all webhooks, active or not.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.GetWebhooksResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/webhooks [get]
+func GetWebhooksHandler(webhookService service.WebhookService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		webhooks, err := webhookService.GetWebhooks(ctx)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to get webhooks", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to get webhooks")
+			return
+		}
+
+		// Returning empty array if no webhooks found
+		if webhooks == nil {
+			webhooks = []models.Webhook{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.GetWebhooksResponse{Webhooks: webhooks})
+	}
+}
+
+// GetWebhookHandler retrieves a webhook
+// @Summary Get a webhook
+// @Description Retrieves the webhook with the given ID.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "The webhook ID"
+// @Success 200 {object} models.Webhook
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/webhooks/{id} [get]
+func GetWebhookHandler(webhookService service.WebhookService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		webhook, err := webhookService.GetWebhook(r.Context(), mux.Vars(r)["id"])
+		writeWebhookResult(w, r, webhook, err, "get", logger)
+	}
+}
+
+// UpdateWebhookHandler handles webhook updates
+// @Summary Update a webhook
+// @Description Updates the URL, events, products or state of a webhook, omitted fields are left unchanged.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "The webhook ID"
+// @Param webhook body models.UpdateWebhookRequest true "Webhook update request"
+// @Success 200 {object} models.Webhook
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/webhooks/{id} [patch]
+func UpdateWebhookHandler(webhookService service.WebhookService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var req models.UpdateWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.ErrorContext(ctx, "Invalid request payload", "error", err)
+			writeErrorResponse(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		if err := validation.Struct(req); err != nil {
+			logger.ErrorContext(ctx, "Validation failed", "error", err)
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		webhook, err := webhookService.UpdateWebhook(ctx, mux.Vars(r)["id"], req)
+		if errors.Is(err, service.ErrInvalidWebhookEvent) {
+			logger.WarnContext(ctx, "Invalid webhook event", "error", err)
+			writeErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeWebhookResult(w, r, webhook, err, "update", logger)
+	}
+}
+
+// DeleteWebhookHandler deletes a webhook
+// @Summary Delete a webhook
+// @Description Deletes the webhook with the given ID along with its pending deliveries and delivery logs.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "The webhook ID"
+// @Success 204
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/webhooks/{id} [delete]
+func DeleteWebhookHandler(webhookService service.WebhookService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id := mux.Vars(r)["id"]
+
+		err := webhookService.DeleteWebhook(ctx, id)
+		if errors.Is(err, mongo.ErrNotFound) {
+			logger.WarnContext(ctx, "Webhook not found", "webhookID", id)
+			writeErrorResponse(w, http.StatusNotFound, "Webhook not found")
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to delete webhook", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete webhook")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		logger.InfoContext(ctx, "Successfully deleted webhook", "webhookID", id)
+	}
+}
+
+// GetWebhookDeliveriesHandler retrieves the delivery log of a webhook
+// @Summary List webhook deliveries
+// @Description Retrieves the deliveries of a webhook with their attempts, newest first.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "The webhook ID"
+// @Param status query string false "Only deliveries with this status (pending, succeeded, dead)"
+// @Param limit query int false "Maximum number of deliveries to return (default 100, max 1000)"
+// @Success 200 {object} models.GetWebhookDeliveriesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/webhooks/{id}/deliveries [get]
+func GetWebhookDeliveriesHandler(webhookService service.WebhookService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id := mux.Vars(r)["id"]
+
+		status := r.URL.Query().Get("status")
+		switch status {
+		case "", models.WebhookDeliveryPending, models.WebhookDeliverySucceeded, models.WebhookDeliveryDead:
+		default:
+			logger.WarnContext(ctx, "Invalid delivery status", "status", status)
+			writeErrorResponse(w, http.StatusBadRequest, "status must be one of pending, succeeded, dead")
+			return
+		}
+
+		limit, ok := parseWebhookDeliveriesLimit(w, r, logger)
+		if !ok {
+			return
+		}
+
+		deliveries, err := webhookService.GetDeliveries(ctx, id, status, limit)
+		if errors.Is(err, mongo.ErrNotFound) {
+			logger.WarnContext(ctx, "Webhook not found", "webhookID", id)
+			writeErrorResponse(w, http.StatusNotFound, "Webhook not found")
+			return
+		}
+		writeWebhookDeliveries(w, r, deliveries, err, logger)
+	}
+}
+
+// GetWebhookDeadLettersHandler retrieves the deliveries that ran out of attempts
+// @Summary List dead letters
+// @Description Retrieves deliveries of all webhooks that failed their last attempt, newest first. They can be redelivered.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param limit query int false "Maximum number of deliveries to return (default 100, max 1000)"
+// @Success 200 {object} models.GetWebhookDeliveriesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/webhooks/dead-letters [get]
+func GetWebhookDeadLettersHandler(webhookService service.WebhookService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, ok := parseWebhookDeliveriesLimit(w, r, logger)
+		if !ok {
+			return
+		}
+
+		deliveries, err := webhookService.GetDeadLetters(r.Context(), limit)
+		writeWebhookDeliveries(w, r, deliveries, err, logger)
+	}
+}
+
+// RedeliverWebhookHandler schedules a dead letter for redelivery
+// @Summary Redeliver a dead letter
+// @Description Schedules a delivery that ran out of attempts for a new round of attempts right away.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "The delivery ID"
+// @Success 200 {object} models.WebhookDelivery
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/webhooks/deliveries/{id}/redeliver [post]
+func RedeliverWebhookHandler(webhookService service.WebhookService, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id := mux.Vars(r)["id"]
+
+		delivery, err := webhookService.Redeliver(ctx, id)
+		if errors.Is(err, mongo.ErrNotFound) {
+			logger.WarnContext(ctx, "Dead letter not found", "deliveryID", id)
+			writeErrorResponse(w, http.StatusNotFound, "Dead letter not found")
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to redeliver webhook delivery", "error", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "Failed to redeliver webhook delivery")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(delivery)
+		logger.InfoContext(ctx, "Successfully scheduled webhook redelivery", "deliveryID", id)
+	}
+}
+
+// writeWebhookResult writes the webhook resulting from an operation or the matching error response
+func writeWebhookResult(w http.ResponseWriter, r *http.Request, webhook models.Webhook, err error, operation string, logger *slog.Logger) {
+	ctx := r.Context()
+	if errors.Is(err, mongo.ErrNotFound) {
+		logger.WarnContext(ctx, "Webhook not found", "webhookID", mux.Vars(r)["id"])
+		writeErrorResponse(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to "+operation+" webhook", "error", err)
+		writeErrorResponse(w, http.StatusInternalServerError, "Failed to "+operation+" webhook")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// writeWebhookDeliveries writes deliveries or the matching error response
+func writeWebhookDeliveries(w http.ResponseWriter, r *http.Request, deliveries []models.WebhookDelivery, err error, logger *slog.Logger) {
+	ctx := r.Context()
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to get webhook deliveries", "error", err)
+		writeErrorResponse(w, http.StatusInternalServerError, "Failed to get webhook deliveries")
+		return
+	}
+
+	// Returning empty array if no deliveries found
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.GetWebhookDeliveriesResponse{Deliveries: deliveries})
+}
+
+// parseWebhookDeliveriesLimit reads the limit query parameter, it writes a bad request response and returns false if it is invalid
+func parseWebhookDeliveriesLimit(w http.ResponseWriter, r *http.Request, logger *slog.Logger) (int64, bool) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return defaultWebhookDeliveriesLimit, true
+	}
+
+	limit, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || limit < 1 || limit > maxWebhookDeliveriesLimit {
+		logger.WarnContext(r.Context(), "Invalid limit", "limit", raw)
+		writeErrorResponse(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+		return 0, false
+	}
+	return limit, true
+}
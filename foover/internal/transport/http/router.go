@@ -2,7 +2,12 @@ package http
 
 import (
 	_ "foover/docs"
+	"foover/internal/auth"
+	"foover/internal/config"
+	"foover/internal/health"
+	"foover/internal/metrics"
 	"foover/internal/middleware"
+	"foover/internal/ratelimit"
 	"foover/internal/service"
 	"foover/internal/transport/http/handler"
 	"github.com/gorilla/mux"
@@ -11,26 +16,113 @@ import (
 	"net/http"
 )
 
-func NewRouter(
-	sessionService service.SessionService,
-	voteService service.VoteService,
-	aggregationService service.AggregationService,
-	productService service.ProductService,
-	logger *slog.Logger,
-) *mux.Router {
+// Dependencies represents the services, infrastructure and configurations the router wires into its handlers
+type Dependencies struct {
+	SessionService        service.SessionService
+	VoteService           service.VoteService
+	AggregationService    service.AggregationService
+	ProductService        service.ProductService
+	AuthService           service.AuthService
+	RetentionService      service.RetentionService
+	ExportService         service.ExportService
+	IdempotencyService    service.IdempotencyService
+	WebhookService        service.WebhookService
+	RecommendationService service.RecommendationService
+	SimilarityService     service.SimilarityService
+	TrendingService       service.TrendingService
+	AnomalyService        service.AnomalyService
+	ExperimentService     service.ExperimentService
+	HealthRegistry        health.Registry
+	Metrics               *metrics.Metrics
+	RateLimiter           ratelimit.Backend
+	HTTPServer            config.HTTPServer
+	RateLimit             config.RateLimit
+	Idempotency           config.Idempotency
+	Logger                *slog.Logger
+}
+
+// NewRouter creates the router serving the API with the given dependencies
+func NewRouter(deps Dependencies) *mux.Router {
 	router := mux.NewRouter()
 
-	router.Use(middleware.NewLoggingMiddleware(logger))
+	router.Use(middleware.NewTracingMiddleware())
+	router.Use(middleware.NewRequestIDMiddleware())
+	router.Use(middleware.NewLoggingMiddleware(deps.Logger))
+	router.Use(middleware.NewMetricsMiddleware(deps.Metrics))
+	router.Use(middleware.NewClientIPMiddleware(deps.HTTPServer.TrustedProxies))
+	// Rate limiting runs before authentication so API keys can't be brute forced
+	if deps.RateLimit.Enabled {
+		router.Use(middleware.NewRateLimitMiddleware(deps.RateLimiter, rateLimitRules(deps.RateLimit), deps.Metrics, deps.Logger))
+	}
+	router.Use(middleware.NewAuthMiddleware(deps.AuthService, deps.Logger))
+	// Idempotency keys are scoped to the authenticated principal
+	if deps.Idempotency.Enabled {
+		router.Use(middleware.NewIdempotencyMiddleware(deps.IdempotencyService, deps.Logger))
+	}
 
 	// Session endpoints
-	router.HandleFunc("/sessions", handler.CreateSessionHandler(sessionService, logger)).Methods("POST")
+	router.HandleFunc("/sessions", handler.CreateSessionHandler(deps.SessionService, deps.Logger)).Methods("POST")
+	router.HandleFunc("/sessions/{session_id}/recommendations", handler.GetRecommendationsHandler(deps.RecommendationService, deps.Logger)).Methods("GET")
+	router.Handle("/sessions/{session_id}/export", withScope(auth.ScopePrivacy, handler.ExportSessionHandler(deps.SessionService, deps.Logger))).Methods("GET")
+	router.Handle("/sessions/{session_id}", withScope(auth.ScopePrivacy, handler.EraseSessionHandler(deps.SessionService, deps.Logger))).Methods("DELETE")
 
 	// Vote endpoints
-	router.HandleFunc("/votes", handler.SaveVoteHandler(voteService, productService, sessionService, logger)).Methods("POST")
-	router.HandleFunc("/votes/{session_id}", handler.GetVotesHandler(voteService, logger)).Methods("GET")
+	router.HandleFunc("/votes", handler.SaveVoteHandler(deps.VoteService, deps.ProductService, deps.SessionService, deps.Logger)).Methods("POST")
+	router.HandleFunc("/votes/batch", handler.SaveVotesBatchHandler(deps.VoteService, deps.ProductService, deps.SessionService, deps.Logger)).Methods("POST")
+	router.Handle("/votes/{session_id}", withScope(auth.ScopeVotesRead, handler.GetVotesHandler(deps.VoteService, deps.Logger))).Methods("GET")
 
 	// Aggregation endpoints
-	router.HandleFunc("/aggregated-scores", handler.GetAggregatedScoresHandler(aggregationService, logger)).Methods("GET")
+	router.HandleFunc("/aggregated-scores", handler.GetAggregatedScoresHandler(deps.AggregationService, deps.Logger)).Methods("GET")
+
+	// Product endpoints
+	router.HandleFunc("/products/{product_id}/similar", handler.GetSimilarProductsHandler(deps.SimilarityService, deps.Logger)).Methods("GET")
+	router.HandleFunc("/trending", handler.GetTrendingHandler(deps.TrendingService, deps.Logger)).Methods("GET")
+
+	// Anomaly endpoints
+	router.Handle("/anomalies", withScope(auth.ScopeVotesRead, handler.GetAnomaliesHandler(deps.AnomalyService, deps.Logger))).Methods("GET")
+
+	// Export endpoints
+	router.Handle("/export/votes", withScope(auth.ScopeVotesRead, handler.ExportVotesHandler(deps.ExportService, deps.Logger))).Methods("GET")
+	router.HandleFunc("/export/scores", handler.ExportScoresHandler(deps.ExportService, deps.Logger)).Methods("GET")
+
+	// Admin endpoints
+	admin := router.PathPrefix("/admin").Subrouter()
+	admin.Use(middleware.RequireScope(auth.ScopeAdmin))
+	admin.HandleFunc("/api-keys", handler.CreateAPIKeyHandler(deps.AuthService, deps.Logger)).Methods("POST")
+	admin.HandleFunc("/api-keys", handler.GetAPIKeysHandler(deps.AuthService, deps.Logger)).Methods("GET")
+	admin.HandleFunc("/api-keys/{id}", handler.RevokeAPIKeyHandler(deps.AuthService, deps.Logger)).Methods("DELETE")
+	admin.HandleFunc("/products", handler.GetProductsHandler(deps.ProductService, deps.Logger)).Methods("GET")
+	admin.HandleFunc("/products", handler.CreateProductHandler(deps.ProductService, deps.Logger)).Methods("POST")
+	admin.HandleFunc("/products/import", handler.ImportProductsHandler(deps.ProductService, deps.Logger)).Methods("POST")
+	admin.HandleFunc("/products/{product_id}", handler.UpdateProductHandler(deps.ProductService, deps.Logger)).Methods("PATCH")
+	admin.HandleFunc("/products/{product_id}/retire", handler.RetireProductHandler(deps.ProductService, deps.Logger)).Methods("POST")
+	admin.HandleFunc("/products/{product_id}/restore", handler.RestoreProductHandler(deps.ProductService, deps.Logger)).Methods("POST")
+	admin.HandleFunc("/votes/flagged", handler.GetFlaggedVotesHandler(deps.VoteService, deps.Logger)).Methods("GET")
+	admin.HandleFunc("/votes/import", handler.ImportVotesHandler(deps.VoteService, deps.Logger)).Methods("POST")
+	admin.HandleFunc("/votes/{id}/approve", handler.ApproveVoteHandler(deps.VoteService, deps.Logger)).Methods("POST")
+	admin.HandleFunc("/votes/{id}/reject", handler.RejectVoteHandler(deps.VoteService, deps.Logger)).Methods("POST")
+	admin.HandleFunc("/retention/report", handler.RetentionReportHandler(deps.RetentionService, deps.Logger)).Methods("GET")
+	admin.HandleFunc("/webhooks", handler.CreateWebhookHandler(deps.WebhookService, deps.Logger)).Methods("POST")
+	admin.HandleFunc("/webhooks", handler.GetWebhooksHandler(deps.WebhookService, deps.Logger)).Methods("GET")
+	admin.HandleFunc("/webhooks/dead-letters", handler.GetWebhookDeadLettersHandler(deps.WebhookService, deps.Logger)).Methods("GET")
+	admin.HandleFunc("/webhooks/deliveries/{id}/redeliver", handler.RedeliverWebhookHandler(deps.WebhookService, deps.Logger)).Methods("POST")
+	admin.HandleFunc("/webhooks/{id}", handler.GetWebhookHandler(deps.WebhookService, deps.Logger)).Methods("GET")
+	admin.HandleFunc("/webhooks/{id}", handler.UpdateWebhookHandler(deps.WebhookService, deps.Logger)).Methods("PATCH")
+	admin.HandleFunc("/webhooks/{id}", handler.DeleteWebhookHandler(deps.WebhookService, deps.Logger)).Methods("DELETE")
+	admin.HandleFunc("/webhooks/{id}/deliveries", handler.GetWebhookDeliveriesHandler(deps.WebhookService, deps.Logger)).Methods("GET")
+	admin.HandleFunc("/experiments", handler.CreateExperimentHandler(deps.ExperimentService, deps.Logger)).Methods("POST")
+	admin.HandleFunc("/experiments", handler.GetExperimentsHandler(deps.ExperimentService, deps.Logger)).Methods("GET")
+	admin.HandleFunc("/experiments/{key}", handler.GetExperimentHandler(deps.ExperimentService, deps.Logger)).Methods("GET")
+	admin.HandleFunc("/experiments/{key}/stop", handler.StopExperimentHandler(deps.ExperimentService, deps.Logger)).Methods("POST")
+	admin.HandleFunc("/experiments/{key}/analysis", handler.GetExperimentAnalysisHandler(deps.ExperimentService, deps.Logger)).Methods("GET")
+
+	// Health endpoints
+	router.HandleFunc("/healthz", handler.LivenessHandler()).Methods("GET")
+	router.HandleFunc("/readyz", handler.ReadinessHandler(deps.HealthRegistry, deps.Logger)).Methods("GET")
+	router.HandleFunc("/status", handler.StatusHandler(deps.HealthRegistry, deps.Logger)).Methods("GET")
+
+	// Metrics endpoint
+	router.Handle("/metrics", deps.Metrics.Handler()).Methods("GET")
 
 	router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 
@@ -43,3 +135,31 @@ func NewRouter(
 
 	return router
 }
+
+// withScope requires the principal of the request to have the scope before calling h
+func withScope(scope string, h http.HandlerFunc) http.Handler {
+	return middleware.RequireScope(scope)(h)
+}
+
+// rateLimitRules declares the rate limits applied per client IP, per session and per route
+func rateLimitRules(cfg config.RateLimit) []middleware.RateLimitRule {
+	return []middleware.RateLimitRule{
+		{
+			Name:  "ip",
+			Scope: middleware.RateLimitByIP,
+			Limit: ratelimit.Limit{Rate: cfg.IPRate, Burst: cfg.IPBurst},
+		},
+		{
+			Name:  "session",
+			Scope: middleware.RateLimitBySession,
+			Limit: ratelimit.Limit{Rate: cfg.SessionRate, Burst: cfg.SessionBurst},
+		},
+		{
+			Name:   "session_create",
+			Scope:  middleware.RateLimitByIP,
+			Method: http.MethodPost,
+			Route:  "/sessions",
+			Limit:  ratelimit.Limit{Rate: cfg.SessionCreateRate, Burst: cfg.SessionCreateBurst},
+		},
+	}
+}
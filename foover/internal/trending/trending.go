@@ -0,0 +1,159 @@
+package trending
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+
+	"foover/internal/config"
+	"foover/internal/models"
+)
+
+// Ranker accumulates the votes of the trending and baseline windows into a ranking of trending products
+type Ranker struct {
+	cfg           config.Trending
+	now           time.Time
+	windowStart   time.Time
+	baselineStart time.Time
+	products      map[string]*momentum
+	// baselineSum and baselineCount cover the baseline votes of all products
+	baselineSum   float64
+	baselineCount int
+}
+
+// momentum represents the votes of a product in both windows
+type momentum struct {
+	recentVotes    int
+	recentWeight   float64 // sum of the recency weights of the recent votes
+	recentWeighted float64 // sum of the recent scores times their recency weight
+	baselineVotes  int
+	baselineSum    float64
+}
+
+// NewRanker creates a new Ranker of the windows ending at now
+func NewRanker(cfg config.Trending, now time.Time) *Ranker {
+	windowStart := now.Add(-cfg.Window)
+	return &Ranker{
+		cfg:           cfg,
+		now:           now,
+		windowStart:   windowStart,
+		baselineStart: windowStart.Add(-cfg.BaselineWindow),
+		products:      make(map[string]*momentum),
+	}
+}
+
+// WindowStart returns the start of the window of recent votes
+func (r *Ranker) WindowStart() time.Time {
+	return r.windowStart
+}
+
+// BaselineStart returns the start of the baseline window, votes last updated before it are ignored
+func (r *Ranker) BaselineStart() time.Time {
+	return r.baselineStart
+}
+
+// AddVote adds a counted vote to the window it was last updated in
+func (r *Ranker) AddVote(vote models.Vote) {
+	if vote.UpdatedAt.Before(r.baselineStart) {
+		return
+	}
+
+	m, ok := r.products[vote.ProductID]
+	if !ok {
+		m = &momentum{}
+		r.products[vote.ProductID] = m
+	}
+
+	if vote.UpdatedAt.Before(r.windowStart) {
+		m.baselineVotes++
+		m.baselineSum += float64(vote.Score)
+		r.baselineSum += float64(vote.Score)
+		r.baselineCount++
+		return
+	}
+
+	weight := r.decay(max(r.now.Sub(vote.UpdatedAt), 0))
+	m.recentVotes++
+	m.recentWeight += weight
+	m.recentWeighted += weight * float64(vote.Score)
+}
+
+// Rank returns the votable products with at least MinVotes recent votes, most trending first.
+// Volume growth compares the recency weighted votes of the window to the votes the product would have
+// weighted there at its baseline rate, both smoothed by one vote so new products don't grow infinitely.
+func (r *Ranker) Rank(votable map[string]struct{}) []models.TrendingProduct {
+	// The weight a vote per second over the whole window adds up to
+	windowWeight := r.windowWeight()
+
+	var globalBaselineAvg float64
+	if r.baselineCount > 0 {
+		globalBaselineAvg = r.baselineSum / float64(r.baselineCount)
+	}
+
+	ranking := make([]models.TrendingProduct, 0, len(r.products))
+	for productID, m := range r.products {
+		if _, ok := votable[productID]; !ok || m.recentVotes < r.cfg.MinVotes {
+			continue
+		}
+
+		var expected float64
+		if r.cfg.BaselineWindow > 0 {
+			expected = float64(m.baselineVotes) / r.cfg.BaselineWindow.Seconds() * windowWeight
+		}
+		growth := (m.recentWeight + 1) / (expected + 1)
+		recentAvg := m.recentWeighted / m.recentWeight
+		baselineAvg := globalBaselineAvg
+		if m.baselineVotes > 0 {
+			baselineAvg = m.baselineSum / float64(m.baselineVotes)
+		}
+		// Without any baseline vote there is no average to compare to
+		change := 0.0
+		if r.baselineCount > 0 {
+			change = recentAvg - baselineAvg
+		}
+
+		ranking = append(ranking, models.TrendingProduct{
+			ProductID:        productID,
+			TrendScore:       round(math.Log(growth) + r.cfg.ScoreWeight*change),
+			RecentVotes:      m.recentVotes,
+			BaselineVotes:    m.baselineVotes,
+			VolumeGrowth:     round(growth),
+			RecentAvgScore:   round(recentAvg),
+			BaselineAvgScore: round(baselineAvg),
+			AvgScoreChange:   round(change),
+		})
+	}
+
+	slices.SortFunc(ranking, func(a, b models.TrendingProduct) int {
+		return cmp.Or(
+			cmp.Compare(b.TrendScore, a.TrendScore),
+			cmp.Compare(b.RecentVotes, a.RecentVotes),
+			cmp.Compare(a.ProductID, b.ProductID),
+		)
+	})
+
+	return ranking
+}
+
+// decay returns the weight of a vote of the given age, halving every HalfLife, votes don't decay without a half-life
+func (r *Ranker) decay(age time.Duration) float64 {
+	if r.cfg.HalfLife <= 0 {
+		return 1
+	}
+	return math.Exp2(-age.Seconds() / r.cfg.HalfLife.Seconds())
+}
+
+// windowWeight integrates the decay over the window, in seconds
+func (r *Ranker) windowWeight() float64 {
+	if r.cfg.HalfLife <= 0 {
+		return r.cfg.Window.Seconds()
+	}
+	halfLife := r.cfg.HalfLife.Seconds()
+	return halfLife / math.Ln2 * (1 - math.Exp2(-r.cfg.Window.Seconds()/halfLife))
+}
+
+// round rounds a value to two decimals
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,79 @@
+package trending
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"foover/internal/config"
+	"foover/internal/models"
+	"foover/internal/testutil"
+)
+
+func TestRank(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	recent := now.Add(-10 * time.Minute)
+	baseline := now.Add(-2 * time.Hour)
+
+	tests := []struct {
+		name    string
+		cfg     config.Trending
+		votable []string
+		votes   [][]models.Vote
+		want    []models.TrendingProduct
+	}{
+		{
+			name:    "growth and score change",
+			cfg:     config.Trending{Window: time.Hour, BaselineWindow: 10 * time.Hour, MinVotes: 2, ScoreWeight: 0.5},
+			votable: []string{"rising", "new", "steady", "few"},
+			votes: [][]models.Vote{
+				// One expected vote in the window at the baseline rate
+				testutil.Votes("rising", baseline, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3),
+				testutil.Votes("rising", recent, 5, 5, 5, 5, 5),
+				// Compared to the average of all baseline votes
+				testutil.Votes("new", recent, 4, 4),
+				testutil.Votes("steady", baseline, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5),
+				testutil.Votes("steady", recent, 5, 5),
+				testutil.Votes("few", recent, 5),
+				testutil.Votes("retired", recent, 5, 5, 5, 5, 5),
+				// Older than the baseline window
+				testutil.Votes("steady", now.Add(-12*time.Hour), 1, 1, 1),
+			},
+			want: []models.TrendingProduct{
+				{ProductID: "rising", TrendScore: 2.1, RecentVotes: 5, BaselineVotes: 10, VolumeGrowth: 3, RecentAvgScore: 5, BaselineAvgScore: 3, AvgScoreChange: 2},
+				{ProductID: "new", TrendScore: 1.1, RecentVotes: 2, BaselineVotes: 0, VolumeGrowth: 3, RecentAvgScore: 4, BaselineAvgScore: 4, AvgScoreChange: 0},
+				{ProductID: "steady", TrendScore: 0.41, RecentVotes: 2, BaselineVotes: 10, VolumeGrowth: 1.5, RecentAvgScore: 5, BaselineAvgScore: 5, AvgScoreChange: 0},
+			},
+		},
+		{
+			name:    "recency decay without baseline",
+			cfg:     config.Trending{Window: time.Hour, BaselineWindow: 10 * time.Hour, HalfLife: 30 * time.Minute, MinVotes: 1, ScoreWeight: 1},
+			votable: []string{"decaying"},
+			votes: [][]models.Vote{
+				testutil.Votes("decaying", now, 5),
+				testutil.Votes("decaying", now.Add(-30*time.Minute), 1),
+			},
+			want: []models.TrendingProduct{
+				// The half-life old vote weighs half, no baseline vote leaves no average to compare to
+				{ProductID: "decaying", TrendScore: 0.92, RecentVotes: 2, BaselineVotes: 0, VolumeGrowth: 2.5, RecentAvgScore: 3.67, BaselineAvgScore: 0, AvgScoreChange: 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranker := NewRanker(tt.cfg, now)
+			for _, productVotes := range tt.votes {
+				for _, vote := range productVotes {
+					ranker.AddVote(vote)
+				}
+			}
+			votable := make(map[string]struct{}, len(tt.votable))
+			for _, productID := range tt.votable {
+				votable[productID] = struct{}{}
+			}
+			if got := ranker.Rank(votable); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Rank() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
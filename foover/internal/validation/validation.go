@@ -1,4 +1,4 @@
-package handler
+package validation
 
 import (
 	"fmt"
@@ -12,8 +12,8 @@ func init() {
 	validate = validator.New()
 }
 
-// ValidateStruct validates a struct using the go-playground/validator library
-func ValidateStruct(s interface{}) error {
+// Struct validates a struct using the go-playground/validator library
+func Struct(s interface{}) error {
 	if err := validate.Struct(s); err != nil {
 		var validationErrors []string
 		errorMessages := map[string]string{
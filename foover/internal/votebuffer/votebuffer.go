@@ -0,0 +1,339 @@
+package votebuffer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"foover/internal/config"
+	"foover/internal/models"
+)
+
+// ErrFull is returned when the buffer holds its maximum number of votes
+var ErrFull = errors.New("vote buffer full")
+
+const (
+	segmentPrefix = "votes-"
+	segmentSuffix = ".wal"
+)
+
+// Buffer defines behaviors of a durable local buffer for votes that couldn't be stored.
+// It doubles as a health checker reporting its depth.
+type Buffer interface {
+	Append(vote models.Vote) error
+	Replay(ctx context.Context, save func(ctx context.Context, votes []models.Vote) error) (int, error)
+	Depth() int64
+	Check(ctx context.Context) error
+	Details() map[string]interface{}
+	Close() error
+}
+
+// record represents a buffered vote as a line of a segment, Vote hides fields from JSON that must survive here
+type record struct {
+	SessionID    string    `json:"session_id"`
+	ProductID    string    `json:"product_id"`
+	Score        int       `json:"score"`
+	Status       string    `json:"status"`
+	FraudScore   float64   `json:"fraud_score,omitempty"`
+	FraudReasons []string  `json:"fraud_reasons,omitempty"`
+	Fingerprint  string    `json:"fingerprint,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// fileBuffer implements the Buffer interface with append-only segment files in a directory.
+// Votes are appended to the active segment and synced to disk before Append returns,
+// Replay seals the active segment and drains sealed segments in the order they were written.
+type fileBuffer struct {
+	dir       string
+	maxDepth  int64
+	batchSize int
+
+	mu          sync.Mutex
+	active      *os.File
+	activeSeq   int64
+	activeDepth int64
+	depth       int64
+
+	replayMu sync.Mutex
+}
+
+// NewFileBuffer opens the buffer in the configured directory, creating it if needed.
+// Segments left by a previous process are kept for replay.
+func NewFileBuffer(cfg config.VoteBuffer) (Buffer, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	b := &fileBuffer{
+		dir:       cfg.Dir,
+		maxDepth:  cfg.MaxDepth,
+		batchSize: cfg.ReplayBatchSize,
+	}
+
+	seqs, err := b.segments()
+	if err != nil {
+		return nil, err
+	}
+	for _, seq := range seqs {
+		lines, err := countLines(b.segmentPath(seq))
+		if err != nil {
+			return nil, err
+		}
+		b.depth += lines
+		b.activeSeq = seq
+	}
+
+	if err := b.openSegment(b.activeSeq + 1); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Append writes the vote to the active segment and syncs it to disk
+func (b *fileBuffer) Append(vote models.Vote) error {
+	line, err := json.Marshal(record{
+		SessionID:    vote.SessionID,
+		ProductID:    vote.ProductID,
+		Score:        vote.Score,
+		Status:       vote.Status,
+		FraudScore:   vote.FraudScore,
+		FraudReasons: vote.FraudReasons,
+		Fingerprint:  vote.Fingerprint,
+		UpdatedAt:    vote.UpdatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.depth >= b.maxDepth {
+		return ErrFull
+	}
+	if _, err := b.active.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err := b.active.Sync(); err != nil {
+		return err
+	}
+	b.activeDepth++
+	b.depth++
+
+	return nil
+}
+
+// Replay seals the active segment and passes the votes of each sealed segment to save in batches, oldest segment first.
+// Within a segment only the latest vote per session and product is kept. A segment is removed once all its batches
+// are saved, a failing save stops the replay and the segment is replayed again next time, so save must be idempotent.
+// It returns the number of replayed votes.
+func (b *fileBuffer) Replay(ctx context.Context, save func(ctx context.Context, votes []models.Vote) error) (int, error) {
+	b.replayMu.Lock()
+	defer b.replayMu.Unlock()
+
+	if err := b.seal(); err != nil {
+		return 0, err
+	}
+
+	seqs, err := b.segments()
+	if err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	for _, seq := range seqs {
+		if seq == b.currentSeq() {
+			continue
+		}
+
+		path := b.segmentPath(seq)
+		votes, lines, err := readSegment(path)
+		if err != nil {
+			return replayed, err
+		}
+
+		for start := 0; start < len(votes); start += b.batchSize {
+			end := min(start+b.batchSize, len(votes))
+			if err := save(ctx, votes[start:end]); err != nil {
+				return replayed, err
+			}
+			replayed += end - start
+		}
+
+		if err := os.Remove(path); err != nil {
+			return replayed, err
+		}
+		b.mu.Lock()
+		b.depth -= lines
+		b.mu.Unlock()
+	}
+
+	return replayed, nil
+}
+
+// Depth returns the number of buffered votes, including superseded ones not replayed yet
+func (b *fileBuffer) Depth() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.depth
+}
+
+// Check reports an error once the buffer is full and votes are rejected
+func (b *fileBuffer) Check(ctx context.Context) error {
+	if depth := b.Depth(); depth >= b.maxDepth {
+		return fmt.Errorf("vote buffer full with %d votes", depth)
+	}
+	return nil
+}
+
+// Details reports the buffer depth for the status endpoint
+func (b *fileBuffer) Details() map[string]interface{} {
+	return map[string]interface{}{
+		"depth":     b.Depth(),
+		"max_depth": b.maxDepth,
+	}
+}
+
+// Close closes the active segment, buffered votes stay on disk
+func (b *fileBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.active.Close()
+}
+
+// seal closes the active segment if it holds votes and starts a new one
+func (b *fileBuffer) seal() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.activeDepth == 0 {
+		return nil
+	}
+	if err := b.active.Close(); err != nil {
+		return err
+	}
+	return b.openSegment(b.activeSeq + 1)
+}
+
+// openSegment creates the segment with the given sequence number as the active segment, b.mu must be held
+func (b *fileBuffer) openSegment(seq int64) error {
+	f, err := os.OpenFile(b.segmentPath(seq), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	b.active = f
+	b.activeSeq = seq
+	b.activeDepth = 0
+	return nil
+}
+
+func (b *fileBuffer) currentSeq() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.activeSeq
+}
+
+// segments returns the sequence numbers of the segments in the directory in ascending order
+func (b *fileBuffer) segments() ([]int64, error) {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var seqs []int64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+
+	return seqs, nil
+}
+
+func (b *fileBuffer) segmentPath(seq int64) string {
+	return filepath.Join(b.dir, fmt.Sprintf("%s%020d%s", segmentPrefix, seq, segmentSuffix))
+}
+
+// readSegment returns the latest vote per session and product of a segment in the order they were last written,
+// and the number of lines read. Lines that can't be decoded, e.g. torn by a crash while appending, are skipped.
+func readSegment(path string) ([]models.Vote, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	type key struct{ sessionID, productID string }
+	latest := make(map[key]int)
+	var votes []models.Vote
+	var lines int64
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines++
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+
+		vote := models.Vote{
+			SessionID:    r.SessionID,
+			ProductID:    r.ProductID,
+			Score:        r.Score,
+			Status:       r.Status,
+			FraudScore:   r.FraudScore,
+			FraudReasons: r.FraudReasons,
+			Fingerprint:  r.Fingerprint,
+			UpdatedAt:    r.UpdatedAt,
+		}
+		k := key{r.SessionID, r.ProductID}
+		if i, ok := latest[k]; !ok || !vote.UpdatedAt.Before(votes[i].UpdatedAt) {
+			latest[k] = len(votes)
+		}
+		votes = append(votes, vote)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	kept := make([]models.Vote, 0, len(latest))
+	for i, vote := range votes {
+		if latest[key{vote.SessionID, vote.ProductID}] == i {
+			kept = append(kept, vote)
+		}
+	}
+
+	return kept, lines, nil
+}
+
+// countLines counts the buffered votes of a segment
+func countLines(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var lines int64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines++
+	}
+	return lines, scanner.Err()
+}
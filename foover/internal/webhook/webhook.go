@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"foover/internal/models"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-Foover-Event"
+	HeaderDelivery  = "X-Foover-Delivery"
+	HeaderSignature = "X-Foover-Signature"
+)
+
+// secretPrefix marks webhook secrets issued by this service so they are easy to recognize in leaks
+const secretPrefix = "whsec_"
+
+// maxResponseBody bounds the response body read to reuse the connection
+const maxResponseBody = 64 * 1024
+
+// GenerateSecret creates a new random webhook secret
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+// Sign returns the signature header of a body sent at timestamp, in the form t=<unix seconds>,v1=<hex HMAC-SHA256>.
+// The MAC covers "<unix seconds>.<body>", so receivers can reject replayed payloads by their age.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns the delay before the next attempt after the given number of failed attempts,
+// doubling from base up to maxDelay with up to 20% jitter so failing endpoints aren't retried in lockstep
+func Backoff(attempts int, base time.Duration, maxDelay time.Duration) time.Duration {
+	delay := maxDelay
+	if shift := attempts - 1; shift >= 0 && shift < 32 && base<<shift > 0 && base<<shift < maxDelay {
+		delay = base << shift
+	}
+
+	jitter, err := rand.Int(rand.Reader, big.NewInt(int64(delay)/5+1))
+	if err != nil {
+		return delay
+	}
+	return delay - time.Duration(jitter.Int64())
+}
+
+// Client sends webhook deliveries
+type Client interface {
+	Send(ctx context.Context, url string, secret string, delivery models.WebhookDelivery) (int, error)
+}
+
+// httpClient implements the Client interface over HTTP
+type httpClient struct {
+	client *http.Client
+}
+
+// NewClient creates a new Client giving up on endpoints that don't respond within timeout.
+// Redirects aren't followed, a webhook must be registered with its final URL.
+func NewClient(timeout time.Duration) Client {
+	return &httpClient{
+		client: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Send posts the signed payload of the delivery to url and returns the response status code.
+// Responses other than 2xx are returned as errors along with their status code.
+func (c *httpClient) Send(ctx context.Context, url string, secret string, delivery models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "foover-webhooks")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID.Hex())
+	req.Header.Set(HeaderSignature, Sign(secret, time.Now(), delivery.Payload))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Group defines behaviors of a set of background workers sharing a lifecycle
+type Group interface {
+	Go(name string, fn func(ctx context.Context))
+	Every(name string, interval time.Duration, fn func(ctx context.Context) error)
+	Stop(ctx context.Context) error
+}
+
+// group implements the Group interface
+type group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	logger *slog.Logger
+}
+
+// NewGroup creates a new Group whose workers run until Stop is called
+func NewGroup(logger *slog.Logger) Group {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &group{
+		ctx:    ctx,
+		cancel: cancel,
+		logger: logger,
+	}
+}
+
+// Go runs fn in its own goroutine, fn must return once its context is canceled
+func (g *group) Go(name string, fn func(ctx context.Context)) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		g.logger.Info("Background worker started", "worker", name)
+		fn(g.ctx)
+		g.logger.Info("Background worker stopped", "worker", name)
+	}()
+}
+
+// Every runs fn on every tick of the given interval until the group is stopped
+func (g *group) Every(name string, interval time.Duration, fn func(ctx context.Context) error) {
+	g.Go(name, func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := fn(ctx); err != nil && ctx.Err() == nil {
+					g.logger.Error("Background worker run failed", "worker", name, "error", err)
+				}
+			}
+		}
+	})
+}
+
+// Stop cancels all workers and waits for them to return or for ctx to expire
+func (g *group) Stop(ctx context.Context) error {
+	g.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}